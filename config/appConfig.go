@@ -2,7 +2,11 @@ package config
 
 import (
 	"errors"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -21,6 +25,38 @@ type AppConfig struct {
 	FlutterwaveClientID      string
 	FlutterwaveSecretKey     string
 	FlutterwaveEncryptionKey string
+	FlutterwaveWebhookHash   string
+	PaymentRedirectURL       string
+	PaymentCurrency          string
+	PaymentProvider          string
+	BankVerifier             string
+	BankCountry              string
+	VerifyMeAPIKey           string
+	CommissionPercent        float64
+	PayoutInterval           time.Duration
+	PayoutMinimumAmount      float64
+	RefreshTokenTTL          time.Duration
+	GuestCartTTL             time.Duration
+	AdminEmails              []string
+	SMTPHost                 string
+	SMTPPort                 string
+	SMTPUsername             string
+	SMTPPassword             string
+	SMTPFrom                 string
+	NotificationMaxAttempts  int
+	NotificationPollInterval time.Duration
+	StorageDriver            string
+	UploadDir                string
+	UploadBaseURL            string
+	UploadMaxBytes           int64
+	// TaxRates are in basis points (1/100 of a percent), keyed by COUNTRY or COUNTRY/STATE
+	TaxRates              map[string]int64
+	DefaultTaxBasisPoints int64
+	// ShippingMethods lists the methods on offer, the default first; ShippingAmounts prices
+	// one seller's shipment by each, in minor currency units
+	ShippingMethods  []string
+	ShippingAmounts  map[string]int64
+	FreeShippingOver int64
 }
 
 func SetupEnv() (config AppConfig, err error) {
@@ -90,6 +126,172 @@ func SetupEnv() (config AppConfig, err error) {
 	// Note: FLUTTERWAVE_SECRET_KEY is required for bank verification features
 	// Get your keys from: https://dashboard.flutterwave.com (Settings > API Keys)
 
+	// Secret hash set under Settings > Webhooks, sent back by Flutterwave in the verif-hash header
+	flutterwaveWebhookHash := os.Getenv("FLUTTERWAVE_WEBHOOK_HASH")
+
+	paymentRedirectURL := os.Getenv("PAYMENT_REDIRECT_URL")
+
+	paymentCurrency := os.Getenv("PAYMENT_CURRENCY")
+	if len(paymentCurrency) < 1 {
+		paymentCurrency = "NGN"
+	}
+
+	verifyMeAPIKey := os.Getenv("VERIFYME_API_KEY")
+
+	bankCountry := os.Getenv("BANK_COUNTRY")
+	if len(bankCountry) < 1 {
+		bankCountry = "NG"
+	}
+
+	// PAYMENT_PROVIDER: flutterwave | fake, BANK_VERIFIER: flutterwave | verifyme | fake
+	// When unset, Flutterwave is used if its key is present, otherwise the features are disabled.
+	// The in-memory fake is never picked implicitly; CI and local setups ask for it by name.
+	defaultProvider := ""
+	if len(flutterwaveSecretKey) > 0 {
+		defaultProvider = "flutterwave"
+	}
+
+	paymentProvider := os.Getenv("PAYMENT_PROVIDER")
+	if len(paymentProvider) < 1 {
+		paymentProvider = defaultProvider
+	}
+
+	bankVerifier := os.Getenv("BANK_VERIFIER")
+	if len(bankVerifier) < 1 {
+		bankVerifier = defaultProvider
+	}
+
+	commissionPercent := 10.0
+	if value := os.Getenv("PLATFORM_COMMISSION_PERCENT"); len(value) > 0 {
+		commissionPercent, err = strconv.ParseFloat(value, 64)
+		if err != nil || commissionPercent < 0 || commissionPercent > 100 {
+			return AppConfig{}, errors.New("PLATFORM_COMMISSION_PERCENT must be a number between 0 and 100")
+		}
+	}
+
+	payoutInterval := 24 * time.Hour
+	if value := os.Getenv("PAYOUT_INTERVAL"); len(value) > 0 {
+		payoutInterval, err = time.ParseDuration(value)
+		if err != nil || payoutInterval <= 0 {
+			return AppConfig{}, errors.New("PAYOUT_INTERVAL must be a positive duration such as 24h")
+		}
+	}
+
+	payoutMinimumAmount := 1000.0
+	if value := os.Getenv("PAYOUT_MINIMUM_AMOUNT"); len(value) > 0 {
+		payoutMinimumAmount, err = strconv.ParseFloat(value, 64)
+		if err != nil || payoutMinimumAmount < 0 {
+			return AppConfig{}, errors.New("PAYOUT_MINIMUM_AMOUNT must be a non-negative number")
+		}
+	}
+
+	refreshTokenTTL := 30 * 24 * time.Hour
+	if value := os.Getenv("REFRESH_TOKEN_TTL"); len(value) > 0 {
+		refreshTokenTTL, err = time.ParseDuration(value)
+		if err != nil || refreshTokenTTL <= 0 {
+			return AppConfig{}, errors.New("REFRESH_TOKEN_TTL must be a positive duration such as 720h")
+		}
+	}
+
+	// Guest carts nobody has touched for GUEST_CART_TTL are deleted
+	guestCartTTL := 30 * 24 * time.Hour
+	if value := os.Getenv("GUEST_CART_TTL"); len(value) > 0 {
+		guestCartTTL, err = time.ParseDuration(value)
+		if err != nil || guestCartTTL <= 0 {
+			return AppConfig{}, errors.New("GUEST_CART_TTL must be a positive duration such as 720h")
+		}
+	}
+
+	// ADMIN_EMAILS is a comma separated list of accounts granted the admin role on startup
+	var adminEmails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); len(email) > 0 {
+			adminEmails = append(adminEmails, email)
+		}
+	}
+
+	// Email is sent through SMTP_HOST; leave it unset to notify by SMS only
+	smtpHost := os.Getenv("SMTP_HOST")
+	smtpPort := os.Getenv("SMTP_PORT")
+	if len(smtpPort) < 1 {
+		smtpPort = "587"
+	}
+	smtpFrom := os.Getenv("SMTP_FROM")
+	if len(smtpHost) > 0 && len(smtpFrom) < 1 {
+		return AppConfig{}, errors.New("SMTP_FROM is not set, env variable is required when SMTP_HOST is set")
+	}
+
+	notificationMaxAttempts := 8
+	if value := os.Getenv("NOTIFICATION_MAX_ATTEMPTS"); len(value) > 0 {
+		notificationMaxAttempts, err = strconv.Atoi(value)
+		if err != nil || notificationMaxAttempts < 1 {
+			return AppConfig{}, errors.New("NOTIFICATION_MAX_ATTEMPTS must be a positive number")
+		}
+	}
+
+	notificationPollInterval := 5 * time.Second
+	if value := os.Getenv("NOTIFICATION_POLL_INTERVAL"); len(value) > 0 {
+		notificationPollInterval, err = time.ParseDuration(value)
+		if err != nil || notificationPollInterval <= 0 {
+			return AppConfig{}, errors.New("NOTIFICATION_POLL_INTERVAL must be a positive duration such as 5s")
+		}
+	}
+
+	// Uploaded files are kept in UPLOAD_DIR and served under UPLOAD_BASE_URL by the local driver
+	storageDriver := os.Getenv("STORAGE_DRIVER")
+	if len(storageDriver) < 1 {
+		storageDriver = "local"
+	}
+
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if len(uploadDir) < 1 {
+		uploadDir = "uploads"
+	}
+
+	uploadBaseURL := os.Getenv("UPLOAD_BASE_URL")
+	if len(uploadBaseURL) < 1 {
+		uploadBaseURL = "/uploads"
+	}
+
+	uploadMaxBytes := int64(5 << 20)
+	if value := os.Getenv("UPLOAD_MAX_SIZE_MB"); len(value) > 0 {
+		megabytes, err := strconv.Atoi(value)
+		if err != nil || megabytes < 1 {
+			return AppConfig{}, errors.New("UPLOAD_MAX_SIZE_MB must be a positive number")
+		}
+		uploadMaxBytes = int64(megabytes) << 20
+	}
+
+	// TAX_RULES lists rates by country or country/state, e.g. "NG:7.5,US/CA:7.25,US:0"; the most
+	// specific match for the delivery address wins, otherwise TAX_DEFAULT_PERCENT applies
+	taxRates, err := parseTaxRates(os.Getenv("TAX_RULES"))
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	defaultTaxBasisPoints := int64(0)
+	if value := os.Getenv("TAX_DEFAULT_PERCENT"); len(value) > 0 {
+		defaultTaxBasisPoints, err = parsePercent(value)
+		if err != nil {
+			return AppConfig{}, errors.New("TAX_DEFAULT_PERCENT must be a number between 0 and 100")
+		}
+	}
+
+	// SHIPPING_RATES prices each seller's shipment by method, e.g. "standard:1500,express:3500";
+	// the first method is the default and the one SHIPPING_FREE_OVER makes free
+	shippingMethods, shippingAmounts, err := parseShippingRates(os.Getenv("SHIPPING_RATES"))
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	freeShippingOver := int64(0)
+	if value := os.Getenv("SHIPPING_FREE_OVER"); len(value) > 0 {
+		freeShippingOver, err = parseAmount(value)
+		if err != nil {
+			return AppConfig{}, errors.New("SHIPPING_FREE_OVER must be a non-negative amount")
+		}
+	}
+
 	return AppConfig{
 		ServerPort:               httpPort,
 		DBHost:                   dbHost,
@@ -104,5 +306,101 @@ func SetupEnv() (config AppConfig, err error) {
 		FlutterwaveClientID:      flutterwaveClientID,
 		FlutterwaveSecretKey:     flutterwaveSecretKey,
 		FlutterwaveEncryptionKey: flutterwaveEncryptionKey,
+		FlutterwaveWebhookHash:   flutterwaveWebhookHash,
+		PaymentRedirectURL:       paymentRedirectURL,
+		PaymentCurrency:          paymentCurrency,
+		PaymentProvider:          paymentProvider,
+		BankVerifier:             bankVerifier,
+		BankCountry:              bankCountry,
+		VerifyMeAPIKey:           verifyMeAPIKey,
+		CommissionPercent:        commissionPercent,
+		PayoutInterval:           payoutInterval,
+		PayoutMinimumAmount:      payoutMinimumAmount,
+		RefreshTokenTTL:          refreshTokenTTL,
+		GuestCartTTL:             guestCartTTL,
+		AdminEmails:              adminEmails,
+		SMTPHost:                 smtpHost,
+		SMTPPort:                 smtpPort,
+		SMTPUsername:             os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:             os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:                 smtpFrom,
+		NotificationMaxAttempts:  notificationMaxAttempts,
+		NotificationPollInterval: notificationPollInterval,
+		StorageDriver:            storageDriver,
+		UploadDir:                uploadDir,
+		UploadBaseURL:            uploadBaseURL,
+		UploadMaxBytes:           uploadMaxBytes,
+		TaxRates:                 taxRates,
+		DefaultTaxBasisPoints:    defaultTaxBasisPoints,
+		ShippingMethods:          shippingMethods,
+		ShippingAmounts:          shippingAmounts,
+		FreeShippingOver:         freeShippingOver,
 	}, nil
 }
+
+func parseTaxRates(value string) (map[string]int64, error) {
+	rates := map[string]int64{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+
+		region, rate, ok := strings.Cut(entry, ":")
+		basisPoints, err := parsePercent(rate)
+		if !ok || err != nil {
+			return nil, errors.New("TAX_RULES entries must look like COUNTRY:PERCENT or COUNTRY/STATE:PERCENT")
+		}
+		country, state, hasState := strings.Cut(region, "/")
+		if country = strings.TrimSpace(country); len(country) == 0 {
+			return nil, errors.New("TAX_RULES entries must name a country")
+		}
+		if state = strings.TrimSpace(state); hasState && len(state) > 0 {
+			country += "/" + state
+		}
+		rates[country] = basisPoints
+	}
+	return rates, nil
+}
+
+func parseShippingRates(value string) ([]string, map[string]int64, error) {
+	var methods []string
+	amounts := map[string]int64{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+
+		method, price, ok := strings.Cut(entry, ":")
+		amount, err := parseAmount(price)
+		method = strings.ToLower(strings.TrimSpace(method))
+		if !ok || err != nil || len(method) == 0 {
+			return nil, nil, errors.New("SHIPPING_RATES entries must look like METHOD:AMOUNT")
+		}
+		if _, listed := amounts[method]; !listed {
+			methods = append(methods, method)
+		}
+		amounts[method] = amount
+	}
+	if len(methods) == 0 {
+		return []string{"standard"}, map[string]int64{"standard": 0}, nil
+	}
+	return methods, amounts, nil
+}
+
+// parsePercent reads a percentage such as 7.5 as basis points
+func parsePercent(value string) (int64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, errors.New("invalid percentage")
+	}
+	return int64(math.Round(percent * 100)), nil
+}
+
+// parseAmount reads an amount in major units such as 1500.50 as minor units
+func parseAmount(value string) (int64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || amount < 0 {
+		return 0, errors.New("invalid amount")
+	}
+	return int64(math.Round(amount * 100)), nil
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/storage"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	roleService      service.RoleService
+	orderService     service.OrderService
+	outboxService    service.OutboxService
+	catalogueService service.CatalogueService
+	auth             helper.Auth
+	config           config.AppConfig
+}
+
+func SetupAdminRoutes(restHandler *rest.RestHandler, notificationClient notification.NotificationClient, fileStorage storage.Storage) {
+	app := restHandler.App
+	auth := restHandler.Auth
+
+	roleRepo := repository.NewRoleRepository(restHandler.DB)
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	orderRepo := repository.NewOrderRepository(restHandler.DB)
+	catalogueRepo := repository.NewCatalogueRepository(restHandler.DB)
+	outboxRepo := repository.NewOutboxRepository(restHandler.DB)
+	auditRepo := repository.NewAuditRepository(restHandler.DB)
+	catalogueService := service.NewCatalogueService(catalogueRepo, auditRepo, roleRepo, newWishlistService(restHandler, notificationClient), auth, restHandler.Config, fileStorage)
+	handler := AdminHandler{
+		roleService:      service.NewRoleService(roleRepo, userRepo),
+		orderService:     service.NewOrderService(orderRepo, auth, restHandler.Config, service.NewNotificationService(notificationClient, userRepo)),
+		outboxService:    service.NewOutboxService(outboxRepo, notificationClient, restHandler.Config),
+		catalogueService: catalogueService,
+		auth:             auth,
+		config:           restHandler.Config,
+	}
+	catalogueHandler := CatalogueHandler{
+		catalogueService: catalogueService,
+		auth:             auth,
+		config:           restHandler.Config,
+	}
+
+	// Private endpoints (authentication required - admin permissions)
+	adminRoutes := app.Group("/admin")
+
+	manageRoles := auth.RequirePermission(roleRepo, domain.PERMISSION_ROLES_MANAGE)
+	adminRoutes.Get("/roles", manageRoles, handler.GetRoles)
+	adminRoutes.Get("/users/:id/roles", manageRoles, handler.GetUserRoles)
+	adminRoutes.Post("/users/:id/roles", manageRoles, handler.AssignRole)
+	adminRoutes.Delete("/users/:id/roles/:role", manageRoles, handler.RemoveRole)
+
+	readAllOrders := auth.RequirePermission(roleRepo, domain.PERMISSION_ORDERS_READ_ALL)
+	adminRoutes.Get("/orders", readAllOrders, handler.GetOrders)
+	adminRoutes.Get("/orders/:id", readAllOrders, handler.GetOrder)
+
+	moderateCatalogue := auth.RequirePermission(roleRepo, domain.PERMISSION_CATALOGUE_MODERATE)
+	adminRoutes.Delete("/products/:id", moderateCatalogue, catalogueHandler.DeleteProduct)
+	adminRoutes.Patch("/categories/:id", moderateCatalogue, catalogueHandler.UpdateCategory)
+	adminRoutes.Post("/categories/:id/move", moderateCatalogue, catalogueHandler.MoveCategory)
+	adminRoutes.Delete("/categories/:id", moderateCatalogue, catalogueHandler.DeleteCategory)
+
+	readAudit := auth.RequirePermission(roleRepo, domain.PERMISSION_AUDIT_READ)
+	adminRoutes.Get("/audit", readAudit, handler.GetAuditEntries)
+
+	manageNotifications := auth.RequirePermission(roleRepo, domain.PERMISSION_NOTIFICATIONS_MANAGE)
+	adminRoutes.Get("/notifications", manageNotifications, handler.GetNotifications)
+	adminRoutes.Get("/notifications/:id", manageNotifications, handler.GetNotification)
+	adminRoutes.Post("/notifications/:id/replay", manageNotifications, handler.ReplayNotification)
+}
+
+func (h *AdminHandler) GetRoles(ctx *fiber.Ctx) error {
+	roles, err := h.roleService.GetRoles()
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Roles fetched successfully",
+		"roles":   roles,
+	})
+}
+
+func (h *AdminHandler) GetUserRoles(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid user ID")
+	}
+
+	roles, err := h.roleService.GetUserRoles(uint(id))
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "User roles fetched successfully",
+		"roles":   roles,
+	})
+}
+
+func (h *AdminHandler) AssignRole(ctx *fiber.Ctx) error {
+	admin := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid user ID")
+	}
+
+	request := dto.AssignRoleRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	request.Role = strings.ToLower(strings.TrimSpace(request.Role))
+	if request.Role == "" {
+		return helper.HandleValidationError(ctx, "Field 'role' is required")
+	}
+
+	roles, err := h.roleService.AssignRole(uint(id), request.Role, admin.ID)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Role assigned successfully",
+		"roles":   roles,
+	})
+}
+
+func (h *AdminHandler) RemoveRole(ctx *fiber.Ctx) error {
+	admin := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid user ID")
+	}
+
+	role := strings.ToLower(ctx.Params("role"))
+	if uint(id) == admin.ID && role == domain.ADMIN {
+		return helper.HandleConflictError(ctx, "You cannot remove your own admin role", domain.ErrForbidden)
+	}
+
+	roles, err := h.roleService.RemoveRole(uint(id), role)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.HandleValidationError(ctx, "The user does not have this role, or it is implied by their user type")
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Role removed successfully",
+		"roles":   roles,
+	})
+}
+
+func (h *AdminHandler) GetOrders(ctx *fiber.Ctx) error {
+	query := dto.OrderQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
+
+	result, err := h.orderService.GetAllOrders(query)
+	if err != nil {
+		return handleListError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Orders fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *AdminHandler) GetOrder(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid order ID")
+	}
+
+	order, err := h.orderService.GetOrder(uint(id))
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Order fetched successfully",
+		"order":   order,
+	})
+}
+
+func (h *AdminHandler) GetNotifications(ctx *fiber.Ctx) error {
+	query := dto.OutboxQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+
+	result, err := h.outboxService.GetMessages(query)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Notifications fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *AdminHandler) GetNotification(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid notification ID")
+	}
+
+	message, err := h.outboxService.GetMessage(uint(id))
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":      "Notification fetched successfully",
+		"notification": message,
+	})
+}
+
+func (h *AdminHandler) ReplayNotification(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid notification ID")
+	}
+
+	message, err := h.outboxService.ReplayMessage(uint(id))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotReplayable) {
+			return helper.HandleConflictError(ctx, "Notification cannot be replayed", err)
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":      "Notification queued for delivery",
+		"notification": message,
+	})
+}
+
+// GetAuditEntries lists refused and overridden catalogue changes, newest first
+func (h *AdminHandler) GetAuditEntries(ctx *fiber.Ctx) error {
+	query := dto.AuditQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
+
+	result, err := h.catalogueService.GetAuditEntries(query)
+	if err != nil {
+		return handleListError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Audit entries fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
@@ -31,7 +31,7 @@ func (h *BankHandler) GetBanks(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"success": false,
 			"message": "Bank service is not available",
-			"error":   "FLUTTERWAVE_SECRET_KEY is not configured",
+			"error":   "BANK_VERIFIER is not configured",
 		})
 	}
 
@@ -57,7 +57,7 @@ func (h *BankHandler) VerifyAccount(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"success": false,
 			"message": "Bank service is not available",
-			"error":   "FLUTTERWAVE_SECRET_KEY is not configured",
+			"error":   "BANK_VERIFIER is not configured",
 		})
 	}
 
@@ -2,29 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-ecommerce-app/config"
 	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/storage"
+	"io"
+	"mime/multipart"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// MaxImagesPerUpload caps how many files one upload request may carry
+const MaxImagesPerUpload = 5
+
+var imageUploadPath = regexp.MustCompile(`(?i)^/seller/products/[^/]+/images/?$`)
+
+// IsImageUpload reports whether the request is a product image upload, the only route that
+// accepts bodies above the default limit
+func IsImageUpload(ctx *fiber.Ctx) bool {
+	return ctx.Method() == fiber.MethodPost && imageUploadPath.MatchString(ctx.Path())
+}
+
 type CatalogueHandler struct {
 	catalogueService service.CatalogueService
 	auth             helper.Auth
 	config           config.AppConfig
 }
 
-func SetupCatalogueRoutes(restHandler *rest.RestHandler, bankService *service.BankService) {
+func SetupCatalogueRoutes(restHandler *rest.RestHandler, bankService *service.BankService, notificationClient notification.NotificationClient, fileStorage storage.Storage) {
 	app := restHandler.App
 
 	catalogueRepo := repository.NewCatalogueRepository(restHandler.DB)
 	userRepo := repository.NewUserRepository(restHandler.DB)
-	catalogueService := service.NewCatalogueService(catalogueRepo, restHandler.Auth, restHandler.Config)
+	roleRepo := repository.NewRoleRepository(restHandler.DB)
+	auditRepo := repository.NewAuditRepository(restHandler.DB)
+	wishlistService := newWishlistService(restHandler, notificationClient)
+	catalogueService := service.NewCatalogueService(catalogueRepo, auditRepo, roleRepo, wishlistService, restHandler.Auth, restHandler.Config, fileStorage)
 	handler := CatalogueHandler{
 		catalogueService: catalogueService,
 		auth:             restHandler.Auth,
@@ -35,12 +59,15 @@ func SetupCatalogueRoutes(restHandler *rest.RestHandler, bankService *service.Ba
 	app.Get("/products", handler.GetProducts)
 	app.Get("/products/:id", handler.GetProductByID)
 	app.Get("/categories", handler.GetCategories)
+	app.Get("/categories/tree", handler.GetCategoryTree)
 	app.Get("/categories/:id", handler.GetCategoryByID)
+	app.Get("/categories/:id/breadcrumbs", handler.GetBreadcrumbs)
 
 	// Private endpoints (authentication required - seller only)
 	sellerPrivateRoutes := app.Group("/seller", restHandler.Auth.AuthorizeSeller(userRepo))
 	sellerPrivateRoutes.Post("/categories", handler.CreateCategory)
 	sellerPrivateRoutes.Patch("/categories/:id", handler.UpdateCategory)
+	sellerPrivateRoutes.Post("/categories/:id/move", handler.MoveCategory)
 	sellerPrivateRoutes.Delete("/categories/:id", handler.DeleteCategory)
 	sellerPrivateRoutes.Get("/categories/:id", handler.GetCategoryByID)
 
@@ -50,6 +77,12 @@ func SetupCatalogueRoutes(restHandler *rest.RestHandler, bankService *service.Ba
 	sellerPrivateRoutes.Put("/products/:id", handler.UpdateProduct)
 	sellerPrivateRoutes.Patch("/products/:id", handler.PatchProduct)
 	sellerPrivateRoutes.Delete("/products/:id", handler.DeleteProduct)
+	sellerPrivateRoutes.Post("/products/:id/variants", handler.CreateVariant)
+	sellerPrivateRoutes.Patch("/products/:id/variants/:variant_id", handler.UpdateVariant)
+	sellerPrivateRoutes.Delete("/products/:id/variants/:variant_id", handler.DeleteVariant)
+	sellerPrivateRoutes.Post("/products/:id/images", handler.UploadProductImages)
+	sellerPrivateRoutes.Put("/products/:id/images/order", handler.ReorderProductImages)
+	sellerPrivateRoutes.Delete("/products/:id/images/:image_id", handler.DeleteProductImage)
 }
 
 // Category Handlers
@@ -96,26 +129,17 @@ func (h *CatalogueHandler) GetCategories(ctx *fiber.Ctx) error {
 	if query.Skip < 0 {
 		query.Skip = 0
 	}
-
-	if beginningStr := ctx.Query("beginning"); beginningStr != "" {
-		beginning, err := time.Parse(time.RFC3339, beginningStr)
-		if err != nil {
-			return helper.HandleValidationError(ctx, "Invalid beginning date format. Use ISO 8601 format (e.g., 2024-01-01T00:00:00Z)")
-		}
-		query.Beginning = &beginning
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
 	}
 
-	if endingStr := ctx.Query("ending"); endingStr != "" {
-		ending, err := time.Parse(time.RFC3339, endingStr)
-		if err != nil {
-			return helper.HandleValidationError(ctx, "Invalid ending date format. Use ISO 8601 format (e.g., 2024-02-01T00:00:00Z)")
-		}
-		query.Ending = &ending
+	if err := parseListFilters(ctx, &query.ListFilters); err != nil {
+		return helper.HandleValidationError(ctx, err.Error())
 	}
 
 	result, err := h.catalogueService.GetCategories(query)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleListError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -136,13 +160,72 @@ func (h *CatalogueHandler) GetCategoryByID(ctx *fiber.Ctx) error {
 		return helper.HandleDBError(ctx, err)
 	}
 
+	productCount, err := h.catalogueService.CountSubtreeProducts(uint(id))
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	breadcrumbs, err := h.catalogueService.GetBreadcrumbs(uint(id))
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message":  "Category retrieved successfully",
-		"category": category,
+		"message":       "Category retrieved successfully",
+		"category":      category,
+		"product_count": productCount,
+		"breadcrumbs":   breadcrumbs,
+	})
+}
+
+// GetCategoryTree returns the nested category hierarchy, optionally only below root_id
+func (h *CatalogueHandler) GetCategoryTree(ctx *fiber.Ctx) error {
+	var rootID *uint
+	if raw := ctx.Query("root_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return helper.HandleValidationError(ctx, "Invalid 'root_id'")
+		}
+		root := uint(id)
+		rootID = &root
+	}
+
+	tree, err := h.catalogueService.GetCategoryTree(rootID)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Category tree retrieved successfully",
+		"tree":    tree,
+	})
+}
+
+func (h *CatalogueHandler) GetBreadcrumbs(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid category ID")
+	}
+
+	breadcrumbs, err := h.catalogueService.GetBreadcrumbs(uint(id))
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Breadcrumbs retrieved successfully",
+		"breadcrumbs": breadcrumbs,
 	})
 }
 
 func (h *CatalogueHandler) UpdateCategory(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+	if user.ID == 0 {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
 	id, err := ctx.ParamsInt("id")
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid category ID")
@@ -155,9 +238,9 @@ func (h *CatalogueHandler) UpdateCategory(ctx *fiber.Ctx) error {
 		})
 	}
 
-	updatedCategory, err := h.catalogueService.UpdateCategory(uint(id), category)
+	updatedCategory, err := h.catalogueService.UpdateCategory(uint(id), user.ID, category)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleCatalogueError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -166,15 +249,59 @@ func (h *CatalogueHandler) UpdateCategory(ctx *fiber.Ctx) error {
 	})
 }
 
+// MoveCategory re-parents a category with its whole subtree; a null parent_id makes it top level
+func (h *CatalogueHandler) MoveCategory(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+	if user.ID == 0 {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid category ID")
+	}
+
+	request := dto.MoveCategoryRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	category, err := h.catalogueService.MoveCategory(uint(id), user.ID, request)
+	if err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Category moved successfully",
+		"category": category,
+	})
+}
+
+// DeleteCategory removes a category. Child categories are deleted with ?children=cascade,
+// or moved to its parent (or to reassign_to) with ?children=reassign.
 func (h *CatalogueHandler) DeleteCategory(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+	if user.ID == 0 {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
 	id, err := ctx.ParamsInt("id")
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid category ID")
 	}
 
-	err = h.catalogueService.DeleteCategory(uint(id))
+	options := dto.DeleteCategoryOptions{}
+	if err := ctx.QueryParser(&options); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	err = h.catalogueService.DeleteCategory(uint(id), user.ID, options)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleCatalogueError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -198,23 +325,23 @@ func (h *CatalogueHandler) CreateProduct(ctx *fiber.Ctx) error {
 		return helper.HandleBodyParserError(ctx, err)
 	}
 
-	// Validate required fields
+	// Validate required fields; products with variants take their price from the SKUs
 	if product.Name == "" {
 		return helper.HandleValidationError(ctx, "Field 'name' is required")
 	}
-	if product.Price <= 0 {
+	if product.Price <= 0 && len(product.Variants) == 0 {
 		return helper.HandleValidationError(ctx, "Field 'price' must be greater than 0")
 	}
 	if product.CategoryID == 0 {
 		return helper.HandleValidationError(ctx, "Field 'category_id' is required and must be a valid category ID")
 	}
-	if product.Stock < 0 {
+	if product.Stock != nil && *product.Stock < 0 {
 		return helper.HandleValidationError(ctx, "Field 'stock' cannot be negative")
 	}
 
 	createdProduct, err := h.catalogueService.CreateProduct(user.ID, product)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleCatalogueError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -236,32 +363,37 @@ func (h *CatalogueHandler) GetProducts(ctx *fiber.Ctx) error {
 	if query.Skip < 0 {
 		query.Skip = 0
 	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
 
-	if beginningStr := ctx.Query("beginning"); beginningStr != "" {
-		beginning, err := time.Parse(time.RFC3339, beginningStr)
-		if err != nil {
-			return helper.HandleValidationError(ctx, "Invalid beginning date format. Use ISO 8601 format (e.g., 2024-01-01T00:00:00Z)")
-		}
-		query.Beginning = &beginning
+	if err := parseListFilters(ctx, &query.ListFilters); err != nil {
+		return helper.HandleValidationError(ctx, err.Error())
 	}
 
-	if endingStr := ctx.Query("ending"); endingStr != "" {
-		ending, err := time.Parse(time.RFC3339, endingStr)
-		if err != nil {
-			return helper.HandleValidationError(ctx, "Invalid ending date format. Use ISO 8601 format (e.g., 2024-02-01T00:00:00Z)")
-		}
-		query.Ending = &ending
+	if query.MinPrice != nil && *query.MinPrice < 0 {
+		return helper.HandleValidationError(ctx, "Parameter 'min_price' cannot be negative")
+	}
+	if query.MaxPrice != nil && *query.MaxPrice < 0 {
+		return helper.HandleValidationError(ctx, "Parameter 'max_price' cannot be negative")
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return helper.HandleValidationError(ctx, "Parameter 'min_price' cannot be greater than 'max_price'")
+	}
+	if !dto.IsValidProductSort(query.Sort) {
+		return helper.HandleValidationError(ctx, "Invalid sort. Use relevance, newest, price_asc, price_desc, name or popularity")
 	}
 
 	result, err := h.catalogueService.GetProducts(query)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleListError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message":    "Products retrieved successfully",
 		"data":       result.Data,
 		"pagination": result.Pagination,
+		"facets":     result.Facets,
 	})
 }
 
@@ -310,13 +442,13 @@ func (h *CatalogueHandler) UpdateProduct(ctx *fiber.Ctx) error {
 	if product.CategoryID == 0 {
 		return helper.HandleValidationError(ctx, "Field 'category_id' is required and must be a valid category ID")
 	}
-	if product.Stock < 0 {
+	if product.Stock != nil && *product.Stock < 0 {
 		return helper.HandleValidationError(ctx, "Field 'stock' cannot be negative")
 	}
 
 	updatedProduct, err := h.catalogueService.UpdateProduct(uint(id), user.ID, product)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleCatalogueError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -376,15 +508,42 @@ func (h *CatalogueHandler) PatchProduct(ctx *fiber.Ctx) error {
 			return helper.HandleValidationError(ctx, "Field 'category_id' must be a valid category ID")
 		}
 	}
-	if _, provided := bodyMap["stock"]; provided {
-		if product.Stock < 0 {
+	_, stockProvided := bodyMap["stock"]
+	if stockProvided {
+		if product.Stock == nil {
+			return helper.HandleValidationError(ctx, "Field 'stock' must be a number")
+		}
+		if *product.Stock < 0 {
 			return helper.HandleValidationError(ctx, "Field 'stock' cannot be negative")
 		}
 	}
 
-	updatedProduct, err := h.catalogueService.UpdateProduct(uint(id), user.ID, product)
-	if err != nil {
-		return helper.HandleDBError(ctx, err)
+	// stock is set on its own, per SKU when "sku" is given
+	stockRequest := dto.UpdateStockRequest{}
+	if err := json.Unmarshal(bodyBytes, &stockRequest); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+	if _, provided := bodyMap["sku"]; provided && !stockProvided {
+		return helper.HandleValidationError(ctx, "Field 'stock' is required when 'sku' is given")
+	}
+
+	var updatedProduct interface{}
+	if stockProvided {
+		updatedProduct, err = h.catalogueService.UpdateStock(uint(id), user.ID, stockRequest)
+		if err != nil {
+			return handleCatalogueError(ctx, err)
+		}
+	}
+
+	delete(bodyMap, "stock")
+	delete(bodyMap, "sku")
+	if len(bodyMap) > 0 {
+		// stock was set above, on its own
+		product.Stock = nil
+		updatedProduct, err = h.catalogueService.UpdateProduct(uint(id), user.ID, product)
+		if err != nil {
+			return handleCatalogueError(ctx, err)
+		}
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -394,17 +553,264 @@ func (h *CatalogueHandler) PatchProduct(ctx *fiber.Ctx) error {
 }
 
 func (h *CatalogueHandler) DeleteProduct(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+	if user.ID == 0 {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Unauthorized",
+		})
+	}
+
 	id, err := ctx.ParamsInt("id")
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid product ID")
 	}
 
-	err = h.catalogueService.DeleteProduct(uint(id))
+	err = h.catalogueService.DeleteProduct(uint(id), user.ID)
 	if err != nil {
-		return helper.HandleDBError(ctx, err)
+		return handleCatalogueError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Product deleted successfully",
 	})
 }
+
+// Variant Handlers
+
+func (h *CatalogueHandler) CreateVariant(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+
+	user := h.auth.GetCurrentUser(ctx)
+
+	variant := dto.Variant{}
+	if err := ctx.BodyParser(&variant); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	createdVariant, err := h.catalogueService.CreateVariant(uint(id), user.ID, variant)
+	if err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Variant created successfully",
+		"variant": createdVariant,
+	})
+}
+
+func (h *CatalogueHandler) UpdateVariant(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+	variantID, err := ctx.ParamsInt("variant_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
+
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.UpdateVariantRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	updatedVariant, err := h.catalogueService.UpdateVariant(uint(id), uint(variantID), user.ID, request)
+	if err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Variant updated successfully",
+		"variant": updatedVariant,
+	})
+}
+
+func (h *CatalogueHandler) DeleteVariant(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+	variantID, err := ctx.ParamsInt("variant_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
+
+	user := h.auth.GetCurrentUser(ctx)
+
+	if err := h.catalogueService.DeleteVariant(uint(id), uint(variantID), user.ID); err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Variant deleted successfully",
+	})
+}
+
+// Image Handlers
+
+// UploadProductImages accepts a multipart form with one or more files in the "images" field
+func (h *CatalogueHandler) UploadProductImages(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+
+	user := h.auth.GetCurrentUser(ctx)
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Request must be multipart/form-data with files in the 'images' field")
+	}
+	files := form.File["images"]
+	if len(files) == 0 {
+		return helper.HandleValidationError(ctx, "Field 'images' must contain at least one file")
+	}
+	if len(files) > MaxImagesPerUpload {
+		return helper.HandleValidationError(ctx, fmt.Sprintf("At most %d images can be uploaded at once", MaxImagesPerUpload))
+	}
+
+	uploads := make([]dto.ImageUpload, 0, len(files))
+	for _, file := range files {
+		if file.Size > h.config.UploadMaxBytes {
+			return ctx.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"message": fmt.Sprintf("%s is larger than %d MB", file.Filename, h.config.UploadMaxBytes>>20),
+				"error":   "File too large",
+			})
+		}
+		if _, ok := storage.Extensions[file.Header.Get("Content-Type")]; !ok {
+			return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"message": fmt.Sprintf("%s is not a JPEG, PNG or GIF image", file.Filename),
+				"error":   "Unsupported media type",
+			})
+		}
+
+		data, err := readUpload(file, h.config.UploadMaxBytes)
+		if err != nil {
+			return helper.HandleValidationError(ctx, fmt.Sprintf("Could not read %s: %v", file.Filename, err))
+		}
+		uploads = append(uploads, dto.ImageUpload{Filename: file.Filename, Data: data})
+	}
+
+	images, err := h.catalogueService.AddProductImages(uint(id), user.ID, uploads)
+	if err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Images uploaded successfully",
+		"images":  images,
+	})
+}
+
+func (h *CatalogueHandler) ReorderProductImages(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.ReorderImagesRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	images, err := h.catalogueService.ReorderProductImages(uint(id), user.ID, request)
+	if err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Images reordered successfully",
+		"images":  images,
+	})
+}
+
+func (h *CatalogueHandler) DeleteProductImage(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+	imageID, err := ctx.ParamsInt("image_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid image ID")
+	}
+
+	user := h.auth.GetCurrentUser(ctx)
+
+	if err := h.catalogueService.DeleteProductImage(uint(id), uint(imageID), user.ID); err != nil {
+		return handleCatalogueError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Image deleted successfully",
+	})
+}
+
+// readUpload reads a multipart file, refusing to read more than limit bytes
+func readUpload(file *multipart.FileHeader, limit int64) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errors.New("file is too large")
+	}
+	return data, nil
+}
+
+// parseListFilters reads the date range the catalogue listings accept, in ISO 8601 format
+func parseListFilters(ctx *fiber.Ctx, filters *dto.ListFilters) error {
+	if beginningStr := ctx.Query("beginning"); beginningStr != "" {
+		beginning, err := time.Parse(time.RFC3339, beginningStr)
+		if err != nil {
+			return errors.New("Invalid beginning date format. Use ISO 8601 format (e.g., 2024-01-01T00:00:00Z)")
+		}
+		filters.Beginning = &beginning
+	}
+
+	if endingStr := ctx.Query("ending"); endingStr != "" {
+		ending, err := time.Parse(time.RFC3339, endingStr)
+		if err != nil {
+			return errors.New("Invalid ending date format. Use ISO 8601 format (e.g., 2024-02-01T00:00:00Z)")
+		}
+		filters.Ending = &ending
+	}
+
+	return nil
+}
+
+func handleCatalogueError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUnsupportedImage):
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"message": err.Error(),
+			"error":   "Unsupported media type",
+		})
+	case errors.Is(err, domain.ErrTooManyImages):
+		return helper.HandleConflictError(ctx, "The product gallery is full", err)
+	case errors.Is(err, domain.ErrInvalidImageOrder):
+		return helper.HandleValidationError(ctx, err.Error())
+	case errors.Is(err, domain.ErrForbidden):
+		return helper.HandleForbiddenError(ctx, err.Error())
+	case errors.Is(err, domain.ErrInvalidVariant), errors.Is(err, domain.ErrInvalidDeleteOption):
+		return helper.HandleValidationError(ctx, err.Error())
+	case errors.Is(err, domain.ErrCategoryCycle):
+		return helper.HandleConflictError(ctx, "The category cannot be moved there", err)
+	case errors.Is(err, domain.ErrCategoryHasChildren), errors.Is(err, domain.ErrCategoryHasProducts):
+		return helper.HandleConflictError(ctx, "The category cannot be deleted", err)
+	case strings.Contains(err.Error(), "duplicate key") && strings.Contains(err.Error(), "sku"):
+		return helper.HandleConflictError(ctx, "A variant with this SKU already exists", err)
+	}
+	return helper.HandleDBError(ctx, err)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CouponHandler struct {
+	couponService service.CouponService
+	auth          helper.Auth
+	config        config.AppConfig
+}
+
+func SetupCouponRoutes(restHandler *rest.RestHandler) {
+	app := restHandler.App
+	auth := restHandler.Auth
+
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	roleRepo := repository.NewRoleRepository(restHandler.DB)
+	couponService := service.NewCouponService(repository.NewCouponRepository(restHandler.DB), repository.NewCatalogueRepository(restHandler.DB), roleRepo)
+	handler := CouponHandler{
+		couponService: couponService,
+		auth:          auth,
+		config:        restHandler.Config,
+	}
+
+	// Sellers manage coupons for their own items
+	sellerPrivateRoutes := app.Group("/seller", auth.AuthorizeSeller(userRepo))
+	sellerPrivateRoutes.Post("/coupons", handler.CreateCoupon)
+	sellerPrivateRoutes.Get("/coupons", handler.GetCoupons)
+	sellerPrivateRoutes.Get("/coupons/:id", handler.GetCoupon)
+	sellerPrivateRoutes.Patch("/coupons/:id", handler.UpdateCoupon)
+	sellerPrivateRoutes.Delete("/coupons/:id", handler.DeleteCoupon)
+
+	// Promotion admins manage store-wide coupons and anyone else's
+	managePromotions := auth.RequirePermission(roleRepo, domain.PERMISSION_PROMOTIONS_MANAGE)
+	adminRoutes := app.Group("/admin")
+	adminRoutes.Post("/coupons", managePromotions, handler.CreateCoupon)
+	adminRoutes.Get("/coupons", managePromotions, handler.GetCoupons)
+	adminRoutes.Get("/coupons/:id", managePromotions, handler.GetCoupon)
+	adminRoutes.Patch("/coupons/:id", managePromotions, handler.UpdateCoupon)
+	adminRoutes.Delete("/coupons/:id", managePromotions, handler.DeleteCoupon)
+}
+
+func (h *CouponHandler) CreateCoupon(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.CreateCouponRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	coupon, err := h.couponService.CreateCoupon(user.ID, request)
+	if err != nil {
+		return handleCouponError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Coupon created successfully",
+		"coupon":  coupon,
+	})
+}
+
+func (h *CouponHandler) GetCoupons(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query := dto.CouponQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
+
+	result, err := h.couponService.GetCoupons(user.ID, query)
+	if err != nil {
+		return handleListError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Coupons fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *CouponHandler) GetCoupon(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid coupon ID")
+	}
+
+	coupon, err := h.couponService.GetCoupon(uint(id), user.ID)
+	if err != nil {
+		return handleCouponError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Coupon fetched successfully",
+		"coupon":  coupon,
+	})
+}
+
+func (h *CouponHandler) UpdateCoupon(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid coupon ID")
+	}
+
+	request := dto.UpdateCouponRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	coupon, err := h.couponService.UpdateCoupon(uint(id), user.ID, request)
+	if err != nil {
+		return handleCouponError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Coupon updated successfully",
+		"coupon":  coupon,
+	})
+}
+
+// DeleteCoupon removes a coupon nobody has used yet; a redeemed one can only be deactivated
+func (h *CouponHandler) DeleteCoupon(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid coupon ID")
+	}
+
+	if err := h.couponService.DeleteCoupon(uint(id), user.ID); err != nil {
+		return handleCouponError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Coupon deleted successfully",
+	})
+}
+
+func handleCouponError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCoupon):
+		return helper.HandleValidationError(ctx, err.Error())
+	case errors.Is(err, domain.ErrForbidden):
+		return helper.HandleForbiddenError(ctx, err.Error())
+	case errors.Is(err, domain.ErrCouponRedeemed):
+		return helper.HandleConflictError(ctx, "The coupon has been used and can only be deactivated", err)
+	case strings.Contains(err.Error(), "duplicate key") && strings.Contains(err.Error(), "code"):
+		return helper.HandleConflictError(ctx, "A coupon with this code already exists", err)
+	}
+	return helper.HandleDBError(ctx, err)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CartTokenHeader carries a guest's cart token. Sent with login or register, it merges the
+// guest cart into the user's cart.
+const CartTokenHeader = "X-Cart-Token"
+
+type GuestCartHandler struct {
+	guestCartService service.GuestCartService
+	config           config.AppConfig
+}
+
+func newGuestCartService(restHandler *rest.RestHandler) service.GuestCartService {
+	return service.NewGuestCartService(
+		repository.NewGuestCartRepository(restHandler.DB),
+		repository.NewUserRepository(restHandler.DB),
+		repository.NewCatalogueRepository(restHandler.DB),
+		restHandler.Auth,
+		restHandler.Config,
+	)
+}
+
+func SetupGuestCartRoutes(restHandler *rest.RestHandler) {
+	app := restHandler.App
+
+	handler := GuestCartHandler{
+		guestCartService: newGuestCartService(restHandler),
+		config:           restHandler.Config,
+	}
+
+	// Public endpoints: the cart token stands in for a login
+	app.Get("/guest/cart", handler.GetCart)
+	app.Post("/guest/cart", handler.AddToCart)
+	app.Post("/guest/cart/acknowledge", handler.AcknowledgeChanges)
+	app.Put("/guest/cart", handler.UpdateCart)
+	app.Delete("/guest/cart/:product_id", handler.DeleteCartItem)
+}
+
+func (h *GuestCartHandler) GetCart(ctx *fiber.Ctx) error {
+	cartItems, unavailable, err := h.guestCartService.GetCart(ctx.Get(CartTokenHeader))
+	if err != nil {
+		return handleGuestCartError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Cart items fetched successfully",
+		"cart":        cartItems,
+		"unavailable": unavailable,
+	})
+}
+
+// AcknowledgeChanges accepts the new prices the cart warned about and removes unavailable lines
+func (h *GuestCartHandler) AcknowledgeChanges(ctx *fiber.Ctx) error {
+	cartItems, err := h.guestCartService.AcknowledgeChanges(ctx.Get(CartTokenHeader))
+	if err != nil {
+		return handleGuestCartError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Cart changes acknowledged",
+		"cart":    cartItems,
+	})
+}
+
+// AddToCart starts a guest cart with the first item; the cart_token in the response must be
+// sent in the X-Cart-Token header from then on
+func (h *GuestCartHandler) AddToCart(ctx *fiber.Ctx) error {
+	var request dto.CreateCartRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+		})
+	}
+
+	token, cartItem, err := h.guestCartService.AddItem(ctx.Get(CartTokenHeader), request)
+	if err != nil {
+		return handleGuestCartError(ctx, err)
+	}
+
+	ctx.Set(CartTokenHeader, token)
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Item added to cart successfully",
+		"cart":       cartItem,
+		"cart_token": token,
+	})
+}
+
+func (h *GuestCartHandler) UpdateCart(ctx *fiber.Ctx) error {
+	var request dto.UpdateCartRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+		})
+	}
+
+	cartItem, err := h.guestCartService.UpdateItem(ctx.Get(CartTokenHeader), request)
+	if err != nil {
+		return handleGuestCartError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Cart item updated successfully",
+		"cart":    cartItem,
+	})
+}
+
+func (h *GuestCartHandler) DeleteCartItem(ctx *fiber.Ctx) error {
+	productID, err := ctx.ParamsInt("product_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+	variantID, err := cartVariantID(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
+
+	if err := h.guestCartService.DeleteItem(ctx.Get(CartTokenHeader), uint(productID), variantID); err != nil {
+		return handleGuestCartError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Cart item deleted successfully",
+	})
+}
+
+func handleGuestCartError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCartToken):
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Cart not found",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, domain.ErrInvalidQuantity):
+		return helper.HandleValidationError(ctx, err.Error())
+	case errors.Is(err, domain.ErrVariantRequired):
+		return helper.HandleValidationError(ctx, "Field 'variant_id' is required for products with variants")
+	case err.Error() == "product ID is required":
+		return helper.HandleValidationError(ctx, "Product ID is required")
+	case err.Error() == "product not found", err.Error() == "product variant not found":
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Product not found",
+			"error":   err.Error(),
+		})
+	case err.Error() == "cart item not found":
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Cart item not found",
+		})
+	}
+	return helper.HandleDBError(ctx, err)
+}
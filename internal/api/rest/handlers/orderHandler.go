@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/notification"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrderHandler struct {
+	orderService service.OrderService
+	auth         helper.Auth
+	config       config.AppConfig
+}
+
+func SetupOrderRoutes(restHandler *rest.RestHandler, notificationClient notification.NotificationClient) {
+	app := restHandler.App
+
+	orderRepo := repository.NewOrderRepository(restHandler.DB)
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	orderService := service.NewOrderService(orderRepo, restHandler.Auth, restHandler.Config, service.NewNotificationService(notificationClient, userRepo))
+	handler := OrderHandler{
+		orderService: orderService,
+		auth:         restHandler.Auth,
+		config:       restHandler.Config,
+	}
+
+	// Private endpoints (authentication required)
+	privateRoutes := app.Group("/", restHandler.Auth.Authorize)
+	privateRoutes.Post("/orders/:id/cancel", handler.CancelOrder)
+
+	// Private endpoints (authentication required - seller only)
+	sellerPrivateRoutes := app.Group("/seller", restHandler.Auth.AuthorizeSeller(userRepo))
+	sellerPrivateRoutes.Get("/orders", handler.GetSellerOrders)
+	sellerPrivateRoutes.Get("/orders/:id", handler.GetSellerOrder)
+	sellerPrivateRoutes.Patch("/orders/:id/status", handler.UpdateOrderStatus)
+}
+
+func (h *OrderHandler) GetSellerOrders(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query := dto.OrderQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
+
+	result, err := h.orderService.GetSellerOrders(user.ID, query)
+	if err != nil {
+		return handleListError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Orders fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *OrderHandler) GetSellerOrder(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid order ID")
+	}
+
+	order, err := h.orderService.GetSellerOrder(user.ID, uint(id))
+	if err != nil {
+		return handleOrderError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Order fetched successfully",
+		"order":   order,
+	})
+}
+
+func (h *OrderHandler) UpdateOrderStatus(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid order ID")
+	}
+
+	request := dto.UpdateOrderStatusRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	if request.Status == "" {
+		return helper.HandleValidationError(ctx, "Field 'status' is required")
+	}
+
+	order, err := h.orderService.UpdateOrderStatus(user.ID, uint(id), request)
+	if err != nil {
+		return handleOrderError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Order status updated successfully",
+		"order":   order,
+	})
+}
+
+func (h *OrderHandler) CancelOrder(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid order ID")
+	}
+
+	order, err := h.orderService.CancelOrder(user.ID, uint(id))
+	if err != nil {
+		return handleOrderError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Order cancelled successfully",
+		"order":   order,
+	})
+}
+
+func handleOrderError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidTransition):
+		return helper.HandleConflictError(ctx, "Order status cannot be changed", err)
+	case errors.Is(err, domain.ErrForbidden):
+		return helper.HandleForbiddenError(ctx, "You can only manage orders for your own products")
+	}
+	return helper.HandleDBError(ctx, err)
+}
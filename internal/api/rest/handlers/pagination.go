@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/helper"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// handleListError answers a failed listing, reporting a bad pagination cursor as a client error
+func handleListError(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return helper.HandleValidationError(ctx, err.Error())
+	}
+	return helper.HandleDBError(ctx, err)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/payment"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PayoutHandler struct {
+	payoutService service.PayoutService
+	auth          helper.Auth
+	config        config.AppConfig
+}
+
+func SetupPayoutRoutes(restHandler *rest.RestHandler, paymentProvider payment.PaymentProvider, notificationClient notification.NotificationClient) {
+	app := restHandler.App
+
+	payoutRepo := repository.NewPayoutRepository(restHandler.DB)
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	payoutService := service.NewPayoutService(payoutRepo, userRepo, restHandler.Config, service.NewNotificationService(notificationClient, userRepo), paymentProvider)
+	handler := PayoutHandler{
+		payoutService: payoutService,
+		auth:          restHandler.Auth,
+		config:        restHandler.Config,
+	}
+
+	// Private endpoints (authentication required - seller only)
+	sellerPrivateRoutes := app.Group("/seller", restHandler.Auth.AuthorizeSeller(userRepo))
+	sellerPrivateRoutes.Get("/balance", handler.GetBalance)
+	sellerPrivateRoutes.Get("/payouts", handler.GetPayouts)
+}
+
+func (h *PayoutHandler) GetBalance(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	balance, err := h.payoutService.GetSellerBalance(user.ID)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Balance fetched successfully",
+		"balance": balance,
+	})
+}
+
+func (h *PayoutHandler) GetPayouts(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query := dto.PaginationParams{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+
+	result, err := h.payoutService.GetSellerPayouts(user.ID, query)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Payouts fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
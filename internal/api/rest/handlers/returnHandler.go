@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/payment"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxReturnImages caps how many photos a buyer can attach to a return
+const maxReturnImages = 5
+
+type ReturnHandler struct {
+	returnService service.ReturnService
+	auth          helper.Auth
+	config        config.AppConfig
+}
+
+func SetupReturnRoutes(restHandler *rest.RestHandler, paymentProvider payment.PaymentProvider) {
+	app := restHandler.App
+
+	returnRepo := repository.NewReturnRepository(restHandler.DB)
+	orderRepo := repository.NewOrderRepository(restHandler.DB)
+	paymentRepo := repository.NewPaymentRepository(restHandler.DB)
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	returnService := service.NewReturnService(returnRepo, orderRepo, paymentRepo, restHandler.Config, paymentProvider)
+	handler := ReturnHandler{
+		returnService: returnService,
+		auth:          restHandler.Auth,
+		config:        restHandler.Config,
+	}
+
+	// Private endpoints (authentication required)
+	privateRoutes := app.Group("/", restHandler.Auth.Authorize)
+	privateRoutes.Post("/returns", handler.CreateReturn)
+	privateRoutes.Get("/returns", handler.GetReturns)
+	privateRoutes.Get("/returns/:id", handler.GetReturn)
+
+	// Private endpoints (authentication required - seller only)
+	sellerPrivateRoutes := app.Group("/seller", restHandler.Auth.AuthorizeSeller(userRepo))
+	sellerPrivateRoutes.Get("/returns", handler.GetSellerReturns)
+	sellerPrivateRoutes.Get("/returns/:id", handler.GetSellerReturn)
+	sellerPrivateRoutes.Post("/returns/:id/approve", handler.ApproveReturn)
+	sellerPrivateRoutes.Post("/returns/:id/reject", handler.RejectReturn)
+}
+
+func (h *ReturnHandler) CreateReturn(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.CreateReturnRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.OrderItemID == 0 {
+		return helper.HandleValidationError(ctx, "Field 'order_item_id' is required")
+	}
+	if request.Quantity < 1 {
+		return helper.HandleValidationError(ctx, "Field 'quantity' must be at least 1")
+	}
+	if request.Reason == "" {
+		return helper.HandleValidationError(ctx, "Field 'reason' is required")
+	}
+	if len(request.Images) > maxReturnImages {
+		return helper.HandleValidationError(ctx, "A return can have at most 5 images")
+	}
+
+	returnRequest, err := h.returnService.CreateReturn(user.ID, request)
+	if err != nil {
+		return handleReturnError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Return requested successfully",
+		"return":  returnRequest,
+	})
+}
+
+func (h *ReturnHandler) GetReturns(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query, err := parseReturnQuery(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	result, err := h.returnService.GetReturns(user.ID, query)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Returns fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *ReturnHandler) GetReturn(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid return ID")
+	}
+
+	returnRequest, err := h.returnService.GetReturn(user.ID, uint(id))
+	if err != nil {
+		return handleReturnError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Return fetched successfully",
+		"return":  returnRequest,
+	})
+}
+
+func (h *ReturnHandler) GetSellerReturns(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query, err := parseReturnQuery(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	result, err := h.returnService.GetSellerReturns(user.ID, query)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Returns fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *ReturnHandler) GetSellerReturn(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid return ID")
+	}
+
+	returnRequest, err := h.returnService.GetSellerReturn(user.ID, uint(id))
+	if err != nil {
+		return handleReturnError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Return fetched successfully",
+		"return":  returnRequest,
+	})
+}
+
+func (h *ReturnHandler) ApproveReturn(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid return ID")
+	}
+
+	request := dto.ApproveReturnRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			return helper.HandleBodyParserError(ctx, err)
+		}
+	}
+
+	returnRequest, err := h.returnService.ApproveReturn(user.ID, uint(id), request)
+	if err != nil {
+		return handleReturnError(ctx, err)
+	}
+
+	switch returnRequest.Status {
+	case domain.RETURN_REFUND_FAILED:
+		return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"message": "Return approved but the provider declined the refund, approve it again to retry",
+			"error":   returnRequest.FailureReason,
+			"return":  returnRequest,
+		})
+	case domain.RETURN_REFUND_PENDING:
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Return approved, the refund is being confirmed with the payment provider",
+			"return":  returnRequest,
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Return approved and refunded successfully",
+		"return":  returnRequest,
+	})
+}
+
+func (h *ReturnHandler) RejectReturn(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid return ID")
+	}
+
+	request := dto.RejectReturnRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	if strings.TrimSpace(request.Note) == "" {
+		return helper.HandleValidationError(ctx, "Field 'note' is required to tell the buyer why")
+	}
+
+	returnRequest, err := h.returnService.RejectReturn(user.ID, uint(id), request)
+	if err != nil {
+		return handleReturnError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Return rejected successfully",
+		"return":  returnRequest,
+	})
+}
+
+func parseReturnQuery(ctx *fiber.Ctx) (dto.ReturnQuery, error) {
+	query := dto.ReturnQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return query, err
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+
+	return query, nil
+}
+
+func handleReturnError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrPaymentServiceUnavailable):
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"success": false,
+			"message": "Payment service is not available",
+			"error":   "PAYMENT_PROVIDER is not configured",
+		})
+	case errors.Is(err, domain.ErrForbidden):
+		return helper.HandleForbiddenError(ctx, "You do not have access to this return")
+	case errors.Is(err, domain.ErrInvalidRefundAmount):
+		return helper.HandleValidationError(ctx, err.Error())
+	case errors.Is(err, domain.ErrReturnNotAllowed),
+		errors.Is(err, domain.ErrReturnQuantity),
+		errors.Is(err, domain.ErrInvalidReturnState),
+		errors.Is(err, domain.ErrNoRefundablePayment):
+		return helper.HandleConflictError(ctx, "Return cannot be processed", err)
+	}
+	return helper.HandleDBError(ctx, err)
+}
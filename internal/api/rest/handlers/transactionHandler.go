@@ -1 +1,144 @@
 package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/payment"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TransactionHandler struct {
+	paymentService service.PaymentService
+	payoutService  service.PayoutService
+	auth           helper.Auth
+	config         config.AppConfig
+}
+
+// SetupTransactionRoutes must run before the route groups that guard "/" with Auth.Authorize,
+// otherwise the provider's webhook calls would be rejected for lacking a user token
+func SetupTransactionRoutes(restHandler *rest.RestHandler, paymentProvider payment.PaymentProvider, notificationClient notification.NotificationClient) {
+	app := restHandler.App
+
+	paymentRepo := repository.NewPaymentRepository(restHandler.DB)
+	orderRepo := repository.NewOrderRepository(restHandler.DB)
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	payoutRepo := repository.NewPayoutRepository(restHandler.DB)
+	notifier := service.NewNotificationService(notificationClient, userRepo)
+	paymentService := service.NewPaymentService(paymentRepo, orderRepo, userRepo, restHandler.Config, notifier, paymentProvider)
+	payoutService := service.NewPayoutService(payoutRepo, userRepo, restHandler.Config, notifier, paymentProvider)
+	handler := TransactionHandler{
+		paymentService: paymentService,
+		payoutService:  payoutService,
+		auth:           restHandler.Auth,
+		config:         restHandler.Config,
+	}
+
+	// Public endpoints (verified by provider signature)
+	app.Post("/webhooks/flutterwave", handler.FlutterwaveWebhook)
+
+	// Private endpoints (authentication required)
+	app.Post("/orders/:id/pay", restHandler.Auth.Authorize, handler.InitiatePayment)
+	app.Get("/payments/verify", restHandler.Auth.Authorize, handler.VerifyPayment)
+}
+
+func (h *TransactionHandler) InitiatePayment(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid order ID")
+	}
+
+	payment, err := h.paymentService.InitiatePayment(user.ID, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPaymentServiceUnavailable):
+			return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"success": false,
+				"message": "Payment service is not available",
+				"error":   "PAYMENT_PROVIDER is not configured",
+			})
+		case errors.Is(err, domain.ErrOrderNotPayable):
+			return helper.HandleConflictError(ctx, "Order cannot be paid", err)
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":      "Payment initiated successfully",
+		"payment":      payment,
+		"payment_link": payment.PaymentLink,
+	})
+}
+
+func (h *TransactionHandler) VerifyPayment(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	txRef := ctx.Query("tx_ref")
+	if txRef == "" {
+		return helper.HandleValidationError(ctx, "Query parameter 'tx_ref' is required")
+	}
+
+	verifiedPayment, err := h.paymentService.VerifyPayment(user.ID, txRef)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPaymentServiceUnavailable):
+			return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"success": false,
+				"message": "Payment service is not available",
+				"error":   "PAYMENT_PROVIDER is not configured",
+			})
+		case errors.Is(err, domain.ErrForbidden):
+			return helper.HandleForbiddenError(ctx, "You can only verify your own payments")
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Payment verified successfully",
+		"payment": verifiedPayment,
+	})
+}
+
+func (h *TransactionHandler) FlutterwaveWebhook(ctx *fiber.Ctx) error {
+	if !flutterwave.VerifyWebhookSignature(h.config.FlutterwaveWebhookHash, ctx.Get("verif-hash")) {
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Invalid webhook signature",
+		})
+	}
+
+	event := flutterwave.WebhookEvent{}
+	if err := json.Unmarshal(ctx.Body(), &event); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	var err error
+	if event.Event == flutterwave.EventTransferCompleted {
+		err = h.payoutService.HandleFlutterwaveTransfer(event)
+	} else {
+		err = h.paymentService.HandleFlutterwaveWebhook(event)
+	}
+
+	if err != nil {
+		log.Printf("Failed to process Flutterwave %s webhook: %v", event.Event, err)
+		// a non 2xx response makes Flutterwave retry the delivery later
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to process webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Webhook received",
+	})
+}
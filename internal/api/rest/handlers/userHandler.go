@@ -1,45 +1,65 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"strconv"
 	"strings"
 
 	"go-ecommerce-app/config"
 	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/notification"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserHandler struct {
 	// service UserService
-	userService service.UserService
-	auth        helper.Auth
-	config      config.AppConfig
+	userService      service.UserService
+	authService      service.AuthService
+	roleService      service.RoleService
+	guestCartService service.GuestCartService
+	auth             helper.Auth
+	config           config.AppConfig
 }
 
-func SetupUserRoutes(restHandler *rest.RestHandler, bankService *service.BankService) {
+func SetupUserRoutes(restHandler *rest.RestHandler, bankService *service.BankService, notificationClient notification.NotificationClient) {
 	app := restHandler.App
 
 	//create an instance of user repository and inject to service
 	userRepo := repository.NewUserRepository(restHandler.DB)
 	catalogueRepo := repository.NewCatalogueRepository(restHandler.DB)
-	userService := service.NewUserService(userRepo, catalogueRepo, restHandler.Auth, restHandler.Config, bankService)
+	orderRepo := repository.NewOrderRepository(restHandler.DB)
+	couponRepo := repository.NewCouponRepository(restHandler.DB)
+	tokenRepo := repository.NewTokenRepository(restHandler.DB)
+	roleRepo := repository.NewRoleRepository(restHandler.DB)
+	resetRepo := repository.NewPasswordResetRepository(restHandler.DB)
+	userService := service.NewUserService(userRepo, resetRepo, catalogueRepo, orderRepo, couponRepo, restHandler.Auth, restHandler.Config, bankService, service.NewNotificationService(notificationClient, userRepo))
+	authService := service.NewAuthService(tokenRepo, userRepo, restHandler.Auth, restHandler.Config)
 	handler := UserHandler{
-		userService: userService,
-		auth:        restHandler.Auth,
-		config:      restHandler.Config,
+		userService:      userService,
+		authService:      authService,
+		roleService:      service.NewRoleService(roleRepo, userRepo),
+		guestCartService: newGuestCartService(restHandler),
+		auth:             restHandler.Auth,
+		config:           restHandler.Config,
 	}
 
 	//public endpoints (no authentication required)
 	app.Post("/register", handler.Register)
 	app.Post("/login", handler.Login)
+	app.Post("/token/refresh", handler.RefreshToken)
+	app.Post("/password/forgot", handler.ForgotPassword)
+	app.Post("/password/reset", handler.ResetPassword)
 
 	//private endpoints (authentication required)
 	privateRoutes := app.Group("/", restHandler.Auth.Authorize)
-	privateRoutes.Get("/users", handler.GetUsers)
+	privateRoutes.Get("/users", restHandler.Auth.RequirePermission(roleRepo, domain.PERMISSION_USERS_READ), handler.GetUsers)
 	privateRoutes.Get("/users/profile", handler.GetProfile)
 	privateRoutes.Post("/users/profile", handler.CreateProfile)
 	privateRoutes.Patch("/users/profile", handler.UpdateProfile)
@@ -57,17 +77,21 @@ func SetupUserRoutes(restHandler *rest.RestHandler, bankService *service.BankSer
 	privateRoutes.Get("/addresses", handler.Addresses)
 	privateRoutes.Get("/payments", handler.Payments)
 	privateRoutes.Get("/reviews", handler.Reviews)
-	privateRoutes.Get("/wishlist", handler.Wishlist)
+	privateRoutes.Get("/cart/summary", handler.GetCartSummary)
+	privateRoutes.Post("/cart/coupon", handler.ApplyCoupon)
+	privateRoutes.Delete("/cart/coupon", handler.RemoveCoupon)
 	privateRoutes.Get("/cart/:product_id", handler.GetCartItem)
 	privateRoutes.Get("/cart", handler.GetCartItems)
+	privateRoutes.Post("/cart/acknowledge", handler.AcknowledgeCartChanges)
 	privateRoutes.Post("/cart", handler.AddToCart)
 	privateRoutes.Patch("/cart/:product_id/increment", handler.IncrementCartItem)
 	privateRoutes.Patch("/cart/:product_id/decrement", handler.DecrementCartItem)
 	privateRoutes.Put("/cart", handler.UpdateCart)
 	privateRoutes.Delete("/cart/:product_id", handler.DeleteCartItem)
 	privateRoutes.Delete("/cart", handler.ClearCart)
-	privateRoutes.Get("/checkout", handler.Checkout)
-	privateRoutes.Get("/logout", handler.Logout)
+	privateRoutes.Post("/checkout", handler.Checkout)
+	privateRoutes.Post("/logout", handler.Logout)
+	privateRoutes.Post("/logout/all", handler.LogoutAll)
 }
 
 func (h *UserHandler) Register(ctx *fiber.Ctx) error {
@@ -86,8 +110,8 @@ func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 		return helper.HandleDBError(ctx, err)
 	}
 
-	// Generate token for the newly registered user
-	token, err := h.auth.GenerateToken(createdUser.ID, createdUser.Email, createdUser.UserType)
+	// Open a session for the newly registered user
+	tokens, err := h.authService.IssueTokens(createdUser, clientInfo(ctx))
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "User registered but failed to generate token",
@@ -107,11 +131,20 @@ func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 		"updated_at": createdUser.UpdatedAt,
 	}
 
-	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "User registered successfully",
-		"user":    userResponse,
-		"token":   token,
-	})
+	response := fiber.Map{
+		"message":            "User registered successfully",
+		"user":               userResponse,
+		"token":              tokens.Token,
+		"token_type":         tokens.TokenType,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	}
+	if adjusted, merged := h.mergeGuestCart(ctx, createdUser.ID); merged {
+		response["cart_adjustments"] = adjusted
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response)
 }
 
 func (h *UserHandler) GetUsers(ctx *fiber.Ctx) error {
@@ -176,6 +209,10 @@ func (h *UserHandler) FindUserByID(ctx *fiber.Ctx) error {
 		return helper.HandleValidationError(ctx, "Invalid user ID")
 	}
 
+	if err := h.roleService.AuthorizeUserAccess(h.auth.GetCurrentUser(ctx).ID, uint(id), domain.PERMISSION_USERS_READ); err != nil {
+		return handleUserAccessError(ctx, err)
+	}
+
 	user, err := h.userService.FindUserByID(uint(id))
 	if err != nil {
 		return helper.HandleDBError(ctx, err)
@@ -205,6 +242,10 @@ func (h *UserHandler) UpdateUser(ctx *fiber.Ctx) error {
 		return helper.HandleValidationError(ctx, "Invalid user ID")
 	}
 
+	if err := h.roleService.AuthorizeUserAccess(h.auth.GetCurrentUser(ctx).ID, uint(id), domain.PERMISSION_USERS_MANAGE); err != nil {
+		return handleUserAccessError(ctx, err)
+	}
+
 	var body struct {
 		dto.UserUpdate
 		UserType *string `json:"user_type,omitempty"`
@@ -239,6 +280,10 @@ func (h *UserHandler) DeleteUser(ctx *fiber.Ctx) error {
 		return helper.HandleValidationError(ctx, "Invalid user ID")
 	}
 
+	if err := h.roleService.AuthorizeUserAccess(h.auth.GetCurrentUser(ctx).ID, uint(id), domain.PERMISSION_USERS_MANAGE); err != nil {
+		return handleUserAccessError(ctx, err)
+	}
+
 	err = h.userService.DeleteUser(uint(id))
 	if err != nil {
 		return helper.HandleDBError(ctx, err)
@@ -257,13 +302,20 @@ func (h *UserHandler) Login(ctx *fiber.Ctx) error {
 		})
 	}
 
-	user, token, err := h.userService.Login(loginData.Email, loginData.Password)
+	user, err := h.userService.Login(loginData.Email, loginData.Password)
 	if err != nil {
 		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"message": "Invalid email or password",
 		})
 	}
 
+	tokens, err := h.authService.IssueTokens(user, clientInfo(ctx))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to generate token",
+		})
+	}
+
 	// Create user response without password
 	userResponse := fiber.Map{
 		"id":         user.ID,
@@ -277,32 +329,57 @@ func (h *UserHandler) Login(ctx *fiber.Ctx) error {
 		"updated_at": user.UpdatedAt,
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "login",
-		"user":    userResponse,
-		"token":   token,
-	})
+	response := fiber.Map{
+		"message":            "login",
+		"user":               userResponse,
+		"token":              tokens.Token,
+		"token_type":         tokens.TokenType,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	}
+	if adjusted, merged := h.mergeGuestCart(ctx, user.ID); merged {
+		response["cart_adjustments"] = adjusted
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// mergeGuestCart moves the guest cart named by the X-Cart-Token header into the user's cart,
+// returning the lines that were cut back or left out for lack of stock. A failed merge does not
+// fail the login; the guest cart is kept until it expires.
+func (h *UserHandler) mergeGuestCart(ctx *fiber.Ctx, userID uint) ([]domain.CartLine, bool) {
+	token := ctx.Get(CartTokenHeader)
+	if token == "" {
+		return nil, false
+	}
+
+	adjusted, err := h.guestCartService.MergeIntoUser(token, userID)
+	if err != nil {
+		log.Printf("Failed to merge guest cart into the cart of user %d: %v", userID, err)
+		return nil, false
+	}
+	return adjusted, true
 }
 
 func (h *UserHandler) GetVerificationCode(ctx *fiber.Ctx) error {
 	user := h.auth.GetCurrentUser(ctx)
 
-	err := h.userService.GetVerificationCode(user.ID)
+	err := h.userService.GetVerificationCode(user.ID, ctx.Query("channel"))
 	if err != nil {
-		errorMessage := err.Error()
-		if strings.Contains(errorMessage, "SMS") || strings.Contains(errorMessage, "Twilio") || strings.Contains(errorMessage, "phone") {
+		if errors.Is(err, domain.ErrInvalidChannel) || errors.Is(err, domain.ErrAlreadyVerified) || errors.Is(err, domain.ErrChannelUnavailable) {
 			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"success": false,
 				"message": "Failed to send verification code",
-				"error":   errorMessage,
+				"error":   err.Error(),
 			})
 		}
 		return helper.HandleDBError(ctx, err)
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
 		"success": true,
-		"message": "Verification code sent successfully",
+		"message": "Verification code will be sent shortly",
 	})
 }
 
@@ -340,15 +417,18 @@ func (h *UserHandler) GetProfile(ctx *fiber.Ctx) error {
 	}
 
 	profileResponse := fiber.Map{
-		"id":         profile.ID,
-		"first_name": profile.FirstName,
-		"last_name":  profile.LastName,
-		"email":      profile.Email,
-		"phone":      profile.Phone,
-		"user_type":  profile.UserType,
-		"verified":   profile.Verified,
-		"created_at": profile.CreatedAt,
-		"updated_at": profile.UpdatedAt,
+		"id":                   profile.ID,
+		"first_name":           profile.FirstName,
+		"last_name":            profile.LastName,
+		"email":                profile.Email,
+		"phone":                profile.Phone,
+		"user_type":            profile.UserType,
+		"verified":             profile.Verified,
+		"phone_verified":       profile.ContactVerified(domain.CHANNEL_SMS),
+		"email_verified":       profile.EmailVerified,
+		"notification_channel": profile.NotificationChannel,
+		"created_at":           profile.CreatedAt,
+		"updated_at":           profile.UpdatedAt,
 	}
 
 	if profile.Address.ID != 0 {
@@ -442,15 +522,18 @@ func (h *UserHandler) CreateProfile(ctx *fiber.Ctx) error {
 	}
 
 	profileResponse := fiber.Map{
-		"id":         profile.ID,
-		"first_name": profile.FirstName,
-		"last_name":  profile.LastName,
-		"email":      profile.Email,
-		"phone":      profile.Phone,
-		"user_type":  profile.UserType,
-		"verified":   profile.Verified,
-		"created_at": profile.CreatedAt,
-		"updated_at": profile.UpdatedAt,
+		"id":                   profile.ID,
+		"first_name":           profile.FirstName,
+		"last_name":            profile.LastName,
+		"email":                profile.Email,
+		"phone":                profile.Phone,
+		"user_type":            profile.UserType,
+		"verified":             profile.Verified,
+		"phone_verified":       profile.ContactVerified(domain.CHANNEL_SMS),
+		"email_verified":       profile.EmailVerified,
+		"notification_channel": profile.NotificationChannel,
+		"created_at":           profile.CreatedAt,
+		"updated_at":           profile.UpdatedAt,
 	}
 
 	if profile.Address.ID != 0 {
@@ -482,6 +565,9 @@ func (h *UserHandler) UpdateProfile(ctx *fiber.Ctx) error {
 			"error":   err.Error(),
 		})
 	}
+	if profileInput.NotificationChannel != nil && !domain.IsValidChannel(*profileInput.NotificationChannel) {
+		return helper.HandleValidationError(ctx, "notification_channel must be sms or email")
+	}
 
 	profile, err := h.userService.UpdateProfile(user.ID, profileInput)
 	if err != nil {
@@ -489,15 +575,18 @@ func (h *UserHandler) UpdateProfile(ctx *fiber.Ctx) error {
 	}
 
 	profileResponse := fiber.Map{
-		"id":         profile.ID,
-		"first_name": profile.FirstName,
-		"last_name":  profile.LastName,
-		"email":      profile.Email,
-		"phone":      profile.Phone,
-		"user_type":  profile.UserType,
-		"verified":   profile.Verified,
-		"created_at": profile.CreatedAt,
-		"updated_at": profile.UpdatedAt,
+		"id":                   profile.ID,
+		"first_name":           profile.FirstName,
+		"last_name":            profile.LastName,
+		"email":                profile.Email,
+		"phone":                profile.Phone,
+		"user_type":            profile.UserType,
+		"verified":             profile.Verified,
+		"phone_verified":       profile.ContactVerified(domain.CHANNEL_SMS),
+		"email_verified":       profile.EmailVerified,
+		"notification_channel": profile.NotificationChannel,
+		"created_at":           profile.CreatedAt,
+		"updated_at":           profile.UpdatedAt,
 	}
 
 	if profile.Address.ID != 0 {
@@ -526,14 +615,51 @@ func (h *UserHandler) DeleteProfile(ctx *fiber.Ctx) error {
 	})
 }
 func (h *UserHandler) Orders(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query := dto.OrderQuery{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
+
+	result, err := h.userService.Orders(user.ID, query)
+	if err != nil {
+		return handleListError(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Orders fetched successfully",
+		"message":    "Orders fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
 	})
 }
 
 func (h *UserHandler) GetOrder(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid order ID")
+	}
+
+	order, err := h.userService.GetOrder(uint(id), user.ID)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Order fetched successfully",
+		"order":   order,
 	})
 }
 
@@ -547,7 +673,7 @@ func (h *UserHandler) BecomeSeller(ctx *fiber.Ctx) error {
 		})
 	}
 
-	updatedUser, token, err := h.userService.BecomeSeller(user.ID, becomeSellerInput)
+	updatedUser, token, err := h.userService.BecomeSeller(user.ID, h.auth.GetCurrentTokenClaims(ctx).SessionID, becomeSellerInput)
 	if err != nil {
 		return helper.HandleDBError(ctx, err)
 	}
@@ -590,26 +716,113 @@ func (h *UserHandler) Reviews(ctx *fiber.Ctx) error {
 	})
 }
 
-func (h *UserHandler) Wishlist(ctx *fiber.Ctx) error {
+// cartVariantID reads the optional variant_id query parameter that picks the cart line of one SKU
+func cartVariantID(ctx *fiber.Ctx) (*uint, error) {
+	value := ctx.Query("variant_id")
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return nil, errors.New("invalid variant ID")
+	}
+	variantID := uint(id)
+	return &variantID, nil
+}
+
+func (h *UserHandler) GetCartItems(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	cartItems, unavailable, err := h.userService.GetCart(user.ID)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Wishlist fetched successfully",
+		"message":     "Cart items fetched successfully",
+		"cart":        cartItems,
+		"unavailable": unavailable,
 	})
 }
 
-func (h *UserHandler) GetCartItems(ctx *fiber.Ctx) error {
+// AcknowledgeCartChanges accepts the new prices the cart warned about and removes the lines
+// that can no longer be bought, so the buyer can check out
+func (h *UserHandler) AcknowledgeCartChanges(ctx *fiber.Ctx) error {
 	user := h.auth.GetCurrentUser(ctx)
 
-	cartItems, err := h.userService.FindCartItems(user.ID)
+	cartItems, err := h.userService.AcknowledgeCartChanges(user.ID)
 	if err != nil {
 		return helper.HandleDBError(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Cart items fetched successfully",
+		"message": "Cart changes acknowledged",
 		"cart":    cartItems,
 	})
 }
 
+// GetCartSummary totals the cart per seller with tax and shipping; shipping_method picks the
+// method the totals use, and every method is quoted alongside
+func (h *UserHandler) GetCartSummary(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	summary, unavailable, err := h.userService.GetCartSummary(user.ID, strings.ToLower(ctx.Query("shipping_method")))
+	if err != nil {
+		if errors.Is(err, domain.ErrUnknownShippingMethod) {
+			return helper.HandleValidationError(ctx, err.Error())
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":     "Cart summary fetched successfully",
+		"summary":     summary,
+		"unavailable": unavailable,
+	})
+}
+
+// ApplyCoupon puts a discount code on the cart; it is checked again at checkout
+func (h *UserHandler) ApplyCoupon(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.ApplyCouponRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+	if strings.TrimSpace(request.Code) == "" {
+		return helper.HandleValidationError(ctx, "Field 'code' is required")
+	}
+
+	summary, err := h.userService.ApplyCoupon(user.ID, strings.TrimSpace(request.Code))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrCartEmpty):
+			return helper.HandleValidationError(ctx, "Your cart is empty")
+		case errors.Is(err, domain.ErrCouponNotApplicable), errors.Is(err, domain.ErrCouponExhausted):
+			return helper.HandleConflictError(ctx, "This coupon cannot be used on your cart", err)
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Coupon applied successfully",
+		"summary": summary,
+	})
+}
+
+func (h *UserHandler) RemoveCoupon(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	if err := h.userService.RemoveCoupon(user.ID); err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Coupon removed successfully",
+	})
+}
+
 func (h *UserHandler) GetCartItem(ctx *fiber.Ctx) error {
 	user := h.auth.GetCurrentUser(ctx)
 
@@ -617,8 +830,12 @@ func (h *UserHandler) GetCartItem(ctx *fiber.Ctx) error {
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid product ID")
 	}
+	variantID, err := cartVariantID(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
 
-	cartItem, err := h.userService.GetCartItem(user.ID, uint(productID))
+	cartItem, err := h.userService.GetCartItem(user.ID, uint(productID), variantID)
 	if err != nil {
 		if err.Error() == "cart item not found" {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -651,6 +868,14 @@ func (h *UserHandler) AddToCart(ctx *fiber.Ctx) error {
 				"message": "Product not found",
 			})
 		}
+		if err.Error() == "product variant not found" {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Product variant not found",
+			})
+		}
+		if errors.Is(err, domain.ErrVariantRequired) {
+			return helper.HandleValidationError(ctx, "Field 'variant_id' is required for products with variants")
+		}
 		return helper.HandleDBError(ctx, err)
 	}
 
@@ -698,8 +923,12 @@ func (h *UserHandler) DeleteCartItem(ctx *fiber.Ctx) error {
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid product ID")
 	}
+	variantID, err := cartVariantID(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
 
-	err = h.userService.DeleteCartItem(user.ID, uint(productID))
+	err = h.userService.DeleteCartItem(user.ID, uint(productID), variantID)
 	if err != nil {
 		if err.Error() == "cart item not found" {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -734,8 +963,12 @@ func (h *UserHandler) IncrementCartItem(ctx *fiber.Ctx) error {
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid product ID")
 	}
+	variantID, err := cartVariantID(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
 
-	cartItem, err := h.userService.IncrementCartItem(user.ID, uint(productID))
+	cartItem, err := h.userService.IncrementCartItem(user.ID, uint(productID), variantID)
 	if err != nil {
 		if err.Error() == "cart item not found" {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -758,8 +991,12 @@ func (h *UserHandler) DecrementCartItem(ctx *fiber.Ctx) error {
 	if err != nil {
 		return helper.HandleValidationError(ctx, "Invalid product ID")
 	}
+	variantID, err := cartVariantID(ctx)
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid variant ID")
+	}
 
-	cartItem, err := h.userService.DecrementCartItem(user.ID, uint(productID))
+	cartItem, err := h.userService.DecrementCartItem(user.ID, uint(productID), variantID)
 	if err != nil {
 		if err.Error() == "cart item not found" {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -781,13 +1018,166 @@ func (h *UserHandler) DecrementCartItem(ctx *fiber.Ctx) error {
 }
 
 func (h *UserHandler) Checkout(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.CheckoutRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			return helper.HandleBodyParserError(ctx, err)
+		}
+	}
+
+	order, err := h.userService.CreateOrder(user.ID, strings.ToLower(strings.TrimSpace(request.ShippingMethod)))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownShippingMethod):
+			return helper.HandleValidationError(ctx, err.Error())
+		case errors.Is(err, domain.ErrCartEmpty):
+			return helper.HandleValidationError(ctx, "Your cart is empty")
+		case errors.Is(err, domain.ErrInsufficientStock):
+			return helper.HandleConflictError(ctx, "Not enough stock to complete checkout", err)
+		case errors.Is(err, domain.ErrPriceChanged):
+			return helper.HandleConflictError(ctx, "Prices in your cart have changed, review and acknowledge them to continue", err)
+		case errors.Is(err, domain.ErrProductUnavailable):
+			return helper.HandleConflictError(ctx, "A product in your cart is no longer available, acknowledge the cart changes to remove it", err)
+		case errors.Is(err, domain.ErrVariantRequired):
+			return helper.HandleConflictError(ctx, "Choose a size or colour for every product in your cart", err)
+		case errors.Is(err, domain.ErrCartChanged):
+			return helper.HandleConflictError(ctx, "Your cart changed during checkout, please try again", err)
+		case errors.Is(err, domain.ErrCouponNotApplicable), errors.Is(err, domain.ErrCouponExhausted):
+			return helper.HandleConflictError(ctx, "The coupon on your cart can no longer be used, remove it to continue", err)
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Checkout completed successfully",
+		"order":   order,
+	})
+}
+
+func (h *UserHandler) ForgotPassword(ctx *fiber.Ctx) error {
+	input := dto.ForgotPasswordInput{}
+	if err := ctx.BodyParser(&input); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	input.Email = strings.TrimSpace(input.Email)
+	if input.Email == "" {
+		return helper.HandleValidationError(ctx, "Field 'email' is required")
+	}
+
+	if err := h.userService.ForgotPassword(input.Email); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to send reset code",
+			"error":   err.Error(),
+		})
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Checkout fetched successfully",
+		"message": "If an account exists for this email, a reset code has been sent to its phone number",
 	})
 }
 
+func (h *UserHandler) ResetPassword(ctx *fiber.Ctx) error {
+	input := dto.ResetPasswordInput{}
+	if err := ctx.BodyParser(&input); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	input.Email = strings.TrimSpace(input.Email)
+	if input.Email == "" || input.Code == 0 || input.NewPassword == "" {
+		return helper.HandleValidationError(ctx, "Fields 'email', 'code' and 'new_password' are required")
+	}
+
+	err := h.userService.ResetPassword(input)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrTooManyAttempts):
+			return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"message": "Too many attempts",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, domain.ErrInvalidResetCode):
+			return helper.HandleValidationError(ctx, err.Error())
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Failed to reset password",
+			"error":   err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Password reset successfully, please log in again",
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new token pair; the old refresh token stops working
+func (h *UserHandler) RefreshToken(ctx *fiber.Ctx) error {
+	request := dto.RefreshTokenRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	if request.RefreshToken == "" {
+		return helper.HandleValidationError(ctx, "Field 'refresh_token' is required")
+	}
+
+	tokens, err := h.authService.Refresh(request.RefreshToken, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidRefreshToken) {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "authorization failed",
+				"reason":  err.Error(),
+			})
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":            "Token refreshed successfully",
+		"token":              tokens.Token,
+		"token_type":         tokens.TokenType,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	})
+}
+
+// Logout ends the session of the access token that made the request
 func (h *UserHandler) Logout(ctx *fiber.Ctx) error {
+	if err := h.authService.Logout(h.auth.GetCurrentTokenClaims(ctx)); err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Logged out successfully",
 	})
 }
+
+// LogoutAll signs the user out on every device, including the one making the request
+func (h *UserHandler) LogoutAll(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	if err := h.authService.LogoutAll(user.ID); err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Logged out of all devices successfully",
+	})
+}
+
+func handleUserAccessError(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, domain.ErrForbidden) {
+		return helper.HandleForbiddenError(ctx, "You can only access your own account")
+	}
+	return helper.HandleDBError(ctx, err)
+}
+
+func clientInfo(ctx *fiber.Ctx) dto.ClientInfo {
+	return dto.ClientInfo{
+		UserAgent: ctx.Get("User-Agent"),
+		IPAddress: ctx.IP(),
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/notification"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WishlistHandler struct {
+	wishlistService service.WishlistService
+	auth            helper.Auth
+	config          config.AppConfig
+}
+
+func newWishlistService(restHandler *rest.RestHandler, notificationClient notification.NotificationClient) service.WishlistService {
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	return service.NewWishlistService(
+		repository.NewWishlistRepository(restHandler.DB),
+		userRepo,
+		repository.NewCatalogueRepository(restHandler.DB),
+		service.NewNotificationService(notificationClient, userRepo),
+		restHandler.Config,
+	)
+}
+
+func SetupWishlistRoutes(restHandler *rest.RestHandler, notificationClient notification.NotificationClient) {
+	app := restHandler.App
+
+	userRepo := repository.NewUserRepository(restHandler.DB)
+	handler := WishlistHandler{
+		wishlistService: newWishlistService(restHandler, notificationClient),
+		auth:            restHandler.Auth,
+		config:          restHandler.Config,
+	}
+
+	// Private endpoints (authentication required)
+	privateRoutes := app.Group("/wishlist", restHandler.Auth.Authorize)
+	privateRoutes.Get("/", handler.GetWishlist)
+	privateRoutes.Post("/", handler.AddToWishlist)
+	privateRoutes.Patch("/:product_id", handler.UpdateWishlistItem)
+	privateRoutes.Delete("/:product_id", handler.RemoveFromWishlist)
+	privateRoutes.Post("/:product_id/move-to-cart", handler.MoveToCart)
+
+	// Private endpoints (authentication required - seller only)
+	sellerPrivateRoutes := app.Group("/seller", restHandler.Auth.AuthorizeSeller(userRepo))
+	sellerPrivateRoutes.Get("/wishlists", handler.GetSellerCounts)
+}
+
+func (h *WishlistHandler) GetWishlist(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	query := dto.PaginationParams{}
+	if err := ctx.QueryParser(&query); err != nil {
+		return helper.HandleValidationError(ctx, "Invalid query parameters")
+	}
+
+	if query.Take < 1 {
+		query.Take = 10
+	}
+	if query.Skip < 0 {
+		query.Skip = 0
+	}
+	if query.After != "" && query.Before != "" {
+		return helper.HandleValidationError(ctx, "Use either 'after' or 'before', not both")
+	}
+
+	result, err := h.wishlistService.GetWishlist(user.ID, query)
+	if err != nil {
+		return handleListError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Wishlist fetched successfully",
+		"data":       result.Data,
+		"pagination": result.Pagination,
+	})
+}
+
+func (h *WishlistHandler) AddToWishlist(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	request := dto.AddWishlistRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+	if request.ProductID == 0 {
+		return helper.HandleValidationError(ctx, "Field 'product_id' is required")
+	}
+
+	item, err := h.wishlistService.AddItem(user.ID, request)
+	if err != nil {
+		if errors.Is(err, domain.ErrAlreadyInWishlist) {
+			return helper.HandleConflictError(ctx, "This product is already in your wishlist", err)
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Product added to wishlist successfully",
+		"wishlist": item,
+	})
+}
+
+// UpdateWishlistItem turns the price-drop and back-in-stock alerts of a saved product on or off
+func (h *WishlistHandler) UpdateWishlistItem(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	productID, err := ctx.ParamsInt("product_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+
+	request := dto.UpdateWishlistRequest{}
+	if err := ctx.BodyParser(&request); err != nil {
+		return helper.HandleBodyParserError(ctx, err)
+	}
+
+	item, err := h.wishlistService.UpdateItem(user.ID, uint(productID), request)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Wishlist item updated successfully",
+		"wishlist": item,
+	})
+}
+
+func (h *WishlistHandler) RemoveFromWishlist(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	productID, err := ctx.ParamsInt("product_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+
+	if err := h.wishlistService.RemoveItem(user.ID, uint(productID)); err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Product removed from wishlist successfully",
+	})
+}
+
+// MoveToCart adds a saved product to the cart, one unit unless a quantity is given, and takes
+// it off the wishlist
+func (h *WishlistHandler) MoveToCart(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	productID, err := ctx.ParamsInt("product_id")
+	if err != nil {
+		return helper.HandleValidationError(ctx, "Invalid product ID")
+	}
+
+	request := dto.MoveToCartRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			return helper.HandleBodyParserError(ctx, err)
+		}
+	}
+
+	cartItem, err := h.wishlistService.MoveToCart(user.ID, uint(productID), request)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidQuantity):
+			return helper.HandleValidationError(ctx, err.Error())
+		case errors.Is(err, domain.ErrVariantRequired):
+			return helper.HandleValidationError(ctx, "Field 'variant_id' is required for products with variants")
+		case err.Error() == "product not found", err.Error() == "product variant not found":
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Product not found",
+				"error":   err.Error(),
+			})
+		}
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Product moved to cart successfully",
+		"cart":    cartItem,
+	})
+}
+
+// GetSellerCounts lists how many wishlists each of the seller's products is on
+func (h *WishlistHandler) GetSellerCounts(ctx *fiber.Ctx) error {
+	user := h.auth.GetCurrentUser(ctx)
+
+	counts, err := h.wishlistService.GetSellerCounts(user.ID)
+	if err != nil {
+		return helper.HandleDBError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":   "Wishlist counts fetched successfully",
+		"wishlists": counts,
+	})
+}
@@ -7,9 +7,14 @@ import (
 	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/infra"
+	"go-ecommerce-app/internal/jobs"
+	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/internal/service"
-	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/payment"
+	"go-ecommerce-app/pkg/storage"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -17,6 +22,11 @@ import (
 
 func StartServer(config config.AppConfig) {
 	app := fiber.New(fiber.Config{
+		// Leave room for a full image upload plus the rest of the multipart form. Every other
+		// route is held to the default limit by LimitBody below; streamed bodies let it refuse
+		// a large body before it is read.
+		BodyLimit:         int(config.UploadMaxBytes)*handlers.MaxImagesPerUpload + 1<<20,
+		StreamRequestBody: true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -43,38 +53,107 @@ func StartServer(config config.AppConfig) {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "*",
 		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Cart-Token",
 		AllowCredentials: false,
-		ExposeHeaders:    "Content-Length",
+		ExposeHeaders:    "Content-Length,X-Cart-Token",
 		MaxAge:           3600,
 	}))
+	app.Use(helper.LimitBody(fiber.DefaultBodyLimit, handlers.IsImageUpload))
 
 	db := infra.GetDB()
 
 	// Run database migrations
+	if err := repository.RunMigrations(db, repository.BeforeAutoMigrate); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
 	err := db.AutoMigrate(
 		&domain.User{},
 		&domain.BankAccount{},
 		&domain.Category{},
 		&domain.Product{},
+		&domain.ProductOption{},
+		&domain.ProductVariant{},
+		&domain.ProductImage{},
 		&domain.Cart{},
+		&domain.GuestCart{},
+		&domain.WishlistItem{},
 		&domain.Address{},
+		&domain.Order{},
+		&domain.SellerOrder{},
+		&domain.OrderItem{},
+		&domain.OrderStatusHistory{},
+		&domain.Payment{},
+		&domain.LedgerEntry{},
+		&domain.Payout{},
+		&domain.ReturnRequest{},
+		&domain.ReturnImage{},
+		&domain.RefreshToken{},
+		&domain.RevokedToken{},
+		&domain.Role{},
+		&domain.Permission{},
+		&domain.UserRole{},
+		&domain.PasswordResetCode{},
+		&domain.OutboxMessage{},
+		&domain.AuditEntry{},
+		&domain.Coupon{},
+		&domain.CouponRedemption{},
+		&domain.CartCoupon{},
 	)
 
-	auth := helper.SetupAuth(config.JwtSecret)
+	tokenRepo := repository.NewTokenRepository(db)
+	auth := helper.SetupAuth(config.JwtSecret, tokenRepo)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
+	if err := repository.RunMigrations(db, repository.AfterAutoMigrate); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
 	log.Println("✅ Database migration completed successfully")
 
+	if err := repository.MigrateProductSearch(db); err != nil {
+		log.Fatalf("Failed to set up product search: %v", err)
+	}
+
+	roleService := service.NewRoleService(repository.NewRoleRepository(db), repository.NewUserRepository(db))
+	if err := roleService.Bootstrap(config.AdminEmails); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
+
 	// Initialize external services
+	paymentProvider, bankVerifier, err := payment.NewProviders(config)
+	if err != nil {
+		log.Fatalf("Failed to configure payment providers: %v", err)
+	}
+
 	var bankService *service.BankService
-	if config.FlutterwaveSecretKey != "" {
-		flutterwaveClient := flutterwave.NewClient(config.FlutterwaveSecretKey)
-		bankService = service.NewBankService(flutterwaveClient)
-		log.Println("✅ Bank verification service initialized (Flutterwave)")
+	if bankVerifier != nil {
+		bankService = service.NewBankService(bankVerifier)
+		log.Printf("✅ Bank verification service initialized (%s)", config.BankVerifier)
+	} else {
+		log.Println("⚠️  BANK_VERIFIER not set - bank verification features disabled")
+	}
+	if paymentProvider != nil {
+		log.Printf("✅ Payment service initialized (%s)", paymentProvider.Name())
 	} else {
-		log.Println("⚠️  FLUTTERWAVE_SECRET_KEY not set - bank verification features disabled")
+		log.Println("⚠️  PAYMENT_PROVIDER not set - payment features disabled (use PAYMENT_PROVIDER=fake for local runs and CI)")
+	}
+	notificationClient := notification.NewNotificationClient(config)
+	if notificationClient.EmailEnabled() {
+		log.Printf("✅ Email notifications enabled (%s)", config.SMTPHost)
+	} else {
+		log.Println("⚠️  SMTP_HOST not set - notifications will be sent by SMS only")
+	}
+	fileStorage, err := storage.NewStorage(config)
+	if err != nil {
+		log.Fatalf("Failed to configure file storage: %v", err)
+	}
+	if localStorage, ok := fileStorage.(*storage.LocalStorage); ok {
+		// Registered before the route groups so uploaded files are public
+		app.Static(localStorage.Route(), config.UploadDir, fiber.Static{MaxAge: 86400})
+		log.Printf("✅ Uploads stored in %s and served from %s", config.UploadDir, config.UploadBaseURL)
+	}
+	if config.FlutterwaveWebhookHash == "" {
+		log.Println("⚠️  FLUTTERWAVE_WEBHOOK_HASH not set - payment webhooks will be rejected")
 	}
 
 	restHandler := &rest.RestHandler{
@@ -84,7 +163,36 @@ func StartServer(config config.AppConfig) {
 		Config: config,
 	}
 
-	setupRoutes(restHandler, bankService)
+	setupRoutes(restHandler, bankService, paymentProvider, notificationClient, fileStorage)
+
+	// Background jobs
+	authService := service.NewAuthService(tokenRepo, repository.NewUserRepository(db), auth, config)
+	stopTokenCleanup := jobs.Every("token-cleanup", time.Hour, authService.CleanupExpiredTokens)
+	defer stopTokenCleanup()
+
+	guestCartService := service.NewGuestCartService(repository.NewGuestCartRepository(db), repository.NewUserRepository(db), repository.NewCatalogueRepository(db), auth, config)
+	stopGuestCartCleanup := jobs.Every("guest-cart-cleanup", time.Hour, guestCartService.CleanupExpiredCarts)
+	defer stopGuestCartCleanup()
+
+	outboxService := service.NewOutboxService(repository.NewOutboxRepository(db), notificationClient, config)
+	stopOutbox := jobs.Every("notification-outbox", config.NotificationPollInterval, outboxService.DeliverDue)
+	defer stopOutbox()
+
+	if paymentProvider != nil {
+		userRepo := repository.NewUserRepository(db)
+		notifier := service.NewNotificationService(notificationClient, userRepo)
+		payoutService := service.NewPayoutService(repository.NewPayoutRepository(db), userRepo, config, notifier, paymentProvider)
+		stopSettlement := jobs.Every("seller-settlement", config.PayoutInterval, payoutService.RunSettlement)
+		defer stopSettlement()
+
+		returnService := service.NewReturnService(repository.NewReturnRepository(db), repository.NewOrderRepository(db), repository.NewPaymentRepository(db), config, paymentProvider)
+		stopRefundCheck := jobs.Every("refund-check", 15*time.Minute, returnService.CheckPendingRefunds)
+		defer stopRefundCheck()
+
+		paymentService := service.NewPaymentService(repository.NewPaymentRepository(db), repository.NewOrderRepository(db), userRepo, config, notifier, paymentProvider)
+		stopUnappliedRefunds := jobs.Every("unapplied-payment-refund", 15*time.Minute, paymentService.RefundUnappliedPayments)
+		defer stopUnappliedRefunds()
+	}
 
 	log.Printf("🚀 Server starting on port %s", config.ServerPort)
 	if err := app.Listen(config.ServerPort); err != nil {
@@ -92,8 +200,18 @@ func StartServer(config config.AppConfig) {
 	}
 }
 
-func setupRoutes(restHandler *rest.RestHandler, bankService *service.BankService) {
-	handlers.SetupUserRoutes(restHandler, bankService)
+func setupRoutes(restHandler *rest.RestHandler, bankService *service.BankService, paymentProvider payment.PaymentProvider, notificationClient notification.NotificationClient, fileStorage storage.Storage) {
+	// The catalogue goes first: its browse endpoints are public and must be matched before
+	// the groups that require a logged in user for every path
+	handlers.SetupCatalogueRoutes(restHandler, bankService, notificationClient, fileStorage)
+	handlers.SetupCouponRoutes(restHandler)
+	handlers.SetupGuestCartRoutes(restHandler)
+	handlers.SetupTransactionRoutes(restHandler, paymentProvider, notificationClient)
+	handlers.SetupUserRoutes(restHandler, bankService, notificationClient)
+	handlers.SetupWishlistRoutes(restHandler, notificationClient)
 	handlers.SetupBankRoutes(restHandler, bankService)
-	handlers.SetupCatalogueRoutes(restHandler, bankService)
+	handlers.SetupOrderRoutes(restHandler, notificationClient)
+	handlers.SetupPayoutRoutes(restHandler, paymentProvider, notificationClient)
+	handlers.SetupReturnRoutes(restHandler, paymentProvider)
+	handlers.SetupAdminRoutes(restHandler, notificationClient, fileStorage)
 }
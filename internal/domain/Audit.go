@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+const (
+	AUDIT_DENIED   = "denied"
+	AUDIT_OVERRIDE = "override"
+)
+
+const (
+	AUDIT_RESOURCE_PRODUCT  = "product"
+	AUDIT_RESOURCE_CATEGORY = "category"
+)
+
+// AuditEntry records an attempt to change a catalogue resource owned by someone else. Sellers
+// are denied; moderators go through, and the override is recorded all the same.
+type AuditEntry struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ActorID      uint      `json:"actor_id" gorm:"index;not null"`
+	Action       string    `json:"action" gorm:"not null"`
+	ResourceType string    `json:"resource_type" gorm:"index:idx_audit_resource,priority:1;not null"`
+	ResourceID   uint      `json:"resource_id" gorm:"index:idx_audit_resource,priority:2;not null"`
+	OwnerID      uint      `json:"owner_id"`
+	Outcome      string    `json:"outcome" gorm:"index;not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index;default:CURRENT_TIMESTAMP"`
+}
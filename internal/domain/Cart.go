@@ -1,16 +1,132 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
+const (
+	CART_PRICE_CHANGED      = "price_changed"
+	CART_INSUFFICIENT_STOCK = "insufficient_stock"
+	CART_OUT_OF_STOCK       = "out_of_stock"
+	CART_UNAVAILABLE        = "unavailable"
+)
+
+// Cart is one line of a cart. A guest's lines have no user yet and belong to their GuestCart.
+// Price is what the buyer added the line at, or the last price they acknowledged; reads
+// compare it with the catalogue and never change it.
 type Cart struct {
-	ID        uint `gorm:"primaryKey"`
-	UserID    uint
-	SellerID  uint
-	Name      string
-	ImageURL  string
-	Price     float64
-	Quantity  int
-	ProductID uint
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ID          uint `gorm:"primaryKey"`
+	UserID      uint
+	GuestCartID *uint `json:"-" gorm:"index"`
+	SellerID    uint
+	Name        string
+	ImageURL    string
+	Price       Money
+	Quantity    int
+	ProductID   uint
+	VariantID   *uint
+	SKU         string
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// GuestCart holds an anonymous visitor's cart until they log in or register. The visitor only
+// ever sees a token signed over Key; UpdatedAt is the last change, from which the cart expires.
+type GuestCart struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"index;default:CURRENT_TIMESTAMP"`
+}
+
+// CartWarning tells the buyer that something about a cart line changed since it was added
+type CartWarning struct {
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+	PreviousPrice *Money `json:"previous_price,omitempty"`
+	Available     *int   `json:"available,omitempty"`
+}
+
+// CartLine is a cart item checked against the current catalogue
+type CartLine struct {
+	Cart
+	Discount Money         `json:"discount,omitempty"`
+	Warnings []CartWarning `json:"warnings,omitempty"`
+}
+
+// LineTotal is the price of the line before any discount
+func (l CartLine) LineTotal() Money {
+	return l.Price.Times(l.Quantity)
+}
+
+// Warning returns the line's warning with the given code, if it has one
+func (l CartLine) Warning(code string) *CartWarning {
+	for i := range l.Warnings {
+		if l.Warnings[i].Code == code {
+			return &l.Warnings[i]
+		}
+	}
+	return nil
+}
+
+// Unavailable reports whether the product or SKU behind the line is gone
+func (l CartLine) Unavailable() bool {
+	return l.Warning(CART_UNAVAILABLE) != nil
+}
+
+// PriceCartLine returns the cart item at the current price of its product or SKU, warning about
+// a changed price, short stock, or a product that can no longer be bought. A nil product means
+// it has been deleted.
+func PriceCartLine(item Cart, product *Product) CartLine {
+	line := CartLine{Cart: item}
+
+	unavailable := func(message string) CartLine {
+		line.Warnings = append(line.Warnings, CartWarning{Code: CART_UNAVAILABLE, Message: message})
+		return line
+	}
+	if product == nil {
+		return unavailable("This product is no longer available")
+	}
+
+	price, stock := product.Price, product.Stock
+	if item.VariantID != nil {
+		var variant *ProductVariant
+		for i := range product.Variants {
+			if product.Variants[i].ID == *item.VariantID {
+				variant = &product.Variants[i]
+				break
+			}
+		}
+		if variant == nil {
+			return unavailable("This option of the product is no longer available")
+		}
+		price, stock = variant.Price, variant.Stock
+	} else if product.HasVariants {
+		return unavailable("This product is now sold in options; add the one you want again")
+	}
+
+	if current := MoneyFromAmount(price); current != item.Price {
+		previous := item.Price
+		line.Price = current
+		line.Warnings = append(line.Warnings, CartWarning{
+			Code:          CART_PRICE_CHANGED,
+			Message:       fmt.Sprintf("The price changed from %s to %s", previous, current),
+			PreviousPrice: &previous,
+		})
+	}
+
+	switch {
+	case stock <= 0:
+		line.Warnings = append(line.Warnings, CartWarning{Code: CART_OUT_OF_STOCK, Message: "This item is out of stock"})
+	case stock < item.Quantity:
+		available := stock
+		line.Warnings = append(line.Warnings, CartWarning{
+			Code:      CART_INSUFFICIENT_STOCK,
+			Message:   fmt.Sprintf("Only %d left in stock", stock),
+			Available: &available,
+		})
+	}
+
+	return line
 }
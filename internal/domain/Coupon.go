@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	COUPON_PERCENT = "percent"
+	COUPON_FIXED   = "fixed"
+
+	// COUPON_FUNDED_BY_SELLER coupons come off the seller's sales
+	COUPON_FUNDED_BY_SELLER = "seller"
+	// COUPON_FUNDED_BY_PLATFORM coupons are paid for by the platform; sellers are settled as if
+	// the buyer had paid full price
+	COUPON_FUNDED_BY_PLATFORM = "platform"
+)
+
+// Coupon is a discount code. Sellers' coupons only ever cover their own items; admins may
+// create coupons for the whole store or narrow them to a seller, a product or a category
+// (with everything below it). Redemptions counts checkouts that have not been called off
+// unpaid and never exceeds MaxRedemptions; a zero cap means no limit. FundedBy says who pays
+// for the discount: sellers always fund their own coupons, and only an admin coupon narrowed to
+// one seller can be charged to that seller.
+type Coupon struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Code           string     `json:"code" gorm:"uniqueIndex;not null"`
+	Description    string     `json:"description"`
+	Type           string     `json:"type" gorm:"not null"`
+	Percent        Rate       `json:"percent,omitempty" gorm:"default:0"`
+	AmountOff      Money      `json:"amount_off,omitempty" gorm:"default:0"`
+	MinimumSpend   Money      `json:"minimum_spend" gorm:"default:0"`
+	SellerID       *uint      `json:"seller_id,omitempty" gorm:"index"`
+	ProductID      *uint      `json:"product_id,omitempty"`
+	CategoryID     *uint      `json:"category_id,omitempty"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	MaxRedemptions int        `json:"max_redemptions" gorm:"default:0"`
+	MaxPerUser     int        `json:"max_per_user" gorm:"default:0"`
+	Redemptions    int        `json:"redemptions" gorm:"default:0"`
+	FundedBy       string     `json:"funded_by" gorm:"not null;default:seller"`
+	Active         bool       `json:"active" gorm:"default:true"`
+	CreatedBy      uint       `json:"created_by" gorm:"not null"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// CouponRedemption records one use of a coupon by a checkout
+type CouponRedemption struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CouponID  uint      `json:"coupon_id" gorm:"index;not null"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	OrderID   uint      `json:"order_id" gorm:"uniqueIndex;not null"`
+	Discount  Money     `json:"discount" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// CartCoupon is the code a buyer has applied to their cart
+type CartCoupon struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// NormalizeCouponCode makes codes case-insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidateCoupon checks the shape of a new coupon
+func ValidateCoupon(coupon *Coupon) error {
+	switch {
+	case coupon.Code == "":
+		return fmt.Errorf("%w: a code is required", ErrInvalidCoupon)
+	case coupon.Type == COUPON_PERCENT && (coupon.Percent <= 0 || coupon.Percent > 10000):
+		return fmt.Errorf("%w: percent must be above 0 and at most 100", ErrInvalidCoupon)
+	case coupon.Type == COUPON_FIXED && coupon.AmountOff <= 0:
+		return fmt.Errorf("%w: amount_off must be positive", ErrInvalidCoupon)
+	case coupon.Type != COUPON_PERCENT && coupon.Type != COUPON_FIXED:
+		return fmt.Errorf("%w: type must be %s or %s", ErrInvalidCoupon, COUPON_PERCENT, COUPON_FIXED)
+	case coupon.MinimumSpend < 0 || coupon.MaxRedemptions < 0 || coupon.MaxPerUser < 0:
+		return fmt.Errorf("%w: minimum spend and usage caps cannot be negative", ErrInvalidCoupon)
+	case coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCoupon)
+	case coupon.FundedBy != COUPON_FUNDED_BY_SELLER && coupon.FundedBy != COUPON_FUNDED_BY_PLATFORM:
+		return fmt.Errorf("%w: funded_by must be %s or %s", ErrInvalidCoupon, COUPON_FUNDED_BY_SELLER, COUPON_FUNDED_BY_PLATFORM)
+	case coupon.FundedBy == COUPON_FUNDED_BY_SELLER && coupon.SellerID == nil:
+		return fmt.Errorf("%w: only a coupon limited to one seller can be funded by the seller", ErrInvalidCoupon)
+	}
+	return nil
+}
+
+// CheckAvailable reports whether the coupon can be used at all right now
+func (c Coupon) CheckAvailable(now time.Time) error {
+	switch {
+	case !c.Active:
+		return fmt.Errorf("%w: %s is no longer active", ErrCouponNotApplicable, c.Code)
+	case c.StartsAt != nil && now.Before(*c.StartsAt):
+		return fmt.Errorf("%w: %s is not valid yet", ErrCouponNotApplicable, c.Code)
+	case c.EndsAt != nil && !now.Before(*c.EndsAt):
+		return fmt.Errorf("%w: %s has expired", ErrCouponNotApplicable, c.Code)
+	case c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions:
+		return fmt.Errorf("%w: %s", ErrCouponExhausted, c.Code)
+	}
+	return nil
+}
+
+// ApplyCoupon sets the discount on every line the coupon covers. covers decides which lines
+// those are. A percentage comes off each covered line; a fixed amount is spread over them in
+// proportion to their value, and never exceeds what they cost.
+func ApplyCoupon(coupon Coupon, lines []CartLine, covers func(CartLine) bool) (Money, error) {
+	var eligible []int
+	var subtotal Money
+	for i, line := range lines {
+		if line.Unavailable() || !covers(line) {
+			continue
+		}
+		eligible = append(eligible, i)
+		subtotal += line.LineTotal()
+	}
+
+	if len(eligible) == 0 {
+		return 0, fmt.Errorf("%w: %s does not cover anything in your cart", ErrCouponNotApplicable, coupon.Code)
+	}
+	if subtotal < coupon.MinimumSpend {
+		return 0, fmt.Errorf("%w: %s needs a spend of at least %s", ErrCouponNotApplicable, coupon.Code, coupon.MinimumSpend)
+	}
+
+	var total Money
+	if coupon.Type == COUPON_PERCENT {
+		for _, i := range eligible {
+			lines[i].Discount = lines[i].LineTotal().ApplyRate(int64(coupon.Percent))
+			total += lines[i].Discount
+		}
+		return total, nil
+	}
+
+	amount := coupon.AmountOff
+	if amount > subtotal {
+		amount = subtotal
+	}
+	// the last line takes the remainder, so the shares add up to the amount exactly
+	for n, i := range eligible {
+		share := Money(int64(amount) * int64(lines[i].LineTotal()) / int64(subtotal))
+		if n == len(eligible)-1 {
+			share = amount - total
+		}
+		lines[i].Discount = share
+		total += share
+	}
+	return total, nil
+}
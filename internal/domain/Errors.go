@@ -0,0 +1,46 @@
+package domain
+
+import "errors"
+
+var (
+	ErrCartEmpty             = errors.New("cart is empty")
+	ErrCartChanged           = errors.New("cart was modified during checkout")
+	ErrInsufficientStock     = errors.New("insufficient stock")
+	ErrPriceChanged          = errors.New("product price has changed")
+	ErrProductUnavailable    = errors.New("product is no longer available")
+	ErrInvalidTransition     = errors.New("invalid order status transition")
+	ErrForbidden             = errors.New("you do not have access to this resource")
+	ErrOrderNotPayable       = errors.New("order is not awaiting payment")
+	ErrUnbalancedLedger      = errors.New("ledger entries do not balance")
+	ErrBalanceTooLow         = errors.New("balance is below the payout minimum")
+	ErrNoBankAccount         = errors.New("no bank account on file")
+	ErrReturnNotAllowed      = errors.New("only delivered items can be returned")
+	ErrReturnQuantity        = errors.New("return quantity exceeds the quantity left to return")
+	ErrInvalidReturnState    = errors.New("return request is not in a state that allows this action")
+	ErrNoRefundablePayment   = errors.New("order has no successful payment to refund")
+	ErrInvalidResetCode      = errors.New("reset code is invalid or expired")
+	ErrTooManyAttempts       = errors.New("too many attempts, request a new code")
+	ErrInvalidRefreshToken   = errors.New("refresh token is invalid or expired")
+	ErrInvalidRefundAmount   = errors.New("refund amount must be positive and not more than the value returned")
+	ErrNotReplayable         = errors.New("only dead messages that have not expired and carry no one-time code can be replayed")
+	ErrInvalidVariant        = errors.New("invalid product variant")
+	ErrVariantRequired       = errors.New("choose a variant of this product")
+	ErrTooManyImages         = errors.New("product image limit reached")
+	ErrInvalidImageOrder     = errors.New("image order must list every image of the product exactly once")
+	ErrInvalidCursor         = errors.New("pagination cursor is invalid or belongs to a different sort")
+	ErrCategoryCycle         = errors.New("a category cannot be placed under itself or one of its descendants")
+	ErrCategoryHasChildren   = errors.New("category has child categories; delete them with children=cascade or move them with children=reassign")
+	ErrCategoryHasProducts   = errors.New("category has associated products. Please remove or reassign products before deleting the category")
+	ErrInvalidDeleteOption   = errors.New("invalid category delete option")
+	ErrUnknownShippingMethod = errors.New("unknown shipping method")
+	ErrInvalidCoupon         = errors.New("invalid coupon")
+	ErrCouponNotApplicable   = errors.New("coupon cannot be applied")
+	ErrCouponExhausted       = errors.New("coupon has reached its usage limit")
+	ErrCouponRedeemed        = errors.New("coupon has been redeemed and can only be deactivated")
+	ErrInvalidCartToken      = errors.New("cart token is invalid or expired")
+	ErrInvalidQuantity       = errors.New("quantity must be at least 1")
+	ErrAlreadyInWishlist     = errors.New("product is already in your wishlist")
+	ErrInvalidChannel        = errors.New("channel must be sms or email")
+	ErrAlreadyVerified       = errors.New("contact is already verified")
+	ErrChannelUnavailable    = errors.New("notification channel is not available")
+)
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// LEDGER_PLATFORM_CASH is the money held with the payment provider
+	LEDGER_PLATFORM_CASH = "platform:cash"
+	// LEDGER_PLATFORM_CLEARING holds buyer money until the goods are delivered
+	LEDGER_PLATFORM_CLEARING = "platform:clearing"
+	// LEDGER_PLATFORM_COMMISSION accumulates the platform's share of each sale
+	LEDGER_PLATFORM_COMMISSION = "platform:commission"
+	// LEDGER_PLATFORM_TAX_PAYABLE holds the tax collected on sales until it is remitted
+	LEDGER_PLATFORM_TAX_PAYABLE = "platform:tax_payable"
+	// LEDGER_PLATFORM_PROMOTIONS pays for the coupon discounts the platform funds
+	LEDGER_PLATFORM_PROMOTIONS = "platform:promotions"
+)
+
+// SellerLedgerAccount is the account holding what the platform owes a seller
+func SellerLedgerAccount(sellerID uint) string {
+	return fmt.Sprintf("seller:%d", sellerID)
+}
+
+// LedgerEntry is one leg of a double-entry posting; all legs sharing an EntryRef must balance
+type LedgerEntry struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	EntryRef      string    `json:"entry_ref" gorm:"uniqueIndex:idx_ledger_entry_account;not null"`
+	Account       string    `json:"account" gorm:"uniqueIndex:idx_ledger_entry_account;index;not null"`
+	SellerID      uint      `json:"seller_id" gorm:"index"`
+	Debit         float64   `json:"debit" gorm:"type:numeric(14,2);default:0"`
+	Credit        float64   `json:"credit" gorm:"type:numeric(14,2);default:0"`
+	SellerOrderID *uint     `json:"seller_order_id,omitempty" gorm:"index"`
+	PayoutID      *uint     `json:"payout_id,omitempty" gorm:"index"`
+	Description   string    `json:"description"`
+	CreatedAt     time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// LedgerEntriesBalance reports whether the debits of a posting equal its credits
+func LedgerEntriesBalance(entries []LedgerEntry) bool {
+	var debit, credit float64
+	for _, entry := range entries {
+		debit += entry.Debit
+		credit += entry.Credit
+	}
+	return math.Abs(debit-credit) < 0.005
+}
+
+// RoundAmount rounds a money amount to two decimal places
+func RoundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// PaymentReceivedEntries moves a confirmed buyer payment into clearing
+func PaymentReceivedEntries(payment *Payment) []LedgerEntry {
+	ref := "PAYMENT-" + payment.TxRef
+	return []LedgerEntry{
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CASH, Debit: payment.Amount, Description: "payment " + payment.TxRef},
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CLEARING, Credit: payment.Amount, Description: "payment " + payment.TxRef},
+	}
+}
+
+// UnappliedPaymentRefundEntries pays money captured for an order that could no longer take it
+// back out of clearing
+func UnappliedPaymentRefundEntries(payment *Payment) []LedgerEntry {
+	ref := "REFUND-" + payment.TxRef
+	return []LedgerEntry{
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CLEARING, Debit: payment.Amount, Description: "refund of unapplied payment " + payment.TxRef},
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CASH, Credit: payment.Amount, Description: "refund of unapplied payment " + payment.TxRef},
+	}
+}
+
+// SaleSettledEntries clears a delivered sub-order out of clearing. The sales, after any
+// discount, go to the seller less the platform commission, with the part of the discount the
+// platform funds made up from promotions; the tax collected is owed to the tax authority and the
+// shipping goes to the seller, who ships the order. Tax and shipping are posted under their own
+// refs so refunds of the goods only see the sale.
+func SaleSettledEntries(sellerOrder *SellerOrder, commissionPercent float64) []LedgerEntry {
+	ref := SaleEntryRef(sellerOrder.ID)
+	sales := sellerOrder.SellerSales()
+	commission := sales.ApplyRate(int64(RateFromPercent(commissionPercent)))
+	description := fmt.Sprintf("order %d delivered", sellerOrder.OrderID)
+	seller := SellerLedgerAccount(sellerOrder.SellerID)
+
+	entries := []LedgerEntry{
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CLEARING, Debit: sellerOrder.Sales().Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+		{EntryRef: ref, Account: seller, SellerID: sellerOrder.SellerID, Credit: (sales - commission).Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+		{EntryRef: ref, Account: LEDGER_PLATFORM_COMMISSION, Credit: commission.Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+	}
+	if sellerOrder.PlatformDiscount > 0 {
+		entries = append(entries, LedgerEntry{EntryRef: ref, Account: LEDGER_PLATFORM_PROMOTIONS, Debit: sellerOrder.PlatformDiscount.Amount(), SellerOrderID: &sellerOrder.ID, Description: description})
+	}
+	if sellerOrder.Tax > 0 {
+		taxRef := ref + "-TAX"
+		entries = append(entries,
+			LedgerEntry{EntryRef: taxRef, Account: LEDGER_PLATFORM_CLEARING, Debit: sellerOrder.Tax.Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+			LedgerEntry{EntryRef: taxRef, Account: LEDGER_PLATFORM_TAX_PAYABLE, Credit: sellerOrder.Tax.Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+		)
+	}
+	if sellerOrder.Shipping > 0 {
+		shippingRef := ref + "-SHIPPING"
+		entries = append(entries,
+			LedgerEntry{EntryRef: shippingRef, Account: LEDGER_PLATFORM_CLEARING, Debit: sellerOrder.Shipping.Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+			LedgerEntry{EntryRef: shippingRef, Account: seller, SellerID: sellerOrder.SellerID, Credit: sellerOrder.Shipping.Amount(), SellerOrderID: &sellerOrder.ID, Description: description},
+		)
+	}
+	return entries
+}
+
+// PayoutEntries takes a payout off the seller's balance as it leaves the platform
+func PayoutEntries(payout *Payout) []LedgerEntry {
+	return []LedgerEntry{
+		{EntryRef: payout.Reference, Account: SellerLedgerAccount(payout.SellerID), SellerID: payout.SellerID, Debit: payout.Amount, PayoutID: &payout.ID, Description: "payout " + payout.Reference},
+		{EntryRef: payout.Reference, Account: LEDGER_PLATFORM_CASH, Credit: payout.Amount, PayoutID: &payout.ID, Description: "payout " + payout.Reference},
+	}
+}
+
+// PayoutReversalEntries gives the amount of a failed payout back to the seller
+func PayoutReversalEntries(payout *Payout) []LedgerEntry {
+	ref := payout.Reference + "-REVERSAL"
+	return []LedgerEntry{
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CASH, Debit: payout.Amount, PayoutID: &payout.ID, Description: "failed payout " + payout.Reference},
+		{EntryRef: ref, Account: SellerLedgerAccount(payout.SellerID), SellerID: payout.SellerID, Credit: payout.Amount, PayoutID: &payout.ID, Description: "failed payout " + payout.Reference},
+	}
+}
+
+// RefundEntries pays a refund back out of the platform. When the sale was already settled the
+// refund is taken from the seller and the commission in the proportion they received it, and the
+// seller also gives back the matching share of any discount the platform funded; before
+// settlement the money is still in clearing and comes straight out of there.
+func RefundEntries(returnRequest *ReturnRequest, sale []LedgerEntry) []LedgerEntry {
+	ref := "REFUND-" + returnRequest.Reference
+	amount := returnRequest.RefundAmount
+	description := "refund " + returnRequest.Reference
+	sellerOrderID := returnRequest.SellerOrderID
+
+	var subtotal, commission, promotion float64
+	for _, entry := range sale {
+		switch entry.Account {
+		case LEDGER_PLATFORM_CLEARING:
+			subtotal += entry.Debit
+		case LEDGER_PLATFORM_COMMISSION:
+			commission += entry.Credit
+		case LEDGER_PLATFORM_PROMOTIONS:
+			promotion += entry.Debit
+		}
+	}
+
+	if subtotal <= 0 {
+		return []LedgerEntry{
+			{EntryRef: ref, Account: LEDGER_PLATFORM_CLEARING, Debit: amount, SellerOrderID: &sellerOrderID, Description: description},
+			{EntryRef: ref, Account: LEDGER_PLATFORM_CASH, Credit: amount, SellerOrderID: &sellerOrderID, Description: description},
+		}
+	}
+
+	commissionShare := RoundAmount(commission * amount / subtotal)
+	promotionShare := RoundAmount(promotion * amount / subtotal)
+	entries := []LedgerEntry{
+		{EntryRef: ref, Account: SellerLedgerAccount(returnRequest.SellerID), SellerID: returnRequest.SellerID, Debit: RoundAmount(amount + promotionShare - commissionShare), SellerOrderID: &sellerOrderID, Description: description},
+		{EntryRef: ref, Account: LEDGER_PLATFORM_COMMISSION, Debit: commissionShare, SellerOrderID: &sellerOrderID, Description: description},
+		{EntryRef: ref, Account: LEDGER_PLATFORM_CASH, Credit: amount, SellerOrderID: &sellerOrderID, Description: description},
+	}
+	if promotionShare > 0 {
+		entries = append(entries, LedgerEntry{EntryRef: ref, Account: LEDGER_PLATFORM_PROMOTIONS, Credit: promotionShare, SellerOrderID: &sellerOrderID, Description: description})
+	}
+	return entries
+}
+
+// SaleEntryRef is the entry ref under which a delivered sub-order was settled
+func SaleEntryRef(sellerOrderID uint) string {
+	return fmt.Sprintf("SALE-%d", sellerOrderID)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// Money is an amount in minor units of the store currency, e.g. kobo for NGN. Carts and orders
+// store their amounts in Money and totals are added up in it, so they never pick up floating
+// point error.
+type Money int64
+
+// moneyScale is the number of minor units in one major unit
+const moneyScale = 100
+
+// MoneyFromAmount converts a stored two decimal amount to Money
+func MoneyFromAmount(amount float64) Money {
+	return Money(math.Round(amount * moneyScale))
+}
+
+// Amount converts back to the two decimal form payments and the ledger are stored in
+func (m Money) Amount() float64 {
+	return float64(m) / moneyScale
+}
+
+func (m Money) Times(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// ApplyRate returns the given share of the amount, in basis points (1/100 of a percent),
+// rounded half away from zero to the nearest minor unit
+func (m Money) ApplyRate(basisPoints int64) Money {
+	product := int64(m) * basisPoints
+	if product < 0 {
+		return Money((product - 5000) / 10000)
+	}
+	return Money((product + 5000) / 10000)
+}
+
+func (m Money) String() string {
+	sign, value := "", int64(m)
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/moneyScale, value%moneyScale)
+}
+
+// MarshalJSON writes the amount as an exact decimal number, e.g. 1250.50
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Rate is a percentage in basis points, 1/100 of a percent, so 7.5% is 750
+type Rate int64
+
+func RateFromPercent(percent float64) Rate {
+	return Rate(math.Round(percent * 100))
+}
+
+// MarshalJSON writes the rate as a percentage, e.g. 7.50
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return Money(r).MarshalJSON()
+}
@@ -0,0 +1,158 @@
+package domain
+
+import "time"
+
+const (
+	ORDER_PENDING    = "pending"
+	ORDER_PAID       = "paid"
+	ORDER_PROCESSING = "processing"
+	ORDER_SHIPPED    = "shipped"
+	ORDER_DELIVERED  = "delivered"
+	ORDER_CANCELLED  = "cancelled"
+	ORDER_REFUNDED   = "refunded"
+)
+
+// orderTransitions lists the statuses an order may move to from its current status. Only an
+// unpaid order can be cancelled; once paid, the buyer's money is in clearing and the order can
+// only be called off by refunding it.
+var orderTransitions = map[string][]string{
+	ORDER_PENDING:    {ORDER_PAID, ORDER_CANCELLED},
+	ORDER_PAID:       {ORDER_PROCESSING, ORDER_REFUNDED},
+	ORDER_PROCESSING: {ORDER_SHIPPED, ORDER_REFUNDED},
+	ORDER_SHIPPED:    {ORDER_DELIVERED, ORDER_REFUNDED},
+	ORDER_DELIVERED:  {ORDER_REFUNDED},
+}
+
+// CanTransitionOrder reports whether an order in status from may move to status to
+func CanTransitionOrder(from string, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RestocksOnTransition reports whether stock reserved at checkout goes back to the
+// catalogue, which is the case when an order is called off before it ships
+func RestocksOnTransition(from string, to string) bool {
+	if to != ORDER_CANCELLED && to != ORDER_REFUNDED {
+		return false
+	}
+	return from == ORDER_PENDING || from == ORDER_PAID || from == ORDER_PROCESSING
+}
+
+// orderProgress ranks the statuses a sub-order passes through on its way to the buyer
+var orderProgress = map[string]int{
+	ORDER_PENDING:    0,
+	ORDER_PAID:       1,
+	ORDER_PROCESSING: 2,
+	ORDER_SHIPPED:    3,
+	ORDER_DELIVERED:  4,
+}
+
+// DeriveOrderStatus works out a parent order's status from its sub-orders: the order is as far
+// along as its slowest sub-order still in progress. Cancelled and refunded sub-orders are left
+// out until none other remain, then the order is refunded if any was, otherwise cancelled.
+func DeriveOrderStatus(sellerOrderStatuses []string) string {
+	status := ""
+	refunded := false
+	for _, next := range sellerOrderStatuses {
+		rank, inProgress := orderProgress[next]
+		if !inProgress {
+			refunded = refunded || next == ORDER_REFUNDED
+			continue
+		}
+		if status == "" || rank < orderProgress[status] {
+			status = next
+		}
+	}
+
+	switch {
+	case status != "":
+		return status
+	case refunded:
+		return ORDER_REFUNDED
+	default:
+		return ORDER_CANCELLED
+	}
+}
+
+// Order is the buyer facing parent order; fulfilment happens on its per seller sub-orders and
+// its status follows theirs
+type Order struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	UserID         uint          `json:"user_id" gorm:"index;not null"`
+	OrderRef       string        `json:"order_ref" gorm:"uniqueIndex;not null"`
+	Status         string        `json:"status" gorm:"default:pending"`
+	TotalAmount    Money         `json:"total_amount" gorm:"not null"`
+	ShippingMethod string        `json:"shipping_method"`
+	CouponCode     string        `json:"coupon_code,omitempty"`
+	Discount       Money         `json:"discount" gorm:"default:0"`
+	SellerOrders   []SellerOrder `json:"seller_orders" gorm:"foreignKey:OrderID"`
+	CreatedAt      time.Time     `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time     `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// SellerOrder holds the part of an order sold by a single seller, with its own
+// subtotal, tax and shipping, status and fulfilment history
+type SellerOrder struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	OrderID  uint   `json:"order_id" gorm:"index;not null"`
+	SellerID uint   `json:"seller_id" gorm:"index;not null"`
+	Status   string `json:"status" gorm:"default:pending"`
+	Subtotal Money  `json:"subtotal" gorm:"not null"`
+	Discount Money  `json:"discount" gorm:"default:0"`
+	// PlatformDiscount is the part of Discount paid for by the platform rather than the seller
+	PlatformDiscount Money                `json:"platform_discount" gorm:"default:0"`
+	Tax              Money                `json:"tax" gorm:"default:0"`
+	Shipping         Money                `json:"shipping" gorm:"default:0"`
+	Items            []OrderItem          `json:"items" gorm:"foreignKey:SellerOrderID"`
+	StatusHistory    []OrderStatusHistory `json:"status_history,omitempty" gorm:"foreignKey:SellerOrderID"`
+	CreatedAt        time.Time            `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time            `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Total is what the buyer pays for the sub-order
+func (o SellerOrder) Total() Money {
+	return o.Subtotal - o.Discount + o.Tax + o.Shipping
+}
+
+// Sales is the value of the goods sold once the discount is taken off, what the buyer paid for them
+func (o SellerOrder) Sales() Money {
+	return o.Subtotal - o.Discount
+}
+
+// SellerSales is what the seller is settled on: only the discount they fund comes off the goods
+func (o SellerOrder) SellerSales() Money {
+	return o.Subtotal - o.Discount + o.PlatformDiscount
+}
+
+// OrderItem is one line of a sub-order. Discount is the coupon's share of the whole line.
+type OrderItem struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	OrderID       uint      `json:"order_id" gorm:"index;not null"`
+	SellerOrderID uint      `json:"seller_order_id" gorm:"index;not null"`
+	ProductID     uint      `json:"product_id" gorm:"index;not null"`
+	VariantID     *uint     `json:"variant_id,omitempty"`
+	SKU           string    `json:"sku,omitempty"`
+	SellerID      uint      `json:"seller_id" gorm:"index;not null"`
+	Name          string    `json:"name" gorm:"not null"`
+	ImageURL      string    `json:"image_url"`
+	Price         Money     `json:"price" gorm:"not null"`
+	Quantity      int       `json:"quantity" gorm:"not null"`
+	Discount      Money     `json:"discount" gorm:"default:0"`
+	CreatedAt     time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+type OrderStatusHistory struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	OrderID       uint      `json:"order_id" gorm:"index;not null"`
+	SellerOrderID uint      `json:"seller_order_id" gorm:"index;not null"`
+	FromStatus    string    `json:"from_status"`
+	ToStatus      string    `json:"to_status" gorm:"not null"`
+	ChangedBy     uint      `json:"changed_by" gorm:"not null"`
+	Note          string    `json:"note"`
+	CreatedAt     time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
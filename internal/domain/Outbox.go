@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+const (
+	OUTBOX_PENDING = "pending"
+	OUTBOX_SENT    = "sent"
+	OUTBOX_DEAD    = "dead"
+)
+
+// OutboxMessage is a rendered notification waiting to be delivered by the outbox worker. It is
+// written in the same transaction as the change it reports, so a notice is never lost or sent for
+// a change that rolled back. Attempts counts deliveries started; NextAttemptAt is both the retry
+// schedule and the lease that hides a message from other workers while it is being sent.
+type OutboxMessage struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"index"`
+	Channel       string     `json:"channel" gorm:"not null"`
+	Recipient     string     `json:"recipient" gorm:"not null"`
+	Template      string     `json:"template" gorm:"index;not null"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body" gorm:"not null"`
+	Sensitive     bool       `json:"sensitive" gorm:"default:false"`
+	Status        string     `json:"status" gorm:"index:idx_outbox_due,priority:1;default:pending"`
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_outbox_due,priority:2;not null"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Redacted hides the body of messages that carry one-time codes, for display to staff. The
+// body of such a message is cleared for good once it is sent or dead.
+func (m OutboxMessage) Redacted() OutboxMessage {
+	if m.Sensitive {
+		m.Body = "[redacted]"
+	}
+	return m
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// PasswordResetCode is a one-time code sent to a user who forgot their password. Only a keyed
+// hash of the code is stored; a code is spent once used, superseded by a newer one or tried too often.
+type PasswordResetCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	Attempts  int        `json:"attempts" gorm:"default:0"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index;default:CURRENT_TIMESTAMP"`
+}
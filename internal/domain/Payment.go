@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+const (
+	PAYMENT_PENDING    = "pending"
+	PAYMENT_SUCCESSFUL = "successful"
+	PAYMENT_FAILED     = "failed"
+	// PAYMENT_CANCELLED is a payment its order no longer accepts, because the order was called
+	// off or its total changed after the payment was started
+	PAYMENT_CANCELLED = "cancelled"
+	// PAYMENT_UNAPPLIED is money captured for a payment its order could no longer take; it is
+	// held in clearing until it goes back to the buyer
+	PAYMENT_UNAPPLIED = "unapplied"
+	// PAYMENT_REFUNDED is unapplied money the provider has accepted to send back to the buyer
+	PAYMENT_REFUNDED = "refunded"
+)
+
+type Payment struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	OrderID      uint      `json:"order_id" gorm:"index;not null"`
+	UserID       uint      `json:"user_id" gorm:"index;not null"`
+	TxRef        string    `json:"tx_ref" gorm:"uniqueIndex;not null"`
+	Provider     string    `json:"provider" gorm:"not null"`
+	ProviderTxID string    `json:"provider_tx_id"`
+	RefundID     string    `json:"refund_id,omitempty"`
+	Amount       float64   `json:"amount" gorm:"not null"`
+	Currency     string    `json:"currency" gorm:"not null"`
+	Status       string    `json:"status" gorm:"default:pending"`
+	PaymentLink  string    `json:"payment_link"`
+	CreatedAt    time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// RefundReference is the reference an unapplied payment is refunded under, by which the refund
+// is found again
+func (p Payment) RefundReference() string {
+	return "RFD-" + p.TxRef
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+const (
+	PAYOUT_PENDING    = "pending"
+	PAYOUT_PROCESSING = "processing"
+	PAYOUT_SUCCESSFUL = "successful"
+	PAYOUT_FAILED     = "failed"
+)
+
+type Payout struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	SellerID          uint      `json:"seller_id" gorm:"index;not null"`
+	Reference         string    `json:"reference" gorm:"uniqueIndex;not null"`
+	Amount            float64   `json:"amount" gorm:"type:numeric(14,2);not null"`
+	Currency          string    `json:"currency" gorm:"not null"`
+	BankAccountNumber string    `json:"bank_account_number" gorm:"not null"`
+	BankCode          string    `json:"bank_code" gorm:"not null"`
+	Status            string    `json:"status" gorm:"default:pending"`
+	ProviderRef       string    `json:"provider_ref"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	CreatedAt         time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaxRule sets the tax rate for a country, or for one state of it when State is set
+type TaxRule struct {
+	Country     string
+	State       string
+	BasisPoints int64
+}
+
+// ShippingRate is the price of sending one seller's part of an order by a shipping method
+type ShippingRate struct {
+	Method string
+	Amount Money
+}
+
+// PricingRules turn priced cart lines into the totals a buyer pays. The cart summary and
+// checkout both use them, so the total quoted is the total charged.
+type PricingRules struct {
+	TaxRules              []TaxRule
+	DefaultTaxBasisPoints int64
+	// ShippingRates lists the methods on offer; the first is the default
+	ShippingRates []ShippingRate
+	// FreeShippingOver waives the default method for a seller's part of the order once its
+	// subtotal reaches this amount. Zero turns it off.
+	FreeShippingOver Money
+}
+
+// SellerSummary is one seller's part of the cart; each seller ships their items separately
+type SellerSummary struct {
+	SellerID uint       `json:"seller_id"`
+	Items    []CartLine `json:"items"`
+	Subtotal Money      `json:"subtotal"`
+	Discount Money      `json:"discount"`
+	Tax      Money      `json:"tax"`
+	Shipping Money      `json:"shipping"`
+	Total    Money      `json:"total"`
+}
+
+// ShippingQuote is what the whole cart would cost with a given shipping method
+type ShippingQuote struct {
+	Method   string `json:"method"`
+	Shipping Money  `json:"shipping"`
+	Total    Money  `json:"total"`
+}
+
+type CartSummary struct {
+	Sellers        []SellerSummary `json:"sellers"`
+	Subtotal       Money           `json:"subtotal"`
+	Coupon         string          `json:"coupon,omitempty"`
+	CouponMessage  string          `json:"coupon_message,omitempty"`
+	Discount       Money           `json:"discount"`
+	TaxRate        float64         `json:"tax_rate"`
+	Tax            Money           `json:"tax"`
+	ShippingMethod string          `json:"shipping_method"`
+	Shipping       Money           `json:"shipping"`
+	ShippingQuotes []ShippingQuote `json:"shipping_quotes"`
+	Total          Money           `json:"total"`
+}
+
+// TaxBasisPoints picks the most specific rule for the address: its state, then its country,
+// then the default. Without an address only the default applies.
+func (r PricingRules) TaxBasisPoints(address *Address) int64 {
+	if address == nil {
+		return r.DefaultTaxBasisPoints
+	}
+
+	rate, found := r.DefaultTaxBasisPoints, false
+	for _, rule := range r.TaxRules {
+		if !strings.EqualFold(rule.Country, strings.TrimSpace(address.Country)) {
+			continue
+		}
+		if rule.State == "" && !found {
+			rate = rule.BasisPoints
+		}
+		if rule.State != "" && strings.EqualFold(rule.State, strings.TrimSpace(address.State)) {
+			rate, found = rule.BasisPoints, true
+		}
+	}
+	return rate
+}
+
+// shipping prices one seller's shipment by method
+func (r PricingRules) shipping(method string, subtotal Money) (Money, error) {
+	for i, rate := range r.ShippingRates {
+		if rate.Method != method {
+			continue
+		}
+		if i == 0 && r.FreeShippingOver > 0 && subtotal >= r.FreeShippingOver {
+			return 0, nil
+		}
+		return rate.Amount, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownShippingMethod, method)
+}
+
+// Summarize totals the available cart lines per seller, takes off their discounts, adds tax on
+// what is left for the delivery address and shipping by method, and quotes every other method
+// alongside. An empty method picks the default.
+func (r PricingRules) Summarize(lines []CartLine, address *Address, method string) (*CartSummary, error) {
+	if method == "" && len(r.ShippingRates) > 0 {
+		method = r.ShippingRates[0].Method
+	}
+	basisPoints := r.TaxBasisPoints(address)
+
+	summary := &CartSummary{
+		Sellers:        []SellerSummary{},
+		TaxRate:        float64(basisPoints) / 100,
+		ShippingMethod: method,
+		ShippingQuotes: []ShippingQuote{},
+	}
+
+	sellerIndex := map[uint]int{}
+	for _, line := range lines {
+		if line.Unavailable() {
+			continue
+		}
+		idx, ok := sellerIndex[line.SellerID]
+		if !ok {
+			summary.Sellers = append(summary.Sellers, SellerSummary{SellerID: line.SellerID})
+			idx = len(summary.Sellers) - 1
+			sellerIndex[line.SellerID] = idx
+		}
+		seller := &summary.Sellers[idx]
+		seller.Items = append(seller.Items, line)
+		seller.Subtotal += line.LineTotal()
+		seller.Discount += line.Discount
+	}
+
+	var goods Money
+	for i := range summary.Sellers {
+		seller := &summary.Sellers[i]
+		payable := seller.Subtotal - seller.Discount
+		shipping, err := r.shipping(method, payable)
+		if err != nil {
+			return nil, err
+		}
+		seller.Tax = payable.ApplyRate(basisPoints)
+		seller.Shipping = shipping
+		seller.Total = payable + seller.Tax + seller.Shipping
+
+		goods += payable + seller.Tax
+		summary.Subtotal += seller.Subtotal
+		summary.Discount += seller.Discount
+		summary.Tax += seller.Tax
+		summary.Shipping += seller.Shipping
+		summary.Total += seller.Total
+	}
+
+	for _, rate := range r.ShippingRates {
+		quote := ShippingQuote{Method: rate.Method}
+		for _, seller := range summary.Sellers {
+			shipping, err := r.shipping(rate.Method, seller.Subtotal-seller.Discount)
+			if err != nil {
+				return nil, err
+			}
+			quote.Shipping += shipping
+		}
+		quote.Total = goods + quote.Shipping
+		summary.ShippingQuotes = append(summary.ShippingQuotes, quote)
+	}
+
+	return summary, nil
+}
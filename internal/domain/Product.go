@@ -1,16 +1,191 @@
 package domain
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
+// Product is what buyers browse. A product with variants is sold per SKU: its Price and Stock
+// then summarise the variants (the lowest price and the total stock) and MinPrice/MaxPrice give
+// the price range. Without variants MinPrice and MaxPrice both equal Price.
 type Product struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"not null"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price" gorm:"not null"`
-	CategoryID  uint      `json:"category_id" gorm:"not null"`
-	Stock       int       `json:"stock" gorm:"default:0"`
-	ImageURL    string    `json:"image_url"`
-	SellerID    uint      `json:"seller_id" gorm:"not null"`
-	CreatedAt   time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	Name        string           `json:"name" gorm:"not null"`
+	Description string           `json:"description"`
+	Price       float64          `json:"price" gorm:"not null"`
+	MinPrice    float64          `json:"min_price"`
+	MaxPrice    float64          `json:"max_price"`
+	CategoryID  uint             `json:"category_id" gorm:"not null"`
+	Stock       int              `json:"stock" gorm:"default:0"`
+	ImageURL    string           `json:"image_url"`
+	SellerID    uint             `json:"seller_id" gorm:"not null"`
+	HasVariants bool             `json:"has_variants" gorm:"default:false"`
+	Options     []ProductOption  `json:"options,omitempty" gorm:"foreignKey:ProductID"`
+	Variants    []ProductVariant `json:"variants,omitempty" gorm:"foreignKey:ProductID"`
+	Images      []ProductImage   `json:"images,omitempty" gorm:"foreignKey:ProductID"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time        `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+
+	// Filled in on full-text search results only
+	Rank                 float64 `json:"rank,omitempty" gorm:"->;-:migration"`
+	NameHighlight        string  `json:"name_highlight,omitempty" gorm:"->;-:migration"`
+	DescriptionHighlight string  `json:"description_highlight,omitempty" gorm:"->;-:migration"`
+	// Filled in when sorting by popularity, for the pagination cursor
+	UnitsSold int64 `json:"-" gorm:"->;-:migration"`
+}
+
+// ProductImage is one picture in a product's gallery. The first image by position is also
+// copied to the product's ImageURL as its cover.
+type ProductImage struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ProductID    uint      `json:"product_id" gorm:"index;not null"`
+	URL          string    `json:"url" gorm:"not null"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	StorageKey   string    `json:"-" gorm:"not null"`
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Position     int       `json:"position" gorm:"default:0"`
+	CreatedAt    time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// ProductOption is a dimension a product varies on, such as size or colour, with its allowed values
+type ProductOption struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	ProductID uint       `json:"product_id" gorm:"index;not null"`
+	Name      string     `json:"name" gorm:"not null"`
+	Values    StringList `json:"values" gorm:"type:jsonb;not null"`
+	Position  int        `json:"position" gorm:"default:0"`
+}
+
+// ProductVariant is one sellable combination of option values, identified by its SKU
+type ProductVariant struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	ProductID  uint              `json:"product_id" gorm:"index;not null"`
+	SKU        string            `json:"sku" gorm:"uniqueIndex;not null"`
+	Attributes VariantAttributes `json:"attributes" gorm:"type:jsonb;not null"`
+	Price      float64           `json:"price" gorm:"not null"`
+	Stock      int               `json:"stock" gorm:"default:0"`
+	ImageURL   string            `json:"image_url"`
+	CreatedAt  time.Time         `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time         `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Label names the variant by its option values in option order, e.g. "M / Red"
+func (v ProductVariant) Label(options []ProductOption) string {
+	parts := make([]string, 0, len(options))
+	for _, option := range options {
+		if value, ok := v.Attributes[option.Name]; ok {
+			parts = append(parts, value)
+		}
+	}
+	if len(parts) == 0 {
+		return v.SKU
+	}
+	return strings.Join(parts, " / ")
+}
+
+// ValidateVariants checks that every variant picks exactly one allowed value for each option
+// and that no two variants share a combination
+func ValidateVariants(options []ProductOption, variants []ProductVariant) error {
+	allowed := make(map[string]map[string]bool, len(options))
+	for _, option := range options {
+		if option.Name == "" || len(option.Values) == 0 {
+			return fmt.Errorf("%w: options need a name and at least one value", ErrInvalidVariant)
+		}
+		if allowed[option.Name] != nil {
+			return fmt.Errorf("%w: option %q is listed twice", ErrInvalidVariant, option.Name)
+		}
+		allowed[option.Name] = map[string]bool{}
+		for _, value := range option.Values {
+			allowed[option.Name][value] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, variant := range variants {
+		if strings.TrimSpace(variant.SKU) == "" {
+			return fmt.Errorf("%w: every variant needs a sku", ErrInvalidVariant)
+		}
+		if variant.Price <= 0 || variant.Stock < 0 {
+			return fmt.Errorf("%w: %s needs a positive price and non-negative stock", ErrInvalidVariant, variant.SKU)
+		}
+		if len(variant.Attributes) != len(options) {
+			return fmt.Errorf("%w: %s must set a value for each option", ErrInvalidVariant, variant.SKU)
+		}
+		for name, value := range variant.Attributes {
+			if !allowed[name][value] {
+				return fmt.Errorf("%w: %s has %s %q, which is not an option value", ErrInvalidVariant, variant.SKU, name, value)
+			}
+		}
+
+		key := variant.Attributes.key()
+		if seen[key] {
+			return fmt.Errorf("%w: %s repeats another variant's options", ErrInvalidVariant, variant.SKU)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// StringList is stored as a JSON array
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+// VariantAttributes maps option names to the variant's value, stored as a JSON object
+type VariantAttributes map[string]string
+
+func (a VariantAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	return string(data), err
+}
+
+func (a *VariantAttributes) Scan(value interface{}) error {
+	return scanJSON(value, a)
+}
+
+func (a VariantAttributes) key() string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + a[name]
+	}
+	return strings.Join(parts, "&")
+}
+
+func scanJSON(value interface{}, target interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, target)
+	case string:
+		return json.Unmarshal([]byte(data), target)
+	}
+	return errors.New("unsupported JSON column value")
 }
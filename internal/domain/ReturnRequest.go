@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+const (
+	RETURN_REQUESTED      = "requested"
+	RETURN_APPROVED       = "approved"
+	RETURN_REJECTED       = "rejected"
+	RETURN_REFUNDED       = "refunded"
+	RETURN_REFUND_PENDING = "refund_pending"
+	RETURN_REFUND_FAILED  = "refund_failed"
+)
+
+// ReturnRequest is a buyer's request to send back some or all of a delivered order item.
+// An approved return is refunded through the payment provider and restocks the product.
+// A refund whose outcome is unknown stays refund_pending until the provider confirms it;
+// only one the provider declined goes to refund_failed and may be approved again.
+type ReturnRequest struct {
+	ID               uint          `json:"id" gorm:"primaryKey"`
+	Reference        string        `json:"reference" gorm:"uniqueIndex;not null"`
+	OrderID          uint          `json:"order_id" gorm:"index;not null"`
+	SellerOrderID    uint          `json:"seller_order_id" gorm:"index;not null"`
+	OrderItemID      uint          `json:"order_item_id" gorm:"index;not null"`
+	ProductID        uint          `json:"product_id" gorm:"not null"`
+	UserID           uint          `json:"user_id" gorm:"index;not null"`
+	SellerID         uint          `json:"seller_id" gorm:"index;not null"`
+	Quantity         int           `json:"quantity" gorm:"not null"`
+	Reason           string        `json:"reason" gorm:"not null"`
+	Images           []ReturnImage `json:"images,omitempty" gorm:"foreignKey:ReturnRequestID"`
+	Status           string        `json:"status" gorm:"default:requested"`
+	RefundAmount     float64       `json:"refund_amount" gorm:"type:numeric(14,2)"`
+	ProviderRefundID string        `json:"provider_refund_id,omitempty"`
+	SellerNote       string        `json:"seller_note,omitempty"`
+	FailureReason    string        `json:"failure_reason,omitempty"`
+	CreatedAt        time.Time     `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time     `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+type ReturnImage struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	ReturnRequestID uint      `json:"return_request_id" gorm:"index;not null"`
+	URL             string    `json:"url" gorm:"not null"`
+	Position        int       `json:"position"`
+	CreatedAt       time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
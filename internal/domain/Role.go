@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+const (
+	PERMISSION_USERS_READ           = "users:read"
+	PERMISSION_USERS_MANAGE         = "users:manage"
+	PERMISSION_ROLES_MANAGE         = "roles:manage"
+	PERMISSION_CATALOGUE_MODERATE   = "catalogue:moderate"
+	PERMISSION_ORDERS_READ_ALL      = "orders:read_all"
+	PERMISSION_NOTIFICATIONS_MANAGE = "notifications:manage"
+	PERMISSION_AUDIT_READ           = "audit:read"
+	PERMISSION_PROMOTIONS_MANAGE    = "promotions:manage"
+)
+
+// DefaultRolePermissions is seeded on startup. Every user holds the role named by their
+// UserType, plus any roles granted to them explicitly.
+var DefaultRolePermissions = map[string][]string{
+	BUYER:  {},
+	SELLER: {},
+	ADMIN: {
+		PERMISSION_USERS_READ,
+		PERMISSION_USERS_MANAGE,
+		PERMISSION_ROLES_MANAGE,
+		PERMISSION_CATALOGUE_MODERATE,
+		PERMISSION_ORDERS_READ_ALL,
+		PERMISSION_NOTIFICATIONS_MANAGE,
+		PERMISSION_AUDIT_READ,
+		PERMISSION_PROMOTIONS_MANAGE,
+	},
+}
+
+type Role struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"uniqueIndex;not null"`
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+type Permission struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// UserRole grants a role to a user on top of the one implied by their UserType
+type UserRole struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	RoleID    uint      `json:"role_id" gorm:"primaryKey"`
+	GrantedBy uint      `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
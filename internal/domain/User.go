@@ -3,21 +3,68 @@ package domain
 import "time"
 
 const (
+	BUYER  = "buyer"
 	SELLER = "seller"
+	ADMIN  = "admin"
+)
+
+// Notification channels a user can be reached on
+const (
+	CHANNEL_SMS   = "sms"
+	CHANNEL_EMAIL = "email"
 )
 
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	FirstName string    `json:"first_name" gorm:"not null"`
-	LastName  string    `json:"last_name" gorm:"not null"`
-	Email     string    `json:"email" gorm:"not null;unique"`
-	Phone     string    `json:"phone" gorm:"index;not null;unique"`
-	Code      int       `json:"code" gorm:"not null"`
-	Expiry    time.Time `json:"expiry"`
-	Address   Address   `json:"address" gorm:"foreignKey:UserID"`
-	Verified  bool      `json:"verified" gorm:"default:false"`
-	Password  string    `json:"password"`
-	UserType  string    `json:"user_type" gorm:"default:buyer"`
-	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	ID                  uint      `json:"id" gorm:"primaryKey"`
+	FirstName           string    `json:"first_name" gorm:"not null"`
+	LastName            string    `json:"last_name" gorm:"not null"`
+	Email               string    `json:"email" gorm:"not null;unique"`
+	Phone               string    `json:"phone" gorm:"index;not null;unique"`
+	Code                int       `json:"code" gorm:"not null"`
+	CodeChannel         string    `json:"-"`
+	Expiry              time.Time `json:"expiry"`
+	Address             Address   `json:"address" gorm:"foreignKey:UserID"`
+	Verified            bool      `json:"verified" gorm:"default:false"`
+	PhoneVerified       bool      `json:"phone_verified" gorm:"default:false"`
+	EmailVerified       bool      `json:"email_verified" gorm:"default:false"`
+	NotificationChannel string    `json:"notification_channel" gorm:"default:sms"`
+	Password            string    `json:"password"`
+	UserType            string    `json:"user_type" gorm:"default:buyer"`
+	TokenVersion        int       `json:"-" gorm:"default:0"`
+	CreatedAt           time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt           time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// ContactVerified reports whether the contact detail behind channel has been verified.
+// Accounts verified before email existed were verified by phone, which Verified alone records.
+func (u User) ContactVerified(channel string) bool {
+	switch channel {
+	case CHANNEL_EMAIL:
+		return u.EmailVerified
+	case CHANNEL_SMS:
+		return u.PhoneVerified || (u.Verified && !u.EmailVerified)
+	}
+	return false
+}
+
+// ContactChangeUpdates are the verification flags to store when the email and phone become the
+// given values. A changed contact has not been proven yet, so its flag is cleared, and Verified
+// stays set only while a verified contact remains. Nothing is returned when neither changes.
+func (u User) ContactChangeUpdates(email string, phone string) map[string]interface{} {
+	emailChanged, phoneChanged := email != u.Email, phone != u.Phone
+	if !emailChanged && !phoneChanged {
+		return nil
+	}
+
+	emailVerified := u.EmailVerified && !emailChanged
+	phoneVerified := u.ContactVerified(CHANNEL_SMS) && !phoneChanged
+	return map[string]interface{}{
+		"email_verified": emailVerified,
+		"phone_verified": phoneVerified,
+		"verified":       emailVerified || phoneVerified,
+	}
+}
+
+func IsValidChannel(channel string) bool {
+	return channel == CHANNEL_SMS || channel == CHANNEL_EMAIL
 }
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+const (
+	WISHLIST_PRICE_DROP    = "price_drop"
+	WISHLIST_BACK_IN_STOCK = "back_in_stock"
+)
+
+// WishlistItem is a product a user saved for later. AlertPrice is the lowest price the user has
+// been told about, starting with the price when it was saved, so a price-drop alert only fires
+// for a price below anything they have already seen.
+type WishlistItem struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	UserID            uint      `json:"user_id" gorm:"uniqueIndex:idx_wishlist_user_product;not null"`
+	ProductID         uint      `json:"product_id" gorm:"uniqueIndex:idx_wishlist_user_product;index;not null"`
+	Product           *Product  `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	NotifyPriceDrop   bool      `json:"notify_price_drop" gorm:"default:false"`
+	NotifyBackInStock bool      `json:"notify_back_in_stock" gorm:"default:false"`
+	AlertPrice        float64   `json:"-"`
+	CreatedAt         time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// WishlistCount is how many users saved one of a seller's products, and how many of them
+// asked for each alert
+type WishlistCount struct {
+	ProductID   uint   `json:"product_id"`
+	Name        string `json:"name"`
+	Count       int64  `json:"count"`
+	PriceDrop   int64  `json:"price_drop"`
+	BackInStock int64  `json:"back_in_stock"`
+}
+
+// WishlistAlerts reports which wishlist alerts a change to a product sets off. Prices are
+// compared by the lowest price the product sells at, so a cheaper SKU counts as a price drop.
+func WishlistAlerts(before Product, after Product) (priceDrop bool, backInStock bool) {
+	priceDrop = after.MinPrice > 0 && after.MinPrice < before.MinPrice
+	backInStock = before.Stock <= 0 && after.Stock > 0
+	return priceDrop, backInStock
+}
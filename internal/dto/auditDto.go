@@ -0,0 +1,9 @@
+package dto
+
+type AuditQuery struct {
+	PaginationParams
+	ActorID      uint   `json:"actor_id" query:"actor_id"`
+	ResourceType string `json:"resource_type" query:"resource_type"`
+	ResourceID   uint   `json:"resource_id" query:"resource_id"`
+	Outcome      string `json:"outcome" query:"outcome"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// AuthTokens is returned on login and refresh; Token is the short lived access token
+type AuthTokens struct {
+	Token            string    `json:"token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int       `json:"expires_in"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ClientInfo identifies the device a session was opened from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
@@ -8,15 +8,88 @@ type Category struct {
 	DisplayOrder int    `json:"display_order,omitempty"`
 }
 
+// MoveCategoryRequest re-parents a category with its subtree; a null parent_id makes it top level
+type MoveCategoryRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+// What DeleteCategory does with the child categories of the category being deleted
+const (
+	CATEGORY_CHILDREN_CASCADE  = "cascade"
+	CATEGORY_CHILDREN_REASSIGN = "reassign"
+)
+
+// DeleteCategoryOptions say what happens to child categories. Reassigned children move to
+// ReassignTo, or to the deleted category's parent when it is not given.
+type DeleteCategoryOptions struct {
+	Children   string `query:"children"`
+	ReassignTo *uint  `query:"reassign_to"`
+}
+
+// CategoryNode is a category in the category tree. ProductCount covers the whole subtree.
+type CategoryNode struct {
+	ID           uint            `json:"id"`
+	Name         string          `json:"name"`
+	ParentID     *uint           `json:"parent_id"`
+	ImageURL     string          `json:"image_url"`
+	DisplayOrder int             `json:"display_order"`
+	Depth        int             `json:"depth"`
+	ProductCount int64           `json:"product_count"`
+	Children     []*CategoryNode `json:"children"`
+}
+
+// Breadcrumb is one step on the path from the top of the tree to a category
+type Breadcrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Product is the body of product create and update requests. A nil Stock leaves the stock as it is
 type Product struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description,omitempty"`
-	Price       float64 `json:"price"`
-	CategoryID  uint    `json:"category_id"`
-	Stock       int     `json:"stock,omitempty"`
-	ImageURL    string  `json:"image_url,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Price       float64         `json:"price"`
+	CategoryID  uint            `json:"category_id"`
+	Stock       *int            `json:"stock,omitempty"`
+	ImageURL    string          `json:"image_url,omitempty"`
+	Options     []ProductOption `json:"options,omitempty"`
+	Variants    []Variant       `json:"variants,omitempty"`
 }
 
+// ProductOption declares a dimension the product's variants differ on, e.g. {"name": "size", "values": ["S", "M", "L"]}
+type ProductOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Variant is a SKU with one value for every product option, e.g. {"size": "M", "colour": "red"}
+type Variant struct {
+	SKU        string            `json:"sku"`
+	Attributes map[string]string `json:"attributes"`
+	Price      float64           `json:"price"`
+	Stock      int               `json:"stock"`
+	ImageURL   string            `json:"image_url,omitempty"`
+}
+
+type UpdateVariantRequest struct {
+	Price    *float64 `json:"price,omitempty"`
+	Stock    *int     `json:"stock,omitempty"`
+	ImageURL *string  `json:"image_url,omitempty"`
+}
+
+// UpdateStockRequest sets the stock of a product, or of one of its SKUs when SKU is given
 type UpdateStockRequest struct {
-	Stock int `json:"stock"`
+	SKU   string `json:"sku,omitempty"`
+	Stock int    `json:"stock"`
+}
+
+// ImageUpload is one uploaded image file read from a multipart form
+type ImageUpload struct {
+	Filename string
+	Data     []byte
+}
+
+// ReorderImagesRequest lists every image of a product in its new gallery order
+type ReorderImagesRequest struct {
+	ImageIDs []uint `json:"image_ids"`
 }
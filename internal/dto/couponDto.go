@@ -0,0 +1,42 @@
+package dto
+
+import "time"
+
+// CreateCouponRequest sets up a discount code. Percent applies to "percent" coupons and
+// amount_off to "fixed" ones; seller_id, product_id and category_id narrow what it covers.
+// funded_by is only read from admins; their coupons are funded by the platform unless set to
+// "seller" on a coupon limited to one seller.
+type CreateCouponRequest struct {
+	Code           string     `json:"code"`
+	Description    string     `json:"description,omitempty"`
+	Type           string     `json:"type"`
+	Percent        float64    `json:"percent,omitempty"`
+	AmountOff      float64    `json:"amount_off,omitempty"`
+	MinimumSpend   float64    `json:"minimum_spend,omitempty"`
+	SellerID       *uint      `json:"seller_id,omitempty"`
+	ProductID      *uint      `json:"product_id,omitempty"`
+	CategoryID     *uint      `json:"category_id,omitempty"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	MaxRedemptions int        `json:"max_redemptions,omitempty"`
+	MaxPerUser     int        `json:"max_per_user,omitempty"`
+	FundedBy       string     `json:"funded_by,omitempty"`
+}
+
+type UpdateCouponRequest struct {
+	Description    *string    `json:"description,omitempty"`
+	Active         *bool      `json:"active,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	MaxRedemptions *int       `json:"max_redemptions,omitempty"`
+	MaxPerUser     *int       `json:"max_per_user,omitempty"`
+}
+
+type CouponQuery struct {
+	PaginationParams
+	Active   *bool `json:"active" query:"active"`
+	SellerID uint  `json:"seller_id" query:"seller_id"`
+}
+
+type ApplyCouponRequest struct {
+	Code string `json:"code"`
+}
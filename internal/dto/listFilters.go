@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+// ListFilters are the filters the product and category listings share
+type ListFilters struct {
+	Search    string     `json:"search" query:"search"`
+	Beginning *time.Time `json:"beginning" query:"beginning"` // ISO 8601 date format: 2024-01-01T00:00:00Z
+	Ending    *time.Time `json:"ending" query:"ending"`       // ISO 8601 date format: 2024-02-01T00:00:00Z
+	SellerID  *uint      `json:"seller_id" query:"seller_id"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// CheckoutRequest picks how the order ships; the default shipping method is used when empty
+type CheckoutRequest struct {
+	ShippingMethod string `json:"shipping_method,omitempty"`
+}
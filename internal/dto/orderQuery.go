@@ -0,0 +1,6 @@
+package dto
+
+type OrderQuery struct {
+	PaginationParams
+	Status string `json:"status" query:"status"`
+}
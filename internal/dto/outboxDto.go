@@ -0,0 +1,8 @@
+package dto
+
+type OutboxQuery struct {
+	PaginationParams
+	Status   string `json:"status" query:"status"`
+	Template string `json:"template" query:"template"`
+	UserID   uint   `json:"user_id" query:"user_id"`
+}
@@ -1,12 +1,17 @@
 package dto
 
+// PaginationParams pages a listing by offset with take/skip, or by keyset with an after/before
+// cursor taken from a previous response. Cursor pages stay stable while rows are added.
 type PaginationParams struct {
-	Take int `json:"take" query:"take" validate:"min=1,max=100"`
-	Skip int `json:"skip" query:"skip" validate:"min=0"`
+	Take   int    `json:"take" query:"take" validate:"min=1,max=100"`
+	Skip   int    `json:"skip" query:"skip" validate:"min=0"`
+	After  string `json:"after" query:"after"`
+	Before string `json:"before" query:"before"`
+	Count  *bool  `json:"count" query:"count"` // defaults to true for offset pages, false for cursor pages
 }
 
 func (p *PaginationParams) GetOffset() int {
-	if p.Skip < 0 {
+	if p.Skip < 0 || p.IsCursor() {
 		return 0
 	}
 	return p.Skip
@@ -22,13 +27,46 @@ func (p *PaginationParams) GetLimit() int {
 	return p.Take
 }
 
+// IsCursor reports whether the page is addressed by cursor rather than by offset
+func (p *PaginationParams) IsCursor() bool {
+	return p.After != "" || p.Before != ""
+}
+
+// ShouldCount reports whether the total number of matching rows is wanted
+func (p *PaginationParams) ShouldCount() bool {
+	if p.Count != nil {
+		return *p.Count
+	}
+	return !p.IsCursor()
+}
+
 type PaginatedResponse struct {
 	Data       interface{}    `json:"data"`
 	Pagination PaginationMeta `json:"pagination"`
+	Facets     interface{}    `json:"facets,omitempty"`
 }
 
 type PaginationMeta struct {
-	Take  int   `json:"take"`
-	Skip  int   `json:"skip"`
-	Total int64 `json:"total"`
+	Take       int    `json:"take"`
+	Skip       int    `json:"skip"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// PageInfo is what a listing query learns about the page it returned
+type PageInfo struct {
+	Total      *int64
+	NextCursor string
+	PrevCursor string
+}
+
+func NewPaginationMeta(params PaginationParams, page PageInfo) PaginationMeta {
+	return PaginationMeta{
+		Take:       params.GetLimit(),
+		Skip:       params.GetOffset(),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
 }
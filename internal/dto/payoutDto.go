@@ -0,0 +1,8 @@
+package dto
+
+type SellerBalance struct {
+	Available    float64 `json:"available"`
+	TotalEarned  float64 `json:"total_earned"`
+	TotalPaidOut float64 `json:"total_paid_out"`
+	Currency     string  `json:"currency"`
+}
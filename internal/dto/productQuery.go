@@ -1,10 +1,66 @@
 package dto
 
-import "time"
+// Product listing sort orders. Without a sort, searches are ordered by relevance and
+// everything else by newest first.
+const (
+	SORT_RELEVANCE  = "relevance"
+	SORT_NEWEST     = "newest"
+	SORT_PRICE_ASC  = "price_asc"
+	SORT_PRICE_DESC = "price_desc"
+	SORT_NAME       = "name"
+	SORT_POPULARITY = "popularity"
+)
 
 type ProductQuery struct {
 	PaginationParams
-	Search    string     `json:"search" query:"search"`
-	Beginning *time.Time `json:"beginning" query:"beginning"` // ISO 8601 date format: 2024-01-01T00:00:00Z
-	Ending    *time.Time `json:"ending" query:"ending"`       // ISO 8601 date format: 2024-02-01T00:00:00Z
+	ListFilters
+	CategoryID *uint    `json:"category_id" query:"category_id"` // includes the category's descendants
+	MinPrice   *float64 `json:"min_price" query:"min_price"`
+	MaxPrice   *float64 `json:"max_price" query:"max_price"`
+	InStock    bool     `json:"in_stock" query:"in_stock"`
+	Sort       string   `json:"sort" query:"sort"`
+	Facets     *bool    `json:"facets" query:"facets"` // defaults to true on the first page only
+}
+
+// ShouldComputeFacets reports whether facets are wanted. They do not change from page to page,
+// so by default only the first page pays for the aggregate queries.
+func (q *ProductQuery) ShouldComputeFacets() bool {
+	if q.Facets != nil {
+		return *q.Facets
+	}
+	return !q.IsCursor() && q.GetOffset() == 0
+}
+
+func IsValidProductSort(sort string) bool {
+	switch sort {
+	case "", SORT_RELEVANCE, SORT_NEWEST, SORT_PRICE_ASC, SORT_PRICE_DESC, SORT_NAME, SORT_POPULARITY:
+		return true
+	}
+	return false
+}
+
+// ProductFacets counts the products matching a query by category, price range and seller
+type ProductFacets struct {
+	Categories  []CategoryFacet `json:"categories"`
+	PriceRanges []PriceFacet    `json:"price_ranges"`
+	Sellers     []SellerFacet   `json:"sellers"`
+}
+
+type CategoryFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// PriceFacet counts products priced from Min up to, but not including, Max; the last range has no Max
+type PriceFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+type SellerFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
 }
@@ -0,0 +1,23 @@
+package dto
+
+type CreateReturnRequest struct {
+	OrderItemID uint     `json:"order_item_id"`
+	Quantity    int      `json:"quantity"`
+	Reason      string   `json:"reason"`
+	Images      []string `json:"images,omitempty"`
+}
+
+// ApproveReturnRequest refunds the full value of the returned units unless RefundAmount is set
+type ApproveReturnRequest struct {
+	RefundAmount float64 `json:"refund_amount,omitempty"`
+	Note         string  `json:"note,omitempty"`
+}
+
+type RejectReturnRequest struct {
+	Note string `json:"note"`
+}
+
+type ReturnQuery struct {
+	PaginationParams
+	Status string `json:"status" query:"status"`
+}
@@ -3,12 +3,14 @@ package dto
 type CreateCartRequest struct {
 	Quantity  int     `json:"quantity"`
 	ProductID uint    `json:"product_id"`
+	VariantID *uint   `json:"variant_id,omitempty"`
 }
 
+// UpdateCartRequest changes a line's quantity. Prices always come from the catalogue.
 type UpdateCartRequest struct {
 	Quantity *int `json:"quantity,omitempty"`
-	Price    *float64 `json:"price,omitempty"`
 	ProductID *uint `json:"product_id,omitempty"`
+	VariantID *uint `json:"variant_id,omitempty"`
 }
 
 type DeleteCartRequest struct {
@@ -24,6 +24,16 @@ type VerificationCodeInput struct {
 	Code int `json:"code"`
 }
 
+type ForgotPasswordInput struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordInput struct {
+	Email       string `json:"email"`
+	Code        int    `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
 type BecomeSellerInput struct {
 	FirstName         string `json:"first_name"`
 	LastName          string `json:"last_name"`
@@ -58,7 +68,8 @@ type AddressUpdateInput struct {
 }
 
 type ProfileUpdateInput struct {
-	FirstName *string            `json:"first_name,omitempty"`
-	LastName  *string            `json:"last_name,omitempty"`
-	Address   AddressUpdateInput `json:"address,omitempty"`
+	FirstName           *string            `json:"first_name,omitempty"`
+	LastName            *string            `json:"last_name,omitempty"`
+	NotificationChannel *string            `json:"notification_channel,omitempty"`
+	Address             AddressUpdateInput `json:"address,omitempty"`
 }
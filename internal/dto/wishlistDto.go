@@ -0,0 +1,21 @@
+package dto
+
+// AddWishlistRequest saves a product, optionally asking to be told when it gets cheaper or is
+// back in stock
+type AddWishlistRequest struct {
+	ProductID         uint `json:"product_id"`
+	NotifyPriceDrop   bool `json:"notify_price_drop,omitempty"`
+	NotifyBackInStock bool `json:"notify_back_in_stock,omitempty"`
+}
+
+type UpdateWishlistRequest struct {
+	NotifyPriceDrop   *bool `json:"notify_price_drop,omitempty"`
+	NotifyBackInStock *bool `json:"notify_back_in_stock,omitempty"`
+}
+
+// MoveToCartRequest picks the quantity, and the SKU for a product with variants, of a wishlist
+// item moved to the cart
+type MoveToCartRequest struct {
+	Quantity  int   `json:"quantity,omitempty"`
+	VariantID *uint `json:"variant_id,omitempty"`
+}
@@ -1,9 +1,16 @@
 package helper
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go-ecommerce-app/internal/domain"
+	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,15 +19,35 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// AccessTokenTTL is how long an access token is accepted; clients renew it with their refresh token
+const AccessTokenTTL = time.Hour
+
+// TokenDenylist reports whether an access token was revoked before it expired, either on its
+// own through logout or because every session of the user was ended
+type TokenDenylist interface {
+	IsTokenRevoked(jti string, userID uint, tokenVersion int) (bool, error)
+}
+
+// TokenClaims is what an access token carries besides the user. SessionID is the family of
+// the refresh token the access token was issued with.
+type TokenClaims struct {
+	ID           string
+	SessionID    string
+	User         domain.User
+	TokenVersion int
+	ExpiresAt    time.Time
+}
+
 type Auth struct {
-	Secret string
+	Secret   string
+	Denylist TokenDenylist
 }
 
-func SetupAuth(secret string) Auth {
+func SetupAuth(secret string, denylist TokenDenylist) Auth {
 	if secret == "" {
 		panic("JWT_SECRET cannot be empty")
 	}
-	return Auth{Secret: secret}
+	return Auth{Secret: secret, Denylist: denylist}
 }
 
 func (a Auth) CreateHashedPassword(password string) (string, error) {
@@ -37,7 +64,7 @@ func (a Auth) CreateHashedPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-func (a Auth) GenerateToken(userId uint, email string, role string) (string, error) {
+func (a Auth) GenerateToken(userId uint, email string, role string, tokenVersion int, sessionID string) (string, error) {
 
 	if userId == 0 || email == "" || role == "" {
 		return "", errors.New("invalid user id, email or role")
@@ -47,11 +74,21 @@ func (a Auth) GenerateToken(userId uint, email string, role string) (string, err
 		return "", errors.New("JWT secret is not configured")
 	}
 
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", errors.New("failed to generate token")
+	}
+
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":   jti,
 		"sub":   userId,
 		"email": email,
 		"role":  role,
-		"exp":   time.Now().Add(time.Hour * 1).Unix(),
+		"ver":   tokenVersion,
+		"sid":   sessionID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(AccessTokenTTL).Unix(),
 	})
 
 	tokenString, err := token.SignedString([]byte(a.Secret))
@@ -77,20 +114,29 @@ func (a Auth) VerifyPassword(password string, hashedPassword string) (bool, erro
 }
 
 func (a Auth) VerifyToken(token string) (domain.User, error) {
+	claims, err := a.ParseToken(token)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return claims.User, nil
+}
+
+// ParseToken checks the signature and expiry of a "Bearer <token>" header value and returns its claims
+func (a Auth) ParseToken(token string) (*TokenClaims, error) {
 
 	tokenArray := strings.Split(token, " ")
 
 	if len(tokenArray) != 2 {
-		return domain.User{}, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	tokenString := tokenArray[1]
 
 	if tokenArray[0] != "Bearer" {
-		return domain.User{}, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 	if a.Secret == "" {
-		return domain.User{}, errors.New("JWT secret is not configured")
+		return nil, errors.New("JWT secret is not configured")
 	}
 
 	parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -101,23 +147,58 @@ func (a Auth) VerifyToken(token string) (domain.User, error) {
 	})
 
 	if err != nil {
-		return domain.User{}, fmt.Errorf("failed to parse token: %v", err)
+		return nil, fmt.Errorf("failed to parse token: %v", err)
 	}
 
 	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			return domain.User{}, errors.New("token is expired")
+		exp, _ := claims["exp"].(float64)
+		if float64(time.Now().Unix()) > exp {
+			return nil, errors.New("token is expired")
 		}
 
+		sub, _ := claims["sub"].(float64)
+		email, _ := claims["email"].(string)
+		role, _ := claims["role"].(string)
+		jti, _ := claims["jti"].(string)
+		sid, _ := claims["sid"].(string)
+		version, _ := claims["ver"].(float64)
+
 		user := domain.User{}
-		user.ID = uint(claims["sub"].(float64))
-		user.Email = claims["email"].(string)
-		user.UserType = claims["role"].(string)
+		user.ID = uint(sub)
+		user.Email = email
+		user.UserType = role
+
+		return &TokenClaims{
+			ID:           jti,
+			SessionID:    sid,
+			User:         user,
+			TokenVersion: int(version),
+			ExpiresAt:    time.Unix(int64(exp), 0),
+		}, nil
+	}
+
+	return nil, errors.New("invalid token claims")
+}
+
+// authenticate parses the Authorization header and rejects tokens that were revoked
+func (a Auth) authenticate(authHeader string) (*TokenClaims, error) {
+	claims, err := a.ParseToken(authHeader)
+	if err != nil {
+		return nil, err
+	}
 
-		return user, nil
+	if a.Denylist != nil {
+		revoked, err := a.Denylist.IsTokenRevoked(claims.ID, claims.User.ID, claims.TokenVersion)
+		if err != nil {
+			log.Printf("Failed to check token revocation: %v", err)
+			return nil, errors.New("failed to verify token")
+		}
+		if revoked {
+			return nil, errors.New("token has been revoked")
+		}
 	}
 
-	return domain.User{}, errors.New("invalid token claims")
+	return claims, nil
 }
 
 func (a Auth) Authorize(ctx *fiber.Ctx) error {
@@ -129,9 +210,10 @@ func (a Auth) Authorize(ctx *fiber.Ctx) error {
 		})
 	}
 
-	user, err := a.VerifyToken(authHeader)
-	if err == nil && user.ID > 0 {
-		ctx.Locals("user", user)
+	claims, err := a.authenticate(authHeader)
+	if err == nil && claims.User.ID > 0 {
+		ctx.Locals("user", claims.User)
+		ctx.Locals("token_claims", *claims)
 		return ctx.Next()
 	} else {
 		errMsg := "unauthorized"
@@ -153,16 +235,85 @@ func (a Auth) GetCurrentUser(ctx *fiber.Ctx) domain.User {
 	return user
 }
 
+// GetCurrentTokenClaims returns the claims of the access token that authorized the request
+func (a Auth) GetCurrentTokenClaims(ctx *fiber.Ctx) TokenClaims {
+	claims, ok := ctx.Locals("token_claims").(TokenClaims)
+	if !ok {
+		return TokenClaims{}
+	}
+	return claims
+}
+
 func (a Auth) GenerateVerificationCode() (int, error) {
 	return RandomNumbers(6)
 }
 
+// HashCode keys a short numeric code with the JWT secret, so stored codes cannot be brute forced offline
+func (a Auth) HashCode(code int) string {
+	mac := hmac.New(sha256.New, []byte(a.Secret))
+	mac.Write([]byte(strconv.Itoa(code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCode compares a code against a hash from HashCode in constant time
+func (a Auth) VerifyCode(code int, codeHash string) bool {
+	return hmac.Equal([]byte(a.HashCode(code)), []byte(codeHash))
+}
+
+// GenerateRefreshToken returns an opaque refresh token for the client and the hash to store for it
+func (a Auth) GenerateRefreshToken() (string, string, error) {
+	token, err := RandomToken(32)
+	if err != nil {
+		return "", "", errors.New("failed to generate refresh token")
+	}
+	return token, HashToken(token), nil
+}
+
+// GenerateCartToken returns a new guest cart key and the signed token the visitor keeps for it
+func (a Auth) GenerateCartToken() (string, string, error) {
+	key, err := RandomToken(24)
+	if err != nil {
+		return "", "", errors.New("failed to generate cart token")
+	}
+	return key, key + "." + a.signCartKey(key), nil
+}
+
+// VerifyCartToken checks the signature of a token from GenerateCartToken and returns its cart key
+func (a Auth) VerifyCartToken(token string) (string, error) {
+	key, signature, found := strings.Cut(token, ".")
+	if !found || key == "" || !hmac.Equal([]byte(signature), []byte(a.signCartKey(key))) {
+		return "", domain.ErrInvalidCartToken
+	}
+	return key, nil
+}
+
+func (a Auth) signCartKey(key string) string {
+	mac := hmac.New(sha256.New, []byte(a.Secret))
+	mac.Write([]byte("guest-cart:" + key))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// HashToken is the form in which refresh tokens are stored, so a database leak does not leak sessions
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomToken returns size random bytes encoded as URL safe base64
+func RandomToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
 func (a Auth) AuthorizeSeller(userRepo interface {
 	FindUserByID(id uint) (*domain.User, error)
 }) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		authHeader := ctx.Get("Authorization")
-		tokenUser, err := a.VerifyToken(authHeader)
+		claims, err := a.authenticate(authHeader)
 		if err != nil {
 			return ctx.Status(401).JSON(fiber.Map{
 				"message": "authorization failed",
@@ -170,6 +321,7 @@ func (a Auth) AuthorizeSeller(userRepo interface {
 			})
 		}
 
+		tokenUser := claims.User
 		if tokenUser.ID == 0 {
 			return ctx.Status(401).JSON(fiber.Map{
 				"message": "authorization failed",
@@ -193,6 +345,61 @@ func (a Auth) AuthorizeSeller(userRepo interface {
 		}
 
 		ctx.Locals("user", *dbUser)
+		ctx.Locals("token_claims", *claims)
+		return ctx.Next()
+	}
+}
+
+// PermissionFinder loads the names of the permissions a user holds through their roles
+type PermissionFinder interface {
+	FindPermissionsByUserID(userID uint) ([]string, error)
+}
+
+// RequirePermission authenticates the request like Authorize and then lets it through only
+// when the user holds every one of the given permissions
+func (a Auth) RequirePermission(finder PermissionFinder, permissions ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		claims, err := a.authenticate(ctx.Get("Authorization"))
+		if err != nil || claims.User.ID == 0 {
+			reason := "invalid token"
+			if err != nil {
+				reason = err.Error()
+			}
+			return ctx.Status(401).JSON(fiber.Map{
+				"message": "authorization failed",
+				"reason":  reason,
+			})
+		}
+
+		granted, err := finder.FindPermissionsByUserID(claims.User.ID)
+		if err != nil {
+			log.Printf("Failed to load permissions for user %d: %v", claims.User.ID, err)
+			return ctx.Status(500).JSON(fiber.Map{
+				"message": "authorization failed",
+				"reason":  "failed to load permissions",
+			})
+		}
+
+		for _, permission := range permissions {
+			if !containsString(granted, permission) {
+				return ctx.Status(403).JSON(fiber.Map{
+					"message": "authorization failed",
+					"reason":  "missing permission " + permission,
+				})
+			}
+		}
+
+		ctx.Locals("user", claims.User)
+		ctx.Locals("token_claims", *claims)
 		return ctx.Next()
 	}
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
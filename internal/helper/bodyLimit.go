@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LimitBody rejects request bodies larger than limit, except for requests exempt reports true
+// for. The server reads bodies as streams, so a body that is too large is refused before it is
+// read into memory.
+func LimitBody(limit int, exempt func(ctx *fiber.Ctx) bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if exempt(ctx) {
+			return ctx.Next()
+		}
+
+		length := ctx.Request().Header.ContentLength()
+		if length > limit {
+			return bodyTooLarge(ctx)
+		}
+
+		// chunked bodies do not declare a length, read them up to the limit
+		if length < 0 {
+			if stream := ctx.Request().BodyStream(); stream != nil {
+				body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+				if err != nil {
+					return HandleBodyParserError(ctx, err)
+				}
+				if len(body) > limit {
+					return bodyTooLarge(ctx)
+				}
+				ctx.Request().SetBody(body)
+			}
+		}
+
+		return ctx.Next()
+	}
+}
+
+// bodyTooLarge closes the connection too, the unread rest of the body cannot be skipped
+func bodyTooLarge(ctx *fiber.Ctx) error {
+	ctx.Context().SetConnectionClose()
+	return ctx.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"message": "Request body is too large",
+	})
+}
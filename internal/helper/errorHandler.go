@@ -102,6 +102,22 @@ func HandleValidationError(ctx *fiber.Ctx, message string) error {
 	})
 }
 
+// HandleConflictError handles requests that conflict with the current state of a resource
+func HandleConflictError(ctx *fiber.Ctx, message string, err error) error {
+	return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"message": message,
+		"error":   err.Error(),
+	})
+}
+
+// HandleForbiddenError handles requests from users who may not act on a resource
+func HandleForbiddenError(ctx *fiber.Ctx, message string) error {
+	return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"message": message,
+		"error":   "Forbidden",
+	})
+}
+
 // HandleBodyParserError processes JSON parsing errors and returns specific field errors
 func HandleBodyParserError(ctx *fiber.Ctx, err error) error {
 	if err == nil {
@@ -2,8 +2,10 @@ package helper
 
 import (
 	"crypto/rand"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const numbers = "1234567890"
@@ -21,6 +23,15 @@ func RandomNumbers(length int) (int, error) {
 	return strconv.Atoi(string(buffer))
 }
 
+// GenerateReference builds a human readable unique reference such as ORD-1700000000-042137
+func GenerateReference(prefix string) (string, error) {
+	suffix, err := RandomNumbers(6)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%06d", prefix, time.Now().Unix(), suffix), nil
+}
+
 // FormatPhoneToE164 formats a phone number to E.164 format with country code
 // If the number already starts with +, it returns it as is (assuming already formatted)
 // Otherwise, it adds the country code (+234 for Nigeria by default)
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Every runs fn on a fixed interval in the background until the returned stop function is called.
+// A run that is still going when the next tick fires delays that tick instead of overlapping it.
+func Every(name string, interval time.Duration, fn func() error) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := fn(); err != nil {
+					log.Printf("Job %s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+
+	log.Printf("Job %s scheduled every %s", name, interval)
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	RecordEntry(entry *domain.AuditEntry) error
+	FindEntries(query dto.AuditQuery) ([]domain.AuditEntry, dto.PageInfo, error)
+}
+
+type auditRepository struct {
+	DB *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{DB: db}
+}
+
+func (r *auditRepository) RecordEntry(entry *domain.AuditEntry) error {
+	err := r.DB.Create(entry).Error
+	if err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+	}
+	return err
+}
+
+// FindEntries lists the audit trail, newest first
+func (r *auditRepository) FindEntries(query dto.AuditQuery) ([]domain.AuditEntry, dto.PageInfo, error) {
+	db := r.DB.Model(&domain.AuditEntry{})
+	if query.ActorID != 0 {
+		db = db.Where("actor_id = ?", query.ActorID)
+	}
+	if query.ResourceType != "" {
+		db = db.Where("resource_type = ?", query.ResourceType)
+	}
+	if query.ResourceID != 0 {
+		db = db.Where("resource_id = ?", query.ResourceID)
+	}
+	if query.Outcome != "" {
+		db = db.Where("outcome = ?", query.Outcome)
+	}
+
+	return paginate(db, query.PaginationParams, listing[domain.AuditEntry]{
+		sort: "newest",
+		keys: []sortKey{
+			{expr: "created_at", cast: "timestamptz", desc: true},
+			{expr: "id", cast: "bigint", desc: true},
+		},
+		values: func(e domain.AuditEntry) []interface{} {
+			return []interface{}{e.CreatedAt, e.ID}
+		},
+	})
+}
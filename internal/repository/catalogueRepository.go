@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"fmt"
 	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
+	"html"
 	"log"
+	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -12,18 +15,38 @@ import (
 type CatalogueRepository interface {
 	// Category methods
 	CreateCategory(sellerID uint, category dto.Category) (*domain.Category, error)
-	GetCategories(query dto.CategoryQuery) ([]domain.Category, int64, error)
+	GetCategories(query dto.CategoryQuery) ([]domain.Category, dto.PageInfo, error)
 	GetCategoryByID(id uint) (*domain.Category, error)
 	UpdateCategory(id uint, category dto.Category) (*domain.Category, error)
 	CountProductsByCategoryID(categoryID uint) (int64, error)
-	DeleteCategory(id uint) error
+	DeleteCategory(id uint, options dto.DeleteCategoryOptions) error
+	GetCategoryTree(rootID *uint) ([]dto.CategoryNode, error)
+	GetCategoryAncestors(id uint) ([]domain.Category, error)
+	CountSubtreeProducts(categoryID uint) (int64, error)
+	MoveCategory(id uint, parentID *uint) (*domain.Category, error)
 
 	// Product methods
 	CreateProduct(sellerID uint, product dto.Product) (*domain.Product, error)
-	GetProducts(query dto.ProductQuery) ([]domain.Product, int64, error)
+	GetProducts(query dto.ProductQuery) ([]domain.Product, dto.PageInfo, error)
+	GetProductFacets(query dto.ProductQuery) (*dto.ProductFacets, error)
 	GetProductByID(id uint) (*domain.Product, error)
+	GetProductsByIDs(ids []uint) ([]domain.Product, error)
 	UpdateProduct(id uint, product dto.Product) (*domain.Product, error)
 	DeleteProduct(id uint) error
+
+	// Variant methods
+	CreateVariant(variant *domain.ProductVariant) (*domain.ProductVariant, error)
+	GetVariantByID(productID uint, variantID uint) (*domain.ProductVariant, error)
+	GetVariantBySKU(productID uint, sku string) (*domain.ProductVariant, error)
+	UpdateVariant(variant *domain.ProductVariant, updates map[string]interface{}) (*domain.ProductVariant, error)
+	DeleteVariant(variant *domain.ProductVariant) error
+
+	// Image methods
+	AddProductImages(productID uint, images []domain.ProductImage, limit int) ([]domain.ProductImage, error)
+	GetProductImages(productID uint) ([]domain.ProductImage, error)
+	GetProductImageByID(productID uint, imageID uint) (*domain.ProductImage, error)
+	ReorderProductImages(productID uint, imageIDs []uint) ([]domain.ProductImage, error)
+	DeleteProductImage(image *domain.ProductImage) error
 }
 
 type catalogueRepository struct {
@@ -58,161 +81,621 @@ func (r *catalogueRepository) CreateCategory(sellerID uint, category dto.Categor
 	return &categoryDomain, nil
 }
 
-func (r *catalogueRepository) GetCategories(query dto.CategoryQuery) ([]domain.Category, int64, error) {
-	var categories []domain.Category
-	var total int64
-
-	db := r.DB.Model(&domain.Category{})
+func (r *catalogueRepository) GetCategories(query dto.CategoryQuery) ([]domain.Category, dto.PageInfo, error) {
+	db := r.DB.Model(&domain.Category{}).Scopes(
+		patternSearchScope(query.Search, "categories.name", "categories.description"),
+		listFilterScope("categories", query.ListFilters),
+	)
 
-	if query.Search != "" {
-		searchPattern := "%" + query.Search + "%"
-		db = db.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
-	}
+	if query.ParentID != nil {
+		db = db.Where("categories.parent_id = ?", *query.ParentID)
+	}
+
+	return paginate(db, query.PaginationParams, listing[domain.Category]{
+		sort: "display_order",
+		keys: []sortKey{
+			{expr: "categories.display_order", cast: "integer"},
+			{expr: "categories.created_at", cast: "timestamptz", desc: true},
+			{expr: "categories.id", cast: "bigint", desc: true},
+		},
+		values: func(c domain.Category) []interface{} {
+			return []interface{}{c.DisplayOrder, c.CreatedAt, c.ID}
+		},
+	})
+}
 
-	if query.Beginning != nil {
-		db = db.Where("created_at >= ?", *query.Beginning)
+func (r *catalogueRepository) GetCategoryByID(id uint) (*domain.Category, error) {
+	var category domain.Category
+	err := r.DB.First(&category, id).Error
+	if err != nil {
+		return nil, err
 	}
+	return &category, nil
+}
 
-	if query.Ending != nil {
-		db = db.Where("created_at <= ?", *query.Ending)
+// UpdateCategory changes a category's details; its place in the tree only changes through MoveCategory
+// UpdateCategory changes a category's details and, when category names a new parent, moves it
+// in the same transaction, so a failed move leaves the details unchanged too
+func (r *catalogueRepository) UpdateCategory(id uint, category dto.Category) (*domain.Category, error) {
+	var categoryDomain domain.Category
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&categoryDomain, id).Error; err != nil {
+			return err
+		}
+
+		if category.ParentID != nil && (categoryDomain.ParentID == nil || *categoryDomain.ParentID != *category.ParentID) {
+			if err := moveCategory(tx, &categoryDomain, category.ParentID); err != nil {
+				return err
+			}
+		}
+
+		categoryDomain.Name = category.Name
+		if category.Description != "" {
+			categoryDomain.Description = category.Description
+		}
+		if category.ImageURL != "" {
+			categoryDomain.ImageURL = category.ImageURL
+		}
+		categoryDomain.DisplayOrder = category.DisplayOrder
+
+		return tx.Model(&categoryDomain).Clauses(clause.Returning{}).Omit("parent_id").Updates(categoryDomain).Error
+	})
+	if err != nil {
+		log.Printf("Error updating category: %v", err)
+		return nil, err
 	}
 
-	if query.ParentID != nil {
-		db = db.Where("parent_id = ?", *query.ParentID)
-	}
+	return &categoryDomain, nil
+}
 
-	if err := db.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+func (r *catalogueRepository) CountProductsByCategoryID(categoryID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&domain.Product{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count, err
+}
 
-	db = db.Order("display_order ASC, created_at DESC")
+// CountSubtreeProducts counts the products in a category and every category below it
+func (r *catalogueRepository) CountSubtreeProducts(categoryID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&domain.Product{}).Scopes(categoryTreeScope("products.category_id", categoryID)).Count(&count).Error
+	return count, err
+}
 
-	offset := query.GetOffset()
-	limit := query.GetLimit()
-	err := db.Offset(offset).Limit(limit).Find(&categories).Error
+// DeleteCategory removes a category. Child categories block the delete unless options say to
+// delete them too (cascade) or to move them up a level (reassign); products in anything that
+// would be deleted always block it.
+func (r *catalogueRepository) DeleteCategory(id uint, options dto.DeleteCategoryOptions) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockCategoryTree(tx); err != nil {
+			return err
+		}
+
+		var category domain.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return err
+		}
+
+		var children int64
+		if err := tx.Model(&domain.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+
+		if children > 0 && options.Children == dto.CATEGORY_CHILDREN_CASCADE {
+			var products int64
+			if err := tx.Model(&domain.Product{}).Scopes(categoryTreeScope("products.category_id", id)).Count(&products).Error; err != nil {
+				return err
+			}
+			if products > 0 {
+				return domain.ErrCategoryHasProducts
+			}
+			return tx.Scopes(categoryTreeScope("id", id)).Delete(&domain.Category{}).Error
+		}
+
+		var products int64
+		if err := tx.Model(&domain.Product{}).Where("category_id = ?", id).Count(&products).Error; err != nil {
+			return err
+		}
+		if products > 0 {
+			return domain.ErrCategoryHasProducts
+		}
+
+		if children > 0 {
+			if options.Children != dto.CATEGORY_CHILDREN_REASSIGN {
+				return domain.ErrCategoryHasChildren
+			}
+
+			target := category.ParentID
+			if options.ReassignTo != nil {
+				target = options.ReassignTo
+				if err := checkCategoryParent(tx, id, *target); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&domain.Category{}).Where("parent_id = ?", id).Update("parent_id", target).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&domain.Category{}, id).Error
+	})
+	if err != nil {
+		log.Printf("Failed to delete category: %v", err)
+	}
+	return err
+}
 
+// GetCategoryTree walks the hierarchy down from rootID, or from every top level category when
+// it is nil, returning categories in depth order with the number of products filed directly
+// under each. Categories whose parent no longer exists count as top level.
+func (r *catalogueRepository) GetCategoryTree(rootID *uint) ([]dto.CategoryNode, error) {
+	start := "categories.parent_id IS NULL OR categories.parent_id NOT IN (SELECT id FROM categories)"
+	var args []interface{}
+	if rootID != nil {
+		start = "categories.id = ?"
+		args = append(args, *rootID)
+	}
+
+	var rows []struct {
+		ID           uint
+		Name         string
+		ParentID     *uint
+		ImageURL     string
+		DisplayOrder int
+		Depth        int
+		ProductCount int64
+	}
+	err := r.DB.Raw(`WITH RECURSIVE tree AS (
+			SELECT categories.id, categories.name, categories.parent_id, categories.image_url,
+				categories.display_order, 0 AS depth, ARRAY[categories.id] AS path
+			FROM categories WHERE `+start+`
+			UNION ALL
+			SELECT categories.id, categories.name, categories.parent_id, categories.image_url,
+				categories.display_order, tree.depth + 1, tree.path || categories.id
+			FROM categories JOIN tree ON categories.parent_id = tree.id
+			WHERE NOT categories.id = ANY(tree.path)
+		)
+		SELECT tree.id, tree.name, tree.parent_id, tree.image_url, tree.display_order, tree.depth,
+			(SELECT COUNT(*) FROM products WHERE products.category_id = tree.id) AS product_count
+		FROM tree
+		ORDER BY tree.depth ASC, tree.display_order ASC, tree.name ASC`, args...).
+		Scan(&rows).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return categories, total, nil
+	nodes := make([]dto.CategoryNode, len(rows))
+	for i, row := range rows {
+		nodes[i] = dto.CategoryNode{
+			ID:           row.ID,
+			Name:         row.Name,
+			ParentID:     row.ParentID,
+			ImageURL:     row.ImageURL,
+			DisplayOrder: row.DisplayOrder,
+			Depth:        row.Depth,
+			ProductCount: row.ProductCount,
+		}
+	}
+	return nodes, nil
 }
 
-func (r *catalogueRepository) GetCategoryByID(id uint) (*domain.Category, error) {
-	var category domain.Category
-	err := r.DB.First(&category, id).Error
+// GetCategoryAncestors returns the path from the top of the tree down to the category itself
+func (r *catalogueRepository) GetCategoryAncestors(id uint) ([]domain.Category, error) {
+	var categories []domain.Category
+	err := r.DB.Raw(`WITH RECURSIVE chain AS (
+			SELECT categories.*, 0 AS depth FROM categories WHERE categories.id = ?
+			UNION ALL
+			SELECT categories.*, chain.depth + 1 FROM categories JOIN chain ON categories.id = chain.parent_id
+			WHERE chain.depth < ?
+		)
+		SELECT * FROM chain ORDER BY depth DESC`, id, maxCategoryDepth).
+		Scan(&categories).Error
 	if err != nil {
 		return nil, err
 	}
-	return &category, nil
+	if len(categories) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return categories, nil
 }
 
-func (r *catalogueRepository) UpdateCategory(id uint, category dto.Category) (*domain.Category, error) {
-	var categoryDomain domain.Category
-	err := r.DB.First(&categoryDomain, id).Error
+// MoveCategory re-parents a category along with its subtree; a nil parent makes it top level
+func (r *catalogueRepository) MoveCategory(id uint, parentID *uint) (*domain.Category, error) {
+	var category domain.Category
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&category, id).Error; err != nil {
+			return err
+		}
+		return moveCategory(tx, &category, parentID)
+	})
 	if err != nil {
+		log.Printf("Failed to move category: %v", err)
 		return nil, err
 	}
+	return &category, nil
+}
 
-	categoryDomain.Name = category.Name
-	if category.Description != "" {
-		categoryDomain.Description = category.Description
+// moveCategory re-parents category inside the caller's transaction
+func moveCategory(tx *gorm.DB, category *domain.Category, parentID *uint) error {
+	if err := lockCategoryTree(tx); err != nil {
+		return err
 	}
-	if category.ParentID != nil {
-		categoryDomain.ParentID = category.ParentID
-	}
-	if category.ImageURL != "" {
-		categoryDomain.ImageURL = category.ImageURL
-	}
-	categoryDomain.DisplayOrder = category.DisplayOrder
 
-	err = r.DB.Model(&categoryDomain).Clauses(clause.Returning{}).Updates(categoryDomain).Error
-	if err != nil {
-		log.Printf("Error updating category: %v", err)
-		return nil, err
+	if parentID != nil {
+		if err := checkCategoryParent(tx, category.ID, *parentID); err != nil {
+			return err
+		}
 	}
 
-	return &categoryDomain, nil
+	category.ParentID = parentID
+	return tx.Model(category).Update("parent_id", parentID).Error
 }
 
-func (r *catalogueRepository) CountProductsByCategoryID(categoryID uint) (int64, error) {
-	var count int64
-	err := r.DB.Model(&domain.Product{}).Where("category_id = ?", categoryID).Count(&count).Error
-	return count, err
+// maxCategoryDepth bounds the walk up to the root should the parent links ever loop
+const maxCategoryDepth = 100
+
+// lockCategoryTree serialises changes to the shape of the tree, so two concurrent moves
+// cannot each pass the cycle check and together create a loop
+func lockCategoryTree(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext('categories.tree'))").Error
 }
 
-func (r *catalogueRepository) DeleteCategory(id uint) error {
-	return r.DB.Delete(&domain.Category{}, id).Error
+// checkCategoryParent makes sure parentID exists and is not the category or one of its descendants
+func checkCategoryParent(tx *gorm.DB, id uint, parentID uint) error {
+	var parent domain.Category
+	if err := tx.First(&parent, parentID).Error; err != nil {
+		return err
+	}
+
+	var inSubtree int64
+	err := tx.Model(&domain.Category{}).Where("id = ?", parentID).Scopes(categoryTreeScope("id", id)).Count(&inSubtree).Error
+	if err != nil {
+		return err
+	}
+	if inSubtree > 0 {
+		return domain.ErrCategoryCycle
+	}
+	return nil
 }
 
 // Product methods
 
+// CreateProduct stores the product with its options and variants in one transaction
 func (r *catalogueRepository) CreateProduct(sellerID uint, product dto.Product) (*domain.Product, error) {
 	productDomain := domain.Product{
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
+		MinPrice:    product.Price,
+		MaxPrice:    product.Price,
 		CategoryID:  product.CategoryID,
-		Stock:       product.Stock,
 		ImageURL:    product.ImageURL,
 		SellerID:    sellerID,
 	}
-
-	err := r.DB.Create(&productDomain).Error
+	if product.Stock != nil {
+		productDomain.Stock = *product.Stock
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Options", "Variants").Create(&productDomain).Error; err != nil {
+			return err
+		}
+
+		for i, option := range product.Options {
+			err := tx.Create(&domain.ProductOption{
+				ProductID: productDomain.ID,
+				Name:      option.Name,
+				Values:    option.Values,
+				Position:  i,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, variant := range product.Variants {
+			err := tx.Create(&domain.ProductVariant{
+				ProductID:  productDomain.ID,
+				SKU:        variant.SKU,
+				Attributes: variant.Attributes,
+				Price:      variant.Price,
+				Stock:      variant.Stock,
+				ImageURL:   variant.ImageURL,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return refreshProductSummary(tx, productDomain.ID)
+	})
 	if err != nil {
 		log.Printf("Failed to create product: %v", err)
 		return nil, err
 	}
 
 	log.Println("Product created successfully")
-	return &productDomain, nil
+	return r.GetProductByID(productDomain.ID)
 }
 
-func (r *catalogueRepository) GetProducts(query dto.ProductQuery) ([]domain.Product, int64, error) {
-	var products []domain.Product
-	var total int64
-
-	db := r.DB.Model(&domain.Product{})
+func (r *catalogueRepository) GetProducts(query dto.ProductQuery) ([]domain.Product, dto.PageInfo, error) {
+	db := r.DB.Model(&domain.Product{}).Scopes(productFilterScope(query))
+	products, page, err := paginate(db, query.PaginationParams, productListing(query))
+	if err != nil {
+		return nil, page, err
+	}
 
-	if query.Search != "" {
-		searchPattern := "%" + query.Search + "%"
-		db = db.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+	if query.Search == "" || len(products) == 0 {
+		return products, page, nil
 	}
 
-	if query.Beginning != nil {
-		db = db.Where("created_at >= ?", *query.Beginning)
+	// Headlines are costly, so they are only built for the rows on the page
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	var headlines []struct {
+		ID                   uint
+		NameHighlight        string
+		DescriptionHighlight string
+	}
+	err = r.DB.Model(&domain.Product{}).
+		Select("id, ts_headline(?, name, websearch_to_tsquery(?, ?), ?) AS name_highlight, ts_headline(?, COALESCE(description, ''), websearch_to_tsquery(?, ?), ?) AS description_highlight",
+			productSearchConfig, productSearchConfig, query.Search, nameHeadlineOptions,
+			productSearchConfig, productSearchConfig, query.Search, descriptionHeadlineOptions).
+		Where("id IN ?", ids).
+		Scan(&headlines).Error
+	if err != nil {
+		log.Printf("Failed to highlight search results: %v", err)
+		return nil, page, err
 	}
 
-	if query.Ending != nil {
-		db = db.Where("created_at <= ?", *query.Ending)
+	byID := make(map[uint]int, len(products))
+	for i, product := range products {
+		byID[product.ID] = i
+	}
+	for _, headline := range headlines {
+		product := &products[byID[headline.ID]]
+		product.NameHighlight = escapeHeadline(headline.NameHighlight)
+		product.DescriptionHighlight = escapeHeadline(headline.DescriptionHighlight)
 	}
 
-	if err := db.Count(&total).Error; err != nil {
-		return nil, 0, err
+	return products, page, nil
+}
+
+// GetProductFacets counts every product matching the query, ignoring pagination, by
+// category, price range and seller
+func (r *catalogueRepository) GetProductFacets(query dto.ProductQuery) (*dto.ProductFacets, error) {
+	facets := dto.ProductFacets{
+		Categories:  []dto.CategoryFacet{},
+		PriceRanges: []dto.PriceFacet{},
+		Sellers:     []dto.SellerFacet{},
+	}
+
+	err := r.DB.Model(&domain.Product{}).Scopes(productFilterScope(query)).
+		Select("categories.id AS id, categories.name AS name, COUNT(*) AS count").
+		Joins("JOIN categories ON categories.id = products.category_id").
+		Group("categories.id, categories.name").
+		Order("count DESC, categories.name ASC").
+		Scan(&facets.Categories).Error
+	if err != nil {
+		return nil, err
 	}
 
-	db = db.Order("created_at DESC")
+	err = r.DB.Model(&domain.Product{}).Scopes(productFilterScope(query)).
+		Select("users.id AS id, TRIM(users.first_name || ' ' || users.last_name) AS name, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = products.seller_id").
+		Group("users.id, users.first_name, users.last_name").
+		Order("count DESC, users.id ASC").
+		Scan(&facets.Sellers).Error
+	if err != nil {
+		return nil, err
+	}
 
-	offset := query.GetOffset()
-	limit := query.GetLimit()
-	err := db.Offset(offset).Limit(limit).Find(&products).Error
+	// Number the buckets in SQL, then map them back to their bounds
+	bucket := "CASE"
+	args := make([]interface{}, 0, len(productPriceBuckets))
+	for i, bound := range productPriceBuckets {
+		bucket += fmt.Sprintf(" WHEN products.price < ? THEN %d", i)
+		args = append(args, bound)
+	}
+	bucket += fmt.Sprintf(" ELSE %d END", len(productPriceBuckets))
 
+	var counts []struct {
+		Bucket int
+		Count  int64
+	}
+	err = r.DB.Model(&domain.Product{}).Scopes(productFilterScope(query)).
+		Select(bucket+" AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").
+		Order("bucket ASC").
+		Scan(&counts).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	for _, c := range counts {
+		facet := dto.PriceFacet{Count: c.Count}
+		if c.Bucket > 0 {
+			facet.Min = productPriceBuckets[c.Bucket-1]
+		}
+		if c.Bucket < len(productPriceBuckets) {
+			upper := productPriceBuckets[c.Bucket]
+			facet.Max = &upper
+		}
+		facets.PriceRanges = append(facets.PriceRanges, facet)
 	}
 
-	return products, total, nil
+	return &facets, nil
+}
+
+const (
+	// productSearchConfig is the text search configuration products.search_vector is built with
+	productSearchConfig        = "english"
+	nameHeadlineOptions        = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	descriptionHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2"
+)
+
+// productPriceBuckets are the upper bounds of the price facet ranges, in the store currency
+var productPriceBuckets = []float64{1000, 5000, 10000, 50000, 100000}
+
+// productFilterScope applies the filters of a product listing; the count, page and facets share it
+func productFilterScope(query dto.ProductQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if query.Search != "" {
+			db = db.Where("products.search_vector @@ websearch_to_tsquery(?, ?)", productSearchConfig, query.Search)
+		}
+
+		db = listFilterScope("products", query.ListFilters)(db)
+
+		if query.CategoryID != nil {
+			db = categoryTreeScope("products.category_id", *query.CategoryID)(db)
+		}
+
+		// A product with variants matches when any part of its price range is inside the bounds
+		if query.MinPrice != nil {
+			db = db.Where("products.max_price >= ?", *query.MinPrice)
+		}
+
+		if query.MaxPrice != nil {
+			db = db.Where("products.min_price <= ?", *query.MaxPrice)
+		}
+
+		if query.InStock {
+			db = db.Where("products.stock > 0")
+		}
+
+		return db
+	}
+}
+
+// productListing is the order a product listing is paged in; relevance needs a search term
+func productListing(query dto.ProductQuery) listing[domain.Product] {
+	byID := sortKey{expr: "products.id", cast: "bigint", desc: true}
+
+	sort := query.Sort
+	if sort == "" || sort == dto.SORT_RELEVANCE {
+		sort = dto.SORT_NEWEST
+		if query.Search != "" {
+			sort = dto.SORT_RELEVANCE
+		}
+	}
+
+	switch sort {
+	case dto.SORT_RELEVANCE:
+		rank := sortKey{
+			expr: "ts_rank_cd(products.search_vector, websearch_to_tsquery(?, ?), 32)",
+			args: []interface{}{productSearchConfig, query.Search},
+			cast: "real",
+			desc: true,
+		}
+		return listing[domain.Product]{
+			sort: sort,
+			keys: []sortKey{rank, byID},
+			values: func(p domain.Product) []interface{} {
+				return []interface{}{p.Rank, p.ID}
+			},
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Select("products.*, "+rank.expr+" AS rank", rank.args...)
+			},
+		}
+	case dto.SORT_PRICE_ASC, dto.SORT_PRICE_DESC:
+		desc := sort == dto.SORT_PRICE_DESC
+		byID.desc = desc
+		return listing[domain.Product]{
+			sort: sort,
+			keys: []sortKey{{expr: "products.min_price", cast: "numeric", desc: desc}, byID},
+			values: func(p domain.Product) []interface{} {
+				return []interface{}{p.MinPrice, p.ID}
+			},
+		}
+	case dto.SORT_NAME:
+		byID.desc = false
+		return listing[domain.Product]{
+			sort: sort,
+			keys: []sortKey{{expr: "products.name", cast: "text"}, byID},
+			values: func(p domain.Product) []interface{} {
+				return []interface{}{p.Name, p.ID}
+			},
+		}
+	case dto.SORT_POPULARITY:
+		unitsSold := "(" + productUnitsSold + ")"
+		return listing[domain.Product]{
+			sort: sort,
+			keys: []sortKey{{expr: unitsSold, cast: "bigint", desc: true}, byID},
+			values: func(p domain.Product) []interface{} {
+				return []interface{}{p.UnitsSold, p.ID}
+			},
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Select("products.*, " + unitsSold + " AS units_sold")
+			},
+		}
+	}
+
+	return listing[domain.Product]{
+		sort: dto.SORT_NEWEST,
+		keys: []sortKey{{expr: "products.created_at", cast: "timestamptz", desc: true}, byID},
+		values: func(p domain.Product) []interface{} {
+			return []interface{}{p.CreatedAt, p.ID}
+		},
+	}
+}
+
+// productUnitsSold counts the units of a product in orders that went on to be paid
+var productUnitsSold = `SELECT COALESCE(SUM(order_items.quantity), 0) FROM order_items
+	JOIN seller_orders ON seller_orders.id = order_items.seller_order_id
+	WHERE order_items.product_id = products.id
+	AND seller_orders.status NOT IN ('` + domain.ORDER_PENDING + `', '` + domain.ORDER_CANCELLED + `')`
+
+// escapeHeadline HTML-escapes seller text in a search headline while keeping the <mark> tags
+func escapeHeadline(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, "&lt;mark&gt;", "<mark>")
+	return strings.ReplaceAll(escaped, "&lt;/mark&gt;", "</mark>")
+}
+
+// MigrateProductSearch adds the generated full-text column behind product search and its GIN index.
+// Names weigh more than descriptions when ranking.
+func MigrateProductSearch(db *gorm.DB) error {
+	err := db.Exec(`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('` + productSearchConfig + `', COALESCE(name, '')), 'A') ||
+			setweight(to_tsvector('` + productSearchConfig + `', COALESCE(description, '')), 'B')
+		) STORED`).Error
+	if err != nil {
+		return err
+	}
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)").Error
 }
 
 func (r *catalogueRepository) GetProductByID(id uint) (*domain.Product, error) {
 	var product domain.Product
-	err := r.DB.First(&product, id).Error
+	err := r.DB.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	}).First(&product, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
+// GetProductsByIDs loads products with their options and variants; missing IDs are skipped
+func (r *catalogueRepository) GetProductsByIDs(ids []uint) ([]domain.Product, error) {
+	var products []domain.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := r.DB.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Preload("Variants").Where("id IN ?", ids).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
 func (r *catalogueRepository) UpdateProduct(id uint, product dto.Product) (*domain.Product, error) {
 	var productDomain domain.Product
 	err := r.DB.First(&productDomain, id).Error
@@ -228,14 +711,17 @@ func (r *catalogueRepository) UpdateProduct(id uint, product dto.Product) (*doma
 	if product.Description != "" {
 		updateMap["description"] = product.Description
 	}
-	if product.Price > 0 {
+	// price and stock of a product with variants follow its SKUs
+	if product.Price > 0 && !productDomain.HasVariants {
 		updateMap["price"] = product.Price
+		updateMap["min_price"] = product.Price
+		updateMap["max_price"] = product.Price
 	}
 	if product.CategoryID > 0 {
 		updateMap["category_id"] = product.CategoryID
 	}
-	if product.Stock >= 0 {
-		updateMap["stock"] = product.Stock
+	if product.Stock != nil && !productDomain.HasVariants {
+		updateMap["stock"] = *product.Stock
 	}
 	if product.ImageURL != "" {
 		updateMap["image_url"] = product.ImageURL
@@ -251,14 +737,241 @@ func (r *catalogueRepository) UpdateProduct(id uint, product dto.Product) (*doma
 		return nil, err
 	}
 
-	err = r.DB.First(&productDomain, id).Error
+	return r.GetProductByID(id)
+}
+
+func (r *catalogueRepository) DeleteProduct(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", id).Delete(&domain.ProductVariant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", id).Delete(&domain.ProductOption{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", id).Delete(&domain.ProductImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", id).Delete(&domain.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Product{}, id).Error
+	})
+}
+
+// Variant methods
+
+func (r *catalogueRepository) CreateVariant(variant *domain.ProductVariant) (*domain.ProductVariant, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+		return refreshProductSummary(tx, variant.ProductID)
+	})
 	if err != nil {
+		log.Printf("Failed to create product variant: %v", err)
 		return nil, err
 	}
+	return variant, nil
+}
 
-	return &productDomain, nil
+func (r *catalogueRepository) GetVariantByID(productID uint, variantID uint) (*domain.ProductVariant, error) {
+	var variant domain.ProductVariant
+	err := r.DB.Where("id = ? AND product_id = ?", variantID, productID).First(&variant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
 }
 
-func (r *catalogueRepository) DeleteProduct(id uint) error {
-	return r.DB.Delete(&domain.Product{}, id).Error
+func (r *catalogueRepository) GetVariantBySKU(productID uint, sku string) (*domain.ProductVariant, error) {
+	var variant domain.ProductVariant
+	err := r.DB.Where("sku = ? AND product_id = ?", sku, productID).First(&variant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *catalogueRepository) UpdateVariant(variant *domain.ProductVariant, updates map[string]interface{}) (*domain.ProductVariant, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.ProductVariant{}).Where("id = ?", variant.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return refreshProductSummary(tx, variant.ProductID)
+	})
+	if err != nil {
+		log.Printf("Failed to update product variant: %v", err)
+		return nil, err
+	}
+	return r.GetVariantByID(variant.ProductID, variant.ID)
+}
+
+func (r *catalogueRepository) DeleteVariant(variant *domain.ProductVariant) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&domain.ProductVariant{}, variant.ID).Error; err != nil {
+			return err
+		}
+		return refreshProductSummary(tx, variant.ProductID)
+	})
+}
+
+// Image methods
+
+// AddProductImages appends images to the end of a product's gallery, refusing to grow it past limit
+func (r *catalogueRepository) AddProductImages(productID uint, images []domain.ProductImage, limit int) ([]domain.ProductImage, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the product so concurrent uploads agree on the count and positions
+		var product domain.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+			return err
+		}
+
+		var gallery struct {
+			Count        int64
+			LastPosition int
+		}
+		err := tx.Model(&domain.ProductImage{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), -1) AS last_position").
+			Where("product_id = ?", productID).
+			Scan(&gallery).Error
+		if err != nil {
+			return err
+		}
+		if int(gallery.Count)+len(images) > limit {
+			return fmt.Errorf("%w: a product can have at most %d images", domain.ErrTooManyImages, limit)
+		}
+
+		for i := range images {
+			images[i].ProductID = productID
+			images[i].Position = gallery.LastPosition + 1 + i
+		}
+		if err := tx.Create(&images).Error; err != nil {
+			return err
+		}
+		return refreshProductCover(tx, productID)
+	})
+	if err != nil {
+		log.Printf("Failed to add product images: %v", err)
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *catalogueRepository) GetProductImages(productID uint) ([]domain.ProductImage, error) {
+	var images []domain.ProductImage
+	err := r.DB.Where("product_id = ?", productID).Order("position ASC, id ASC").Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *catalogueRepository) GetProductImageByID(productID uint, imageID uint) (*domain.ProductImage, error) {
+	var image domain.ProductImage
+	err := r.DB.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// ReorderProductImages renumbers the gallery in the given order, which must name every image once
+func (r *catalogueRepository) ReorderProductImages(productID uint, imageIDs []uint) ([]domain.ProductImage, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var product domain.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+			return err
+		}
+
+		var existing []uint
+		if err := tx.Model(&domain.ProductImage{}).Where("product_id = ?", productID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(imageIDs) {
+			return domain.ErrInvalidImageOrder
+		}
+		remaining := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			remaining[id] = true
+		}
+		for _, id := range imageIDs {
+			if !remaining[id] {
+				return domain.ErrInvalidImageOrder
+			}
+			delete(remaining, id)
+		}
+
+		for position, id := range imageIDs {
+			if err := tx.Model(&domain.ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return refreshProductCover(tx, productID)
+	})
+	if err != nil {
+		log.Printf("Failed to reorder product images: %v", err)
+		return nil, err
+	}
+	return r.GetProductImages(productID)
+}
+
+func (r *catalogueRepository) DeleteProductImage(image *domain.ProductImage) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&domain.ProductImage{}, image.ID).Error; err != nil {
+			return err
+		}
+		// the file of the deleted image goes away, so it cannot stay the cover
+		err := tx.Model(&domain.Product{}).
+			Where("id = ? AND image_url = ?", image.ProductID, image.URL).
+			Update("image_url", "").Error
+		if err != nil {
+			return err
+		}
+		return refreshProductCover(tx, image.ProductID)
+	})
+}
+
+// refreshProductCover points the product's ImageURL at the first image of its gallery. A
+// product without gallery images keeps the image it had, e.g. one given when it was created.
+func refreshProductCover(tx *gorm.DB, productID uint) error {
+	var covers []domain.ProductImage
+	err := tx.Where("product_id = ?", productID).Order("position ASC, id ASC").Limit(1).Find(&covers).Error
+	if err != nil || len(covers) == 0 {
+		return err
+	}
+	return tx.Model(&domain.Product{}).Where("id = ?", productID).Update("image_url", covers[0].URL).Error
+}
+
+// refreshProductSummary recomputes the price range and total stock a product shows from its
+// variants. It must run in every transaction that changes a variant's price or stock.
+func refreshProductSummary(tx *gorm.DB, productID uint) error {
+	var summary struct {
+		Count    int64
+		MinPrice float64
+		MaxPrice float64
+		Stock    int
+	}
+	err := tx.Model(&domain.ProductVariant{}).
+		Select("COUNT(*) AS count, COALESCE(MIN(price), 0) AS min_price, COALESCE(MAX(price), 0) AS max_price, COALESCE(SUM(stock), 0) AS stock").
+		Where("product_id = ?", productID).
+		Scan(&summary).Error
+	if err != nil {
+		return err
+	}
+
+	if summary.Count == 0 {
+		return tx.Model(&domain.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+			"has_variants": false,
+			"min_price":    gorm.Expr("price"),
+			"max_price":    gorm.Expr("price"),
+		}).Error
+	}
+
+	return tx.Model(&domain.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		"has_variants": true,
+		"price":        summary.MinPrice,
+		"min_price":    summary.MinPrice,
+		"max_price":    summary.MaxPrice,
+		"stock":        summary.Stock,
+	}).Error
 }
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository interface {
+	CreateCoupon(coupon *domain.Coupon) (*domain.Coupon, error)
+	FindCoupons(query dto.CouponQuery) ([]domain.Coupon, dto.PageInfo, error)
+	FindCouponByID(id uint) (*domain.Coupon, error)
+	FindCouponByCode(code string) (*domain.Coupon, error)
+	UpdateCoupon(coupon *domain.Coupon, updates map[string]interface{}) (*domain.Coupon, error)
+	DeleteCoupon(id uint) error
+	CountUserRedemptions(couponID uint, userID uint) (int64, error)
+
+	// Cart methods
+	SetCartCoupon(userID uint, code string) error
+	FindCartCoupon(userID uint) (*domain.CartCoupon, error)
+	DeleteCartCoupon(userID uint) error
+}
+
+type couponRepository struct {
+	DB *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{DB: db}
+}
+
+func (r *couponRepository) CreateCoupon(coupon *domain.Coupon) (*domain.Coupon, error) {
+	err := r.DB.Create(coupon).Error
+	if err != nil {
+		log.Printf("Failed to create coupon: %v", err)
+		return nil, err
+	}
+	return coupon, nil
+}
+
+func (r *couponRepository) FindCoupons(query dto.CouponQuery) ([]domain.Coupon, dto.PageInfo, error) {
+	db := r.DB.Model(&domain.Coupon{})
+	if query.Active != nil {
+		db = db.Where("active = ?", *query.Active)
+	}
+	if query.SellerID != 0 {
+		db = db.Where("seller_id = ?", query.SellerID)
+	}
+
+	return paginate(db, query.PaginationParams, listing[domain.Coupon]{
+		sort: "newest",
+		keys: []sortKey{
+			{expr: "created_at", cast: "timestamptz", desc: true},
+			{expr: "id", cast: "bigint", desc: true},
+		},
+		values: func(c domain.Coupon) []interface{} {
+			return []interface{}{c.CreatedAt, c.ID}
+		},
+	})
+}
+
+func (r *couponRepository) FindCouponByID(id uint) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	err := r.DB.First(&coupon, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) FindCouponByCode(code string) (*domain.Coupon, error) {
+	var coupon domain.Coupon
+	err := r.DB.Where("code = ?", code).First(&coupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) UpdateCoupon(coupon *domain.Coupon, updates map[string]interface{}) (*domain.Coupon, error) {
+	var updated domain.Coupon
+	err := r.DB.Model(&updated).Clauses(clause.Returning{}).Where("id = ?", coupon.ID).Updates(updates).Error
+	if err != nil {
+		log.Printf("Failed to update coupon: %v", err)
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteCoupon removes a coupon nobody has used; redeemed coupons stay for the orders that
+// reference them and can only be deactivated
+func (r *couponRepository) DeleteCoupon(id uint) error {
+	result := r.DB.Where("id = ? AND redemptions = 0", id).Delete(&domain.Coupon{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.FindCouponByID(id); err != nil {
+			return err
+		}
+		return domain.ErrCouponRedeemed
+	}
+	return nil
+}
+
+func (r *couponRepository) CountUserRedemptions(couponID uint, userID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&domain.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&count).Error
+	return count, err
+}
+
+// redeemCoupon counts a use of the coupon inside the checkout transaction. The coupon row is
+// locked first, so concurrent checkouts queue up behind each other and the caps are checked
+// against an up to date count.
+func redeemCoupon(tx *gorm.DB, redemption *domain.CouponRedemption) error {
+	var coupon domain.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, redemption.CouponID).Error
+	if err != nil {
+		return err
+	}
+
+	if err := coupon.CheckAvailable(time.Now()); err != nil {
+		return err
+	}
+
+	if coupon.MaxPerUser > 0 {
+		var used int64
+		err := tx.Model(&domain.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", coupon.ID, redemption.UserID).
+			Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used >= int64(coupon.MaxPerUser) {
+			return fmt.Errorf("%w: you have already used %s", domain.ErrCouponExhausted, coupon.Code)
+		}
+	}
+
+	err = tx.Model(&domain.Coupon{}).
+		Where("id = ?", coupon.ID).
+		Update("redemptions", gorm.Expr("redemptions + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Create(redemption).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("user_id = ?", redemption.UserID).Delete(&domain.CartCoupon{}).Error
+}
+
+// releaseCoupon gives back the coupon use of an order called off before it was paid, so it
+// counts against neither the coupon's cap nor the buyer's
+func releaseCoupon(tx *gorm.DB, orderID uint) error {
+	var redemption domain.CouponRedemption
+	result := tx.Clauses(clause.Returning{}).Where("order_id = ?", orderID).Delete(&redemption)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return tx.Model(&domain.Coupon{}).
+		Where("id = ? AND redemptions > 0", redemption.CouponID).
+		Update("redemptions", gorm.Expr("redemptions - 1")).Error
+}
+
+// Cart methods
+
+func (r *couponRepository) SetCartCoupon(userID uint, code string) error {
+	cartCoupon := domain.CartCoupon{UserID: userID, Code: code, CreatedAt: time.Now()}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code", "created_at"}),
+	}).Create(&cartCoupon).Error
+}
+
+// FindCartCoupon returns the code applied to the user's cart, or nil when there is none
+func (r *couponRepository) FindCartCoupon(userID uint) (*domain.CartCoupon, error) {
+	var cartCoupon domain.CartCoupon
+	err := r.DB.Where("user_id = ?", userID).First(&cartCoupon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cartCoupon, nil
+}
+
+func (r *couponRepository) DeleteCartCoupon(userID uint) error {
+	return r.DB.Where("user_id = ?", userID).Delete(&domain.CartCoupon{}).Error
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GuestCartRepository interface {
+	CreateGuestCart(key string) (*domain.GuestCart, error)
+	FindGuestCartByKey(key string) (*domain.GuestCart, error)
+	FindGuestCartItems(cartID uint) ([]domain.Cart, error)
+	FindGuestCartItem(cartID uint, productID uint, variantID *uint) (*domain.Cart, error)
+	SaveGuestCartItem(item *domain.Cart) (*domain.Cart, error)
+	DeleteGuestCartItem(cartID uint, productID uint, variantID *uint) error
+	MergeGuestCart(cartID uint, userID uint, lines []domain.Cart) error
+	DeleteExpiredGuestCarts(before time.Time) (int64, error)
+}
+
+type guestCartRepository struct {
+	DB *gorm.DB
+}
+
+func NewGuestCartRepository(db *gorm.DB) GuestCartRepository {
+	return &guestCartRepository{DB: db}
+}
+
+func (r *guestCartRepository) CreateGuestCart(key string) (*domain.GuestCart, error) {
+	cart := &domain.GuestCart{Key: key}
+	err := r.DB.Create(cart).Error
+	if err != nil {
+		log.Printf("Failed to create guest cart: %v", err)
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (r *guestCartRepository) FindGuestCartByKey(key string) (*domain.GuestCart, error) {
+	var cart domain.GuestCart
+	err := r.DB.Where("key = ?", key).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (r *guestCartRepository) FindGuestCartItems(cartID uint) ([]domain.Cart, error) {
+	var items []domain.Cart
+	err := r.DB.Where("guest_cart_id = ?", cartID).Order("id").Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *guestCartRepository) FindGuestCartItem(cartID uint, productID uint, variantID *uint) (*domain.Cart, error) {
+	var item domain.Cart
+	err := r.DB.Where("guest_cart_id = ? AND product_id = ?", cartID, productID).Scopes(cartVariantScope(variantID)).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// SaveGuestCartItem creates or updates a line of a guest cart and counts it as activity on the
+// cart, which pushes back its expiry
+func (r *guestCartRepository) SaveGuestCartItem(item *domain.Cart) (*domain.Cart, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(item).Error; err != nil {
+			return err
+		}
+		return touchGuestCart(tx, *item.GuestCartID)
+	})
+	if err != nil {
+		log.Printf("Failed to save guest cart item: %v", err)
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *guestCartRepository) DeleteGuestCartItem(cartID uint, productID uint, variantID *uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("guest_cart_id = ? AND product_id = ?", cartID, productID).Scopes(cartVariantScope(variantID)).Delete(&domain.Cart{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("cart item not found")
+		}
+		return touchGuestCart(tx, cartID)
+	})
+}
+
+// MergeGuestCart saves the merged lines to the user's cart and deletes the guest cart in one
+// transaction. Lines with an ID update the user's existing line; the rest are added. The guest
+// cart is locked first, so the same cart cannot be merged twice by concurrent logins.
+func (r *guestCartRepository) MergeGuestCart(cartID uint, userID uint, lines []domain.Cart) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var cart domain.GuestCart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cart, cartID).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range lines {
+			line := &lines[i]
+			line.UserID = userID
+			line.GuestCartID = nil
+
+			if line.ID == 0 {
+				if err := tx.Create(line).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			err := tx.Model(&domain.Cart{}).
+				Where("id = ? AND user_id = ?", line.ID, userID).
+				Updates(map[string]interface{}{"quantity": line.Quantity, "price": line.Price}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("guest_cart_id = ?", cartID).Delete(&domain.Cart{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&cart).Error
+	})
+	if err != nil {
+		log.Printf("Failed to merge guest cart %d: %v", cartID, err)
+	}
+	return err
+}
+
+// DeleteExpiredGuestCarts drops guest carts, and their lines, last changed before the given time
+func (r *guestCartRepository) DeleteExpiredGuestCarts(before time.Time) (int64, error) {
+	var expired []domain.GuestCart
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+			Where("updated_at < ?", before).
+			Delete(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(expired))
+		for i, cart := range expired {
+			ids[i] = cart.ID
+		}
+		return tx.Where("guest_cart_id IN ?", ids).Delete(&domain.Cart{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(expired)), nil
+}
+
+func touchGuestCart(tx *gorm.DB, cartID uint) error {
+	return tx.Model(&domain.GuestCart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+}
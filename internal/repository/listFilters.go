@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/dto"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// listFilterScope applies the filters the catalogue listings share to the rows of table
+func listFilterScope(table string, filters dto.ListFilters) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filters.Beginning != nil {
+			db = db.Where(table+".created_at >= ?", *filters.Beginning)
+		}
+
+		if filters.Ending != nil {
+			db = db.Where(table+".created_at <= ?", *filters.Ending)
+		}
+
+		if filters.SellerID != nil {
+			db = db.Where(table+".seller_id = ?", *filters.SellerID)
+		}
+
+		return db
+	}
+}
+
+// patternSearchScope matches term anywhere in any of the columns, ignoring case
+func patternSearchScope(term string, columns ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if term == "" || len(columns) == 0 {
+			return db
+		}
+
+		conditions := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			conditions[i] = column + " ILIKE ?"
+			args[i] = "%" + term + "%"
+		}
+		return db.Where(strings.Join(conditions, " OR "), args...)
+	}
+}
+
+// categoryTreeScope keeps rows whose column points at the category or any category below it
+func categoryTreeScope(column string, categoryID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		// UNION rather than UNION ALL stops the walk should the parent links ever loop
+		return db.Where(column+` IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM categories WHERE id = ?
+				UNION
+				SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+			)
+			SELECT id FROM tree
+		)`, categoryID)
+	}
+}
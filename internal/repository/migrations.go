@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Migration is a one-off change to existing rows or columns that AutoMigrate cannot make on its
+// own. Each one runs once per database and is recorded in schema_migrations.
+type Migration struct {
+	ID  string
+	Run func(tx *gorm.DB) error
+}
+
+type schemaMigration struct {
+	ID        string    `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// BeforeAutoMigrate prepares existing tables so AutoMigrate can bring them up to the models
+var BeforeAutoMigrate = []Migration{
+	{ID: "0001_nullable_seller_order_id", Run: addNullableSellerOrderID},
+	{ID: "0005_money_minor_units", Run: convertAmountsToMinorUnits},
+}
+
+// AfterAutoMigrate fills in data for columns and tables AutoMigrate has just created
+var AfterAutoMigrate = []Migration{
+	{ID: "0002_backfill_seller_orders", Run: backfillSellerOrders},
+	{ID: "0003_scrub_delivered_codes", Run: scrubDeliveredCodes},
+	{ID: "0004_backfill_product_price_range", Run: backfillProductPriceRange},
+	{ID: "0006_platform_funded_store_coupons", Run: fundStoreCouponsByPlatform},
+}
+
+// RunMigrations applies the migrations that have not run on this database yet, in order, each
+// in its own transaction. The migration is recorded before it runs, so a second instance
+// starting at the same time waits on the row and then skips it.
+func RunMigrations(db *gorm.DB, migrations []Migration) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaMigration{ID: migration.ID})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+
+			log.Printf("Running migration %s", migration.ID)
+			return migration.Run(tx)
+		})
+		if err != nil {
+			log.Printf("Migration %s failed: %v", migration.ID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// addNullableSellerOrderID adds the sub-order column to items and status history recorded
+// before orders were split per seller. It starts out nullable so AutoMigrate does not fail on
+// the existing rows; backfillSellerOrders fills it in and adds the constraint.
+func addNullableSellerOrderID(tx *gorm.DB) error {
+	for _, table := range []string{"order_items", "order_status_histories"} {
+		if err := tx.Exec("ALTER TABLE IF EXISTS " + table + " ADD COLUMN IF NOT EXISTS seller_order_id bigint").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minorUnitColumns are the order and cart amounts stored as Money
+var minorUnitColumns = []struct{ table, column string }{
+	{"orders", "total_amount"},
+	{"orders", "discount"},
+	{"seller_orders", "subtotal"},
+	{"seller_orders", "discount"},
+	{"seller_orders", "tax"},
+	{"seller_orders", "shipping"},
+	{"order_items", "price"},
+	{"order_items", "discount"},
+	{"carts", "price"},
+}
+
+// convertAmountsToMinorUnits turns amounts stored as decimals into whole minor units. It has to
+// run before AutoMigrate, which would change the column type without scaling the values.
+func convertAmountsToMinorUnits(tx *gorm.DB) error {
+	for _, c := range minorUnitColumns {
+		var decimal bool
+		err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?
+			AND data_type IN ('double precision', 'real', 'numeric'))`, c.table, c.column).Scan(&decimal).Error
+		if err != nil {
+			return err
+		}
+		if !decimal {
+			continue
+		}
+		statement := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE bigint USING round(%s * 100)", c.table, c.column, c.column)
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSellerOrders gives every order placed before the split one sub-order per seller,
+// carrying the order's status, and points its items and status history at them. Every
+// sub-order gets its own copy of the order's history.
+func backfillSellerOrders(tx *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO seller_orders (order_id, seller_id, status, subtotal, created_at, updated_at)
+			SELECT i.order_id, i.seller_id, o.status, SUM(i.price * i.quantity), o.created_at, o.updated_at
+			FROM order_items i
+			JOIN orders o ON o.id = i.order_id
+			WHERE i.seller_order_id IS NULL
+			GROUP BY i.order_id, i.seller_id, o.status, o.created_at, o.updated_at`,
+		`UPDATE order_items i SET seller_order_id = s.id
+			FROM seller_orders s
+			WHERE i.seller_order_id IS NULL AND s.order_id = i.order_id AND s.seller_id = i.seller_id`,
+		`INSERT INTO order_status_histories (order_id, seller_order_id, from_status, to_status, changed_by, note, created_at)
+			SELECT h.order_id, s.id, h.from_status, h.to_status, h.changed_by, h.note, h.created_at
+			FROM order_status_histories h
+			JOIN seller_orders s ON s.order_id = h.order_id
+			WHERE h.seller_order_id IS NULL`,
+		`DELETE FROM order_status_histories WHERE seller_order_id IS NULL`,
+		`ALTER TABLE order_items ALTER COLUMN seller_order_id SET NOT NULL`,
+		`ALTER TABLE order_status_histories ALTER COLUMN seller_order_id SET NOT NULL`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrubDeliveredCodes clears the one-time codes of messages sent or dead before bodies were
+// cleared on delivery
+func scrubDeliveredCodes(tx *gorm.DB) error {
+	return tx.Exec("UPDATE outbox_messages SET body = '' WHERE sensitive AND status IN (?, ?)", domain.OUTBOX_SENT, domain.OUTBOX_DEAD).Error
+}
+
+// backfillProductPriceRange gives products created before variants existed a price range
+func backfillProductPriceRange(tx *gorm.DB) error {
+	return tx.Exec("UPDATE products SET min_price = price, max_price = price WHERE NOT has_variants AND COALESCE(max_price, 0) = 0").Error
+}
+
+// fundStoreCouponsByPlatform charges coupons created for the whole store before coupons said who
+// funds them to the platform; coupons limited to a seller stay with that seller
+func fundStoreCouponsByPlatform(tx *gorm.DB) error {
+	return tx.Exec("UPDATE coupons SET funded_by = ? WHERE seller_id IS NULL", domain.COUPON_FUNDED_BY_PLATFORM).Error
+}
@@ -0,0 +1,509 @@
+package repository
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderRepository interface {
+	CreateOrder(order *domain.Order, cartItems []domain.Cart, redemption *domain.CouponRedemption) (*domain.Order, error)
+	FindOrderByID(id uint) (*domain.Order, error)
+	FindOrderByUserIDAndID(userID uint, id uint) (*domain.Order, error)
+	FindOrdersByUserID(userID uint, query dto.OrderQuery) ([]domain.Order, dto.PageInfo, error)
+	FindAllOrders(query dto.OrderQuery) ([]domain.Order, dto.PageInfo, error)
+	UpdateOrderStatus(order *domain.Order, toStatus string, changedBy uint, note string) (*domain.Order, error)
+
+	// Seller fulfilment methods
+	FindSellerOrderByID(id uint) (*domain.SellerOrder, error)
+	FindSellerOrdersBySellerID(sellerID uint, query dto.OrderQuery) ([]domain.SellerOrder, dto.PageInfo, error)
+	UpdateSellerOrderStatus(sellerOrder *domain.SellerOrder, toStatus string, changedBy uint, note string, entries []domain.LedgerEntry, messages []domain.OutboxMessage) (*domain.SellerOrder, error)
+}
+
+type orderRepository struct {
+	DB *gorm.DB
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &orderRepository{DB: db}
+}
+
+// CreateOrder reserves stock for every cart line, persists the order with its seller
+// sub-orders and items, redeems the coupon if one was used and removes the checked out cart
+// lines in a single transaction. The cart is locked and checked against the lines the order
+// was built from first, then product and variant rows are locked with SELECT ... FOR UPDATE
+// so concurrent checkouts cannot oversell; the coupon is locked last for the same reason.
+func (r *orderRepository) CreateOrder(order *domain.Order, cartItems []domain.Cart, redemption *domain.CouponRedemption) (*domain.Order, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockCart(tx, order.UserID, cartItems); err != nil {
+			return err
+		}
+
+		// lock products in a stable order to avoid deadlocks between concurrent checkouts
+		productIDs := make([]uint, 0, len(cartItems))
+		variantIDs := []uint{}
+		cartIDs := make([]uint, 0, len(cartItems))
+		for _, item := range cartItems {
+			productIDs = append(productIDs, item.ProductID)
+			if item.VariantID != nil {
+				variantIDs = append(variantIDs, *item.VariantID)
+			}
+			cartIDs = append(cartIDs, item.ID)
+		}
+		sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+		var products []domain.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", productIDs).
+			Order("id").
+			Find(&products).Error
+		if err != nil {
+			return err
+		}
+
+		productsByID := make(map[uint]domain.Product, len(products))
+		for _, product := range products {
+			productsByID[product.ID] = product
+		}
+
+		// variants are always locked after their products, so the lock order stays consistent
+		var variants []domain.ProductVariant
+		if len(variantIDs) > 0 {
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id IN ?", variantIDs).
+				Order("id").
+				Find(&variants).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		variantsByID := make(map[uint]domain.ProductVariant, len(variants))
+		for _, variant := range variants {
+			variantsByID[variant.ID] = variant
+		}
+
+		for _, item := range cartItems {
+			product, ok := productsByID[item.ProductID]
+			if !ok {
+				return fmt.Errorf("%w: %s", domain.ErrProductUnavailable, item.Name)
+			}
+
+			if item.VariantID != nil {
+				variant, ok := variantsByID[*item.VariantID]
+				if !ok || variant.ProductID != product.ID {
+					return fmt.Errorf("%w: %s", domain.ErrProductUnavailable, item.Name)
+				}
+				if err := reserveVariantStock(tx, item, variant); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if product.HasVariants {
+				return fmt.Errorf("%w: %s", domain.ErrVariantRequired, product.Name)
+			}
+			if domain.MoneyFromAmount(product.Price) != item.Price {
+				return fmt.Errorf("%w: %s", domain.ErrPriceChanged, product.Name)
+			}
+			if product.Stock < item.Quantity {
+				return fmt.Errorf("%w: %s has %d left", domain.ErrInsufficientStock, product.Name, product.Stock)
+			}
+
+			result := tx.Model(&domain.Product{}).
+				Where("id = ? AND stock >= ?", product.ID, item.Quantity).
+				Update("stock", gorm.Expr("stock - ?", item.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: %s", domain.ErrInsufficientStock, product.Name)
+			}
+		}
+
+		sellerOrders := order.SellerOrders
+		order.SellerOrders = nil
+
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+
+		if redemption != nil {
+			redemption.OrderID = order.ID
+			if err := redeemCoupon(tx, redemption); err != nil {
+				return err
+			}
+		}
+
+		for i := range sellerOrders {
+			items := sellerOrders[i].Items
+			sellerOrders[i].Items = nil
+			sellerOrders[i].OrderID = order.ID
+
+			if err := tx.Create(&sellerOrders[i]).Error; err != nil {
+				return err
+			}
+
+			for j := range items {
+				items[j].OrderID = order.ID
+				items[j].SellerOrderID = sellerOrders[i].ID
+			}
+			if err := tx.Create(&items).Error; err != nil {
+				return err
+			}
+			sellerOrders[i].Items = items
+
+			history := domain.OrderStatusHistory{
+				OrderID:       order.ID,
+				SellerOrderID: sellerOrders[i].ID,
+				ToStatus:      sellerOrders[i].Status,
+				ChangedBy:     order.UserID,
+				Note:          "order placed",
+			}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+		}
+		order.SellerOrders = sellerOrders
+
+		return tx.Where("user_id = ? AND id IN ?", order.UserID, cartIDs).Delete(&domain.Cart{}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to create order: %v", err)
+		return nil, err
+	}
+
+	log.Println("Order created successfully")
+	return order, nil
+}
+
+func (r *orderRepository) FindOrderByID(id uint) (*domain.Order, error) {
+	var order domain.Order
+	err := r.DB.Scopes(preloadSellerOrders).First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) FindOrderByUserIDAndID(userID uint, id uint) (*domain.Order, error) {
+	var order domain.Order
+	err := r.DB.Scopes(preloadSellerOrders).Where("user_id = ?", userID).First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) FindOrdersByUserID(userID uint, query dto.OrderQuery) ([]domain.Order, dto.PageInfo, error) {
+	return r.findOrders(r.DB.Where("user_id = ?", userID), query)
+}
+
+func (r *orderRepository) FindAllOrders(query dto.OrderQuery) ([]domain.Order, dto.PageInfo, error) {
+	return r.findOrders(r.DB, query)
+}
+
+func (r *orderRepository) findOrders(db *gorm.DB, query dto.OrderQuery) ([]domain.Order, dto.PageInfo, error) {
+	db = db.Model(&domain.Order{})
+
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	return paginate(db, query.PaginationParams, listing[domain.Order]{
+		sort: "newest",
+		keys: []sortKey{
+			{expr: "orders.created_at", cast: "timestamptz", desc: true},
+			{expr: "orders.id", cast: "bigint", desc: true},
+		},
+		values: func(o domain.Order) []interface{} {
+			return []interface{}{o.CreatedAt, o.ID}
+		},
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Preload("SellerOrders.Items")
+		},
+	})
+}
+
+// UpdateOrderStatus moves the parent order to toStatus together with every sub-order
+// still in the parent's current status, recording each change and releasing reserved
+// stock when the order is called off, and its open payments and coupon use when it was still
+// unpaid, all in one transaction.
+// The update only applies if the order is still in the status it was read with.
+func (r *orderRepository) UpdateOrderStatus(order *domain.Order, toStatus string, changedBy uint, note string) (*domain.Order, error) {
+	fromStatus := order.Status
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Order{}).
+			Where("id = ? AND status = ?", order.ID, fromStatus).
+			Update("status", toStatus)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: order is no longer %s", domain.ErrInvalidTransition, fromStatus)
+		}
+
+		var sellerOrders []domain.SellerOrder
+		err := tx.Where("order_id = ? AND status = ?", order.ID, fromStatus).Find(&sellerOrders).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range sellerOrders {
+			if err := transitionSellerOrder(tx, &sellerOrders[i], toStatus, changedBy, note); err != nil {
+				return err
+			}
+		}
+
+		if fromStatus == domain.ORDER_PENDING && toStatus == domain.ORDER_CANCELLED {
+			if err := cancelOpenPayments(tx, order.ID); err != nil {
+				return err
+			}
+			return releaseCoupon(tx, order.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to update order status: %v", err)
+		return nil, err
+	}
+
+	return r.FindOrderByID(order.ID)
+}
+
+// Seller fulfilment methods
+
+func (r *orderRepository) FindSellerOrderByID(id uint) (*domain.SellerOrder, error) {
+	var sellerOrder domain.SellerOrder
+	err := r.DB.Preload("Items").
+		Preload("StatusHistory", orderStatusHistoryScope).
+		First(&sellerOrder, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sellerOrder, nil
+}
+
+func (r *orderRepository) FindSellerOrdersBySellerID(sellerID uint, query dto.OrderQuery) ([]domain.SellerOrder, dto.PageInfo, error) {
+	db := r.DB.Model(&domain.SellerOrder{}).Where("seller_id = ?", sellerID)
+
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	return paginate(db, query.PaginationParams, listing[domain.SellerOrder]{
+		sort: "newest",
+		keys: []sortKey{
+			{expr: "seller_orders.created_at", cast: "timestamptz", desc: true},
+			{expr: "seller_orders.id", cast: "bigint", desc: true},
+		},
+		values: func(o domain.SellerOrder) []interface{} {
+			return []interface{}{o.CreatedAt, o.ID}
+		},
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Items")
+		},
+	})
+}
+
+// UpdateSellerOrderStatus moves a single sub-order to toStatus, posts the given ledger
+// entries and queues messages in one transaction. Calling off a sub-order of an unpaid order takes its subtotal
+// with its tax and shipping off the parent total, and the parent order's status is brought in line with its sub-orders.
+// The unpaid order's open payments are cancelled, since they were started for the old total, and
+// an unpaid order left with every sub-order called off gives its coupon use back.
+func (r *orderRepository) UpdateSellerOrderStatus(sellerOrder *domain.SellerOrder, toStatus string, changedBy uint, note string, entries []domain.LedgerEntry, messages []domain.OutboxMessage) (*domain.SellerOrder, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := transitionSellerOrder(tx, sellerOrder, toStatus, changedBy, note); err != nil {
+			return err
+		}
+
+		if err := postLedgerEntries(tx, entries); err != nil {
+			return err
+		}
+
+		if err := enqueueOutbox(tx, messages); err != nil {
+			return err
+		}
+
+		unpaid := false
+		if toStatus == domain.ORDER_CANCELLED {
+			result := tx.Model(&domain.Order{}).
+				Where("id = ? AND status = ?", sellerOrder.OrderID, domain.ORDER_PENDING).
+				Update("total_amount", gorm.Expr("total_amount - ?", sellerOrder.Total()))
+			if result.Error != nil {
+				return result.Error
+			}
+			unpaid = result.RowsAffected > 0
+		}
+
+		if err := syncOrderStatus(tx, sellerOrder.OrderID); err != nil {
+			return err
+		}
+		if !unpaid {
+			return nil
+		}
+		if err := cancelOpenPayments(tx, sellerOrder.OrderID); err != nil {
+			return err
+		}
+
+		var status string
+		err := tx.Model(&domain.Order{}).Where("id = ?", sellerOrder.OrderID).Select("status").Scan(&status).Error
+		if err != nil {
+			return err
+		}
+		if status == domain.ORDER_CANCELLED {
+			return releaseCoupon(tx, sellerOrder.OrderID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to update seller order status: %v", err)
+		return nil, err
+	}
+
+	return r.FindSellerOrderByID(sellerOrder.ID)
+}
+
+// lockCart locks the buyer's cart rows for the rest of the checkout and makes sure they are
+// still the lines the order was priced from. A concurrent checkout or cart change waits on the
+// lock and then sees the cart it left behind.
+func lockCart(tx *gorm.DB, userID uint, cartItems []domain.Cart) error {
+	var current []domain.Cart
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&current).Error
+	if err != nil {
+		return err
+	}
+	if len(current) != len(cartItems) {
+		return domain.ErrCartChanged
+	}
+
+	priced := make(map[uint]domain.Cart, len(cartItems))
+	for _, item := range cartItems {
+		priced[item.ID] = item
+	}
+	for _, item := range current {
+		expected, ok := priced[item.ID]
+		if !ok || item.Quantity != expected.Quantity || item.Price != expected.Price {
+			return domain.ErrCartChanged
+		}
+	}
+	return nil
+}
+
+// reserveVariantStock takes a cart line's quantity off its SKU and off the product's total stock
+func reserveVariantStock(tx *gorm.DB, item domain.Cart, variant domain.ProductVariant) error {
+	if domain.MoneyFromAmount(variant.Price) != item.Price {
+		return fmt.Errorf("%w: %s", domain.ErrPriceChanged, item.Name)
+	}
+	if variant.Stock < item.Quantity {
+		return fmt.Errorf("%w: %s has %d left", domain.ErrInsufficientStock, item.Name, variant.Stock)
+	}
+
+	result := tx.Model(&domain.ProductVariant{}).
+		Where("id = ? AND stock >= ?", variant.ID, item.Quantity).
+		Update("stock", gorm.Expr("stock - ?", item.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrInsufficientStock, item.Name)
+	}
+
+	return tx.Model(&domain.Product{}).
+		Where("id = ?", variant.ProductID).
+		Update("stock", gorm.Expr("stock - ?", item.Quantity)).Error
+}
+
+// restockItem puts units of an order item back on its SKU, or on the product when it has none
+func restockItem(tx *gorm.DB, item domain.OrderItem, quantity int) error {
+	if item.VariantID != nil {
+		err := tx.Model(&domain.ProductVariant{}).
+			Where("id = ?", *item.VariantID).
+			Update("stock", gorm.Expr("stock + ?", quantity)).Error
+		if err != nil {
+			return err
+		}
+		return refreshProductSummary(tx, item.ProductID)
+	}
+
+	return tx.Model(&domain.Product{}).
+		Where("id = ?", item.ProductID).
+		Update("stock", gorm.Expr("stock + ?", quantity)).Error
+}
+
+// transitionSellerOrder applies a guarded status change to a sub-order, records it in
+// the status history and returns reserved stock when the sub-order is called off
+func transitionSellerOrder(tx *gorm.DB, sellerOrder *domain.SellerOrder, toStatus string, changedBy uint, note string) error {
+	fromStatus := sellerOrder.Status
+
+	result := tx.Model(&domain.SellerOrder{}).
+		Where("id = ? AND status = ?", sellerOrder.ID, fromStatus).
+		Update("status", toStatus)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: sub-order is no longer %s", domain.ErrInvalidTransition, fromStatus)
+	}
+
+	history := domain.OrderStatusHistory{
+		OrderID:       sellerOrder.OrderID,
+		SellerOrderID: sellerOrder.ID,
+		FromStatus:    fromStatus,
+		ToStatus:      toStatus,
+		ChangedBy:     changedBy,
+		Note:          note,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	if domain.RestocksOnTransition(fromStatus, toStatus) {
+		var items []domain.OrderItem
+		if err := tx.Where("seller_order_id = ?", sellerOrder.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := restockItem(tx, item, item.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+
+	sellerOrder.Status = toStatus
+	return nil
+}
+
+// syncOrderStatus sets a parent order's status from the statuses of its sub-orders
+func syncOrderStatus(tx *gorm.DB, orderID uint) error {
+	var statuses []string
+	err := tx.Model(&domain.SellerOrder{}).Where("order_id = ?", orderID).Pluck("status", &statuses).Error
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	return tx.Model(&domain.Order{}).
+		Where("id = ?", orderID).
+		Update("status", domain.DeriveOrderStatus(statuses)).Error
+}
+
+func preloadSellerOrders(db *gorm.DB) *gorm.DB {
+	return db.Preload("SellerOrders.Items").
+		Preload("SellerOrders.StatusHistory", orderStatusHistoryScope)
+}
+
+func orderStatusHistoryScope(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at ASC, id ASC")
+}
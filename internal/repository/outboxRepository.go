@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	ClaimDueMessages(limit int, lease time.Duration) ([]domain.OutboxMessage, error)
+	ExtendLease(ids []uint, until time.Time) error
+	MarkSent(message *domain.OutboxMessage) error
+	ScheduleRetry(message *domain.OutboxMessage, nextAttemptAt time.Time, lastError string) error
+	MarkDead(message *domain.OutboxMessage, lastError string) error
+	FindMessageByID(id uint) (*domain.OutboxMessage, error)
+	FindMessages(query dto.OutboxQuery) ([]domain.OutboxMessage, int64, error)
+	ReplayMessage(id uint) (*domain.OutboxMessage, error)
+}
+
+type outboxRepository struct {
+	DB *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{DB: db}
+}
+
+// enqueueOutbox queues messages inside the caller's transaction
+func enqueueOutbox(tx *gorm.DB, messages []domain.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range messages {
+		messages[i].Status = domain.OUTBOX_PENDING
+		if messages[i].NextAttemptAt.IsZero() {
+			messages[i].NextAttemptAt = now
+		}
+	}
+	return tx.Create(&messages).Error
+}
+
+// ClaimDueMessages picks pending messages that are due and leases them by pushing their next
+// attempt past the lease, so other workers skip them and a crashed worker's messages come back
+func (r *outboxRepository) ClaimDueMessages(limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	var messages []domain.OutboxMessage
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.OUTBOX_PENDING, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+			messages[i].Attempts++
+		}
+
+		return tx.Model(&domain.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": now.Add(lease),
+			}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to claim outbox messages: %v", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// ExtendLease keeps messages that are still being delivered hidden from other workers
+func (r *outboxRepository) ExtendLease(ids []uint, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.DB.Model(&domain.OutboxMessage{}).
+		Where("id IN ? AND status = ?", ids, domain.OUTBOX_PENDING).
+		Update("next_attempt_at", until).Error
+}
+
+func (r *outboxRepository) MarkSent(message *domain.OutboxMessage) error {
+	return r.DB.Model(&domain.OutboxMessage{}).
+		Where("id = ? AND status = ?", message.ID, domain.OUTBOX_PENDING).
+		Updates(scrubbed(message, map[string]interface{}{
+			"status":     domain.OUTBOX_SENT,
+			"sent_at":    time.Now(),
+			"last_error": "",
+		})).Error
+}
+
+func (r *outboxRepository) ScheduleRetry(message *domain.OutboxMessage, nextAttemptAt time.Time, lastError string) error {
+	return r.DB.Model(&domain.OutboxMessage{}).
+		Where("id = ? AND status = ?", message.ID, domain.OUTBOX_PENDING).
+		Updates(map[string]interface{}{
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+func (r *outboxRepository) MarkDead(message *domain.OutboxMessage, lastError string) error {
+	return r.DB.Model(&domain.OutboxMessage{}).
+		Where("id = ? AND status = ?", message.ID, domain.OUTBOX_PENDING).
+		Updates(scrubbed(message, map[string]interface{}{
+			"status":     domain.OUTBOX_DEAD,
+			"last_error": lastError,
+		})).Error
+}
+
+// scrubbed adds clearing the body to the updates of a message that carries a one-time code,
+// so the code is not kept once the message will not be sent again
+func scrubbed(message *domain.OutboxMessage, updates map[string]interface{}) map[string]interface{} {
+	if message.Sensitive {
+		updates["body"] = ""
+	}
+	return updates
+}
+
+func (r *outboxRepository) FindMessageByID(id uint) (*domain.OutboxMessage, error) {
+	var message domain.OutboxMessage
+	err := r.DB.First(&message, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *outboxRepository) FindMessages(query dto.OutboxQuery) ([]domain.OutboxMessage, int64, error) {
+	var messages []domain.OutboxMessage
+	var total int64
+
+	db := r.DB.Model(&domain.OutboxMessage{})
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Template != "" {
+		db = db.Where("template = ?", query.Template)
+	}
+	if query.UserID != 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("created_at DESC").
+		Limit(query.GetLimit()).
+		Offset(query.GetOffset()).
+		Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// ReplayMessage puts a dead message back in the queue with a fresh set of attempts. Messages
+// with one-time codes lost their body when they died, the user asks for a new code instead.
+func (r *outboxRepository) ReplayMessage(id uint) (*domain.OutboxMessage, error) {
+	result := r.DB.Model(&domain.OutboxMessage{}).
+		Where("id = ? AND status = ? AND NOT sensitive AND (expires_at IS NULL OR expires_at > ?)", id, domain.OUTBOX_DEAD, time.Now()).
+		Updates(map[string]interface{}{
+			"status":          domain.OUTBOX_PENDING,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("Failed to replay outbox message: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.FindMessageByID(id); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrNotReplayable
+	}
+
+	return r.FindMessageByID(id)
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sortKey is one expression a listing is ordered by. Cursors carry values as text, and cast
+// is the SQL type they are converted back to for comparison.
+type sortKey struct {
+	expr string
+	args []interface{}
+	cast string
+	desc bool
+}
+
+// listing describes how to page a query: the name of its sort, which cursors are tied to,
+// its sort keys, the last of which must be unique, the values of those keys on a row,
+// and anything the page query needs beyond the filters
+type listing[T any] struct {
+	sort   string
+	keys   []sortKey
+	values func(row T) []interface{}
+	query  func(db *gorm.DB) *gorm.DB
+}
+
+// cursor is the decoded form of the opaque after/before parameters
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// paginate loads one page of the filtered query db, by offset or by keyset cursor, and
+// reports the cursors either side of it. The total is only counted when asked for.
+func paginate[T any](db *gorm.DB, params dto.PaginationParams, list listing[T]) ([]T, dto.PageInfo, error) {
+	var page dto.PageInfo
+	base := db.Session(&gorm.Session{})
+
+	if params.ShouldCount() {
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			return nil, page, err
+		}
+		page.Total = &total
+	}
+
+	limit := params.GetLimit()
+	backwards := params.Before != ""
+
+	tx := base.Limit(limit + 1)
+	if list.query != nil {
+		tx = list.query(tx)
+	}
+
+	switch {
+	case params.After != "":
+		condition, args, err := keysetCondition(list, params.After, false)
+		if err != nil {
+			return nil, page, err
+		}
+		tx = tx.Where(condition, args...)
+	case backwards:
+		condition, args, err := keysetCondition(list, params.Before, true)
+		if err != nil {
+			return nil, page, err
+		}
+		tx = tx.Where(condition, args...)
+	default:
+		tx = tx.Offset(params.GetOffset())
+	}
+
+	// Walk backwards from a before cursor, then flip the rows back into listing order
+	order := make([]string, len(list.keys))
+	var orderArgs []interface{}
+	for i, key := range list.keys {
+		order[i] = key.expr + " ASC"
+		if key.desc != backwards {
+			order[i] = key.expr + " DESC"
+		}
+		orderArgs = append(orderArgs, key.args...)
+	}
+	tx = tx.Order(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(order, ", "), Vars: orderArgs, WithoutParentheses: true}})
+
+	var rows []T
+	if err := tx.Find(&rows).Error; err != nil {
+		return nil, page, err
+	}
+
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, page, nil
+	}
+
+	first, last := encodeCursor(list.sort, list.values(rows[0])), encodeCursor(list.sort, list.values(rows[len(rows)-1]))
+	switch {
+	case backwards:
+		page.NextCursor = last
+		if more {
+			page.PrevCursor = first
+		}
+	case params.After != "":
+		page.PrevCursor = first
+		if more {
+			page.NextCursor = last
+		}
+	default:
+		if params.GetOffset() > 0 {
+			page.PrevCursor = first
+		}
+		if more {
+			page.NextCursor = last
+		}
+	}
+
+	return rows, page, nil
+}
+
+// keysetCondition builds the WHERE clause selecting rows after the cursor in listing order,
+// or before it when backwards is set:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func keysetCondition[T any](list listing[T], encoded string, backwards bool) (string, []interface{}, error) {
+	values, err := decodeCursor(encoded, list)
+	if err != nil {
+		return "", nil, err
+	}
+
+	keys := list.keys
+	var branches []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = CAST(CAST(? AS text) AS %s)", keys[j].expr, keys[j].cast))
+			args = append(args, keys[j].args...)
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if key.desc != backwards {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s CAST(CAST(? AS text) AS %s)", key.expr, operator, key.cast))
+		args = append(args, key.args...)
+		args = append(args, values[i])
+
+		branches = append(branches, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(branches, " OR ") + ")", args, nil
+}
+
+func encodeCursor(sort string, values []interface{}) string {
+	c := cursor{Sort: sort, Values: make([]string, len(values))}
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			c.Values[i] = t.UTC().Format(time.RFC3339Nano)
+		} else {
+			c.Values[i] = fmt.Sprint(value)
+		}
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor unpacks a cursor and checks it was made for this listing, so a tampered or
+// stale cursor is reported as such rather than failing in the database
+func decodeCursor[T any](encoded string, list listing[T]) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != list.sort || len(c.Values) != len(list.keys) {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	for i, key := range list.keys {
+		var err error
+		switch key.cast {
+		case "bigint", "integer":
+			_, err = strconv.ParseInt(c.Values[i], 10, 64)
+		case "real", "double precision", "numeric":
+			_, err = strconv.ParseFloat(c.Values[i], 64)
+		case "timestamptz":
+			_, err = time.Parse(time.RFC3339Nano, c.Values[i])
+		}
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+	}
+	return c.Values, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	CountResetCodesSince(userID uint, since time.Time) (int64, error)
+	CreateResetCode(code *domain.PasswordResetCode, messages []domain.OutboxMessage) (*domain.PasswordResetCode, error)
+	FindActiveResetCode(userID uint) (*domain.PasswordResetCode, error)
+	RegisterResetAttempt(codeID uint, maxAttempts int) (bool, error)
+	CompletePasswordReset(code *domain.PasswordResetCode, hashedPassword string) error
+}
+
+type passwordResetRepository struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{DB: db}
+}
+
+func (r *passwordResetRepository) CountResetCodesSince(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.DB.Model(&domain.PasswordResetCode{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateResetCode stores a new code, retires any earlier unused one so only the latest code works,
+// and queues the messages that deliver it
+func (r *passwordResetRepository) CreateResetCode(code *domain.PasswordResetCode, messages []domain.OutboxMessage) (*domain.PasswordResetCode, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.PasswordResetCode{}).
+			Where("user_id = ? AND used_at IS NULL", code.UserID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(code).Error; err != nil {
+			return err
+		}
+
+		return enqueueOutbox(tx, messages)
+	})
+	if err != nil {
+		log.Printf("Failed to create password reset code: %v", err)
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *passwordResetRepository) FindActiveResetCode(userID uint) (*domain.PasswordResetCode, error) {
+	var code domain.PasswordResetCode
+	err := r.DB.Where("user_id = ? AND used_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		First(&code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// RegisterResetAttempt counts a guess against the code and reports false once the limit was reached.
+// The guarded increment keeps concurrent guesses from slipping past the limit.
+func (r *passwordResetRepository) RegisterResetAttempt(codeID uint, maxAttempts int) (bool, error) {
+	result := r.DB.Model(&domain.PasswordResetCode{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", codeID, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CompletePasswordReset spends the code, sets the new password and ends every existing session
+// of the user in one transaction
+func (r *passwordResetRepository) CompletePasswordReset(code *domain.PasswordResetCode, hashedPassword string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.PasswordResetCode{}).
+			Where("id = ? AND used_at IS NULL", code.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrInvalidResetCode
+		}
+
+		err := tx.Model(&domain.User{}).
+			Where("id = ?", code.UserID).
+			Update("password", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+
+		return revokeUserTokens(tx, code.UserID)
+	})
+}
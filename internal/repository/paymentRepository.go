@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRepository interface {
+	CreatePayment(payment *domain.Payment) (*domain.Payment, error)
+	FindPaymentByTxRef(txRef string) (*domain.Payment, error)
+	FindSuccessfulPaymentByOrderID(orderID uint) (*domain.Payment, error)
+	FindPendingPaymentByOrderID(orderID uint) (*domain.Payment, error)
+	FindUnappliedPayments() ([]domain.Payment, error)
+	UpdatePaymentLink(id uint, link string) error
+	MarkPaymentSuccessful(payment *domain.Payment, providerTxID string, messages []domain.OutboxMessage) (bool, error)
+	MarkPaymentFailed(payment *domain.Payment, providerTxID string) error
+	MarkPaymentRefunded(payment *domain.Payment, refundID string) error
+}
+
+type paymentRepository struct {
+	DB *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	return &paymentRepository{DB: db}
+}
+
+// CreatePayment starts a new payment for an order and cancels the order's other pending payments
+// in the same transaction, so an order has at most one payment the buyer can still pay. The order
+// is locked first, so two payments started at once cannot both stay open.
+func (r *paymentRepository) CreatePayment(payment *domain.Payment) (*domain.Payment, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var order domain.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&order, payment.OrderID).Error
+		if err != nil {
+			return err
+		}
+		if err := cancelOpenPayments(tx, payment.OrderID); err != nil {
+			return err
+		}
+		return tx.Create(payment).Error
+	})
+	if err != nil {
+		log.Printf("Failed to create payment: %v", err)
+		return nil, err
+	}
+	log.Println("Payment created successfully")
+	return payment, nil
+}
+
+func (r *paymentRepository) FindPaymentByTxRef(txRef string) (*domain.Payment, error) {
+	var payment domain.Payment
+	err := r.DB.Where("tx_ref = ?", txRef).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) FindSuccessfulPaymentByOrderID(orderID uint) (*domain.Payment, error) {
+	var payment domain.Payment
+	err := r.DB.Where("order_id = ? AND status = ?", orderID, domain.PAYMENT_SUCCESSFUL).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) FindPendingPaymentByOrderID(orderID uint) (*domain.Payment, error) {
+	var payment domain.Payment
+	err := r.DB.Where("order_id = ? AND status = ?", orderID, domain.PAYMENT_PENDING).Order("id DESC").First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// FindUnappliedPayments lists captured money that still has to go back to the buyer
+func (r *paymentRepository) FindUnappliedPayments() ([]domain.Payment, error) {
+	var payments []domain.Payment
+	err := r.DB.Where("status = ?", domain.PAYMENT_UNAPPLIED).Order("id").Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) UpdatePaymentLink(id uint, link string) error {
+	return r.DB.Model(&domain.Payment{}).Where("id = ?", id).Update("payment_link", link).Error
+}
+
+// capturablePaymentStatuses are the statuses a payment the provider reports as paid can be in:
+// pending, failed when a retry under the same reference paid after all, and cancelled when the
+// buyer paid a link the order no longer accepts
+var capturablePaymentStatuses = []string{domain.PAYMENT_PENDING, domain.PAYMENT_FAILED, domain.PAYMENT_CANCELLED}
+
+// MarkPaymentSuccessful records a captured payment, books it into the clearing account and, when
+// its order is still pending at the amount the payment was started for, moves the order and
+// sub-orders from pending to paid and queues messages, all in one transaction. The order is locked
+// first, so a cancellation that changes its total either happens before and is seen here or waits.
+// Money captured for a cancelled payment or an order that moved on is marked unapplied instead.
+// It reports whether the payment was applied to its order; a payment that was already settled
+// is left alone, which makes replayed webhooks a no-op.
+func (r *paymentRepository) MarkPaymentSuccessful(payment *domain.Payment, providerTxID string, messages []domain.OutboxMessage) (bool, error) {
+	applied := false
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var order domain.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, payment.OrderID).Error
+		if err != nil {
+			return err
+		}
+
+		var current domain.Payment
+		if err := tx.Select("status").First(&current, payment.ID).Error; err != nil {
+			return err
+		}
+
+		status := domain.PAYMENT_SUCCESSFUL
+		if current.Status == domain.PAYMENT_CANCELLED || order.Status != domain.ORDER_PENDING ||
+			domain.MoneyFromAmount(payment.Amount) != order.TotalAmount {
+			status = domain.PAYMENT_UNAPPLIED
+		}
+
+		result := tx.Model(&domain.Payment{}).
+			Where("id = ? AND status IN ?", payment.ID, capturablePaymentStatuses).
+			Updates(map[string]interface{}{
+				"status":         status,
+				"provider_tx_id": providerTxID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := postLedgerEntries(tx, domain.PaymentReceivedEntries(payment)); err != nil {
+			return err
+		}
+
+		if status == domain.PAYMENT_UNAPPLIED {
+			log.Printf("Payment %s captured for order %d which no longer takes it, it has to be refunded", payment.TxRef, payment.OrderID)
+			return nil
+		}
+		applied = true
+
+		if err := enqueueOutbox(tx, messages); err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Order{}).
+			Where("id = ?", payment.OrderID).
+			Update("status", domain.ORDER_PAID).Error
+		if err != nil {
+			return err
+		}
+
+		var sellerOrders []domain.SellerOrder
+		err = tx.Where("order_id = ? AND status = ?", payment.OrderID, domain.ORDER_PENDING).Find(&sellerOrders).Error
+		if err != nil {
+			return err
+		}
+		for i := range sellerOrders {
+			if err := transitionSellerOrder(tx, &sellerOrders[i], domain.ORDER_PAID, payment.UserID, "payment confirmed: "+payment.TxRef); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to mark payment successful: %v", err)
+		return false, err
+	}
+
+	return applied, nil
+}
+
+func (r *paymentRepository) MarkPaymentFailed(payment *domain.Payment, providerTxID string) error {
+	return r.DB.Model(&domain.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, domain.PAYMENT_PENDING).
+		Updates(map[string]interface{}{
+			"status":         domain.PAYMENT_FAILED,
+			"provider_tx_id": providerTxID,
+		}).Error
+}
+
+// MarkPaymentRefunded records that an unapplied payment went back to the buyer and takes it out
+// of clearing in one transaction
+func (r *paymentRepository) MarkPaymentRefunded(payment *domain.Payment, refundID string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, domain.PAYMENT_UNAPPLIED).
+			Updates(map[string]interface{}{
+				"status":    domain.PAYMENT_REFUNDED,
+				"refund_id": refundID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return postLedgerEntries(tx, domain.UnappliedPaymentRefundEntries(payment))
+	})
+}
+
+// cancelOpenPayments stops the order's pending payments from being applied once its total has
+// changed or it has been called off; the buyer starts a new payment for what is left
+func cancelOpenPayments(tx *gorm.DB, orderID uint) error {
+	return tx.Model(&domain.Payment{}).
+		Where("order_id = ? AND status = ?", orderID, domain.PAYMENT_PENDING).
+		Update("status", domain.PAYMENT_CANCELLED).Error
+}
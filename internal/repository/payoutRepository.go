@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PayoutRepository interface {
+	GetSellerBalance(sellerID uint) (*dto.SellerBalance, error)
+	FindSellerIDsWithBalance(minimum float64) ([]uint, error)
+	CreatePayout(payout *domain.Payout, minimum float64) (*domain.Payout, error)
+	UpdatePayoutStatus(payout *domain.Payout, status string, providerRef string, reason string, messages []domain.OutboxMessage) error
+	FindPayoutByReference(reference string) (*domain.Payout, error)
+	FindUnsettledPayouts(updatedBefore time.Time) ([]domain.Payout, error)
+	FindPayoutsBySellerID(sellerID uint, query dto.PaginationParams) ([]domain.Payout, int64, error)
+}
+
+type payoutRepository struct {
+	DB *gorm.DB
+}
+
+func NewPayoutRepository(db *gorm.DB) PayoutRepository {
+	return &payoutRepository{DB: db}
+}
+
+func (r *payoutRepository) GetSellerBalance(sellerID uint) (*dto.SellerBalance, error) {
+	var balance dto.SellerBalance
+	err := r.DB.Model(&domain.LedgerEntry{}).
+		Select("COALESCE(SUM(credit), 0) - COALESCE(SUM(debit), 0) AS available, "+
+			"COALESCE(SUM(CASE WHEN seller_order_id IS NOT NULL THEN credit - debit ELSE 0 END), 0) AS total_earned, "+
+			"COALESCE(SUM(CASE WHEN payout_id IS NOT NULL THEN debit - credit ELSE 0 END), 0) AS total_paid_out").
+		Where("account = ?", domain.SellerLedgerAccount(sellerID)).
+		Scan(&balance).Error
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+func (r *payoutRepository) FindSellerIDsWithBalance(minimum float64) ([]uint, error) {
+	var sellerIDs []uint
+	err := r.DB.Model(&domain.LedgerEntry{}).
+		Select("seller_id").
+		Where("seller_id > 0").
+		Group("seller_id").
+		Having("SUM(credit) - SUM(debit) >= ? AND SUM(credit) - SUM(debit) > 0", minimum).
+		Pluck("seller_id", &sellerIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return sellerIDs, nil
+}
+
+// CreatePayout sweeps the seller's whole available balance into a pending payout. The
+// seller row is locked for the duration so two settlement runs cannot pay the same money twice.
+func (r *payoutRepository) CreatePayout(payout *domain.Payout, minimum float64) (*domain.Payout, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var seller domain.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&seller, payout.SellerID).Error
+		if err != nil {
+			return err
+		}
+
+		var available float64
+		err = tx.Model(&domain.LedgerEntry{}).
+			Select("COALESCE(SUM(credit), 0) - COALESCE(SUM(debit), 0)").
+			Where("account = ?", domain.SellerLedgerAccount(payout.SellerID)).
+			Scan(&available).Error
+		if err != nil {
+			return err
+		}
+
+		available = domain.RoundAmount(available)
+		if available <= 0 || available < minimum {
+			return domain.ErrBalanceTooLow
+		}
+
+		payout.Amount = available
+		payout.Status = domain.PAYOUT_PENDING
+		if err := tx.Create(payout).Error; err != nil {
+			return err
+		}
+
+		return postLedgerEntries(tx, domain.PayoutEntries(payout))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Payout %s created for seller %d", payout.Reference, payout.SellerID)
+	return payout, nil
+}
+
+// UpdatePayoutStatus records the outcome of a transfer and queues messages about it. A failed payout gives
+// the money back to the seller's balance in the same transaction; settled payouts are never changed again.
+func (r *payoutRepository) UpdatePayoutStatus(payout *domain.Payout, status string, providerRef string, reason string, messages []domain.OutboxMessage) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":         status,
+			"failure_reason": reason,
+		}
+		if providerRef != "" {
+			updates["provider_ref"] = providerRef
+		}
+
+		result := tx.Model(&domain.Payout{}).
+			Where("id = ? AND status IN ?", payout.ID, []string{domain.PAYOUT_PENDING, domain.PAYOUT_PROCESSING}).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := enqueueOutbox(tx, messages); err != nil {
+			return err
+		}
+		if status != domain.PAYOUT_FAILED {
+			return nil
+		}
+
+		return postLedgerEntries(tx, domain.PayoutReversalEntries(payout))
+	})
+}
+
+func (r *payoutRepository) FindPayoutByReference(reference string) (*domain.Payout, error) {
+	var payout domain.Payout
+	err := r.DB.Where("reference = ?", reference).First(&payout).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// FindUnsettledPayouts lists payouts still waiting on their transfer that have not changed since updatedBefore
+func (r *payoutRepository) FindUnsettledPayouts(updatedBefore time.Time) ([]domain.Payout, error) {
+	var payouts []domain.Payout
+	err := r.DB.Where("status IN ? AND updated_at < ?", []string{domain.PAYOUT_PENDING, domain.PAYOUT_PROCESSING}, updatedBefore).
+		Order("id").
+		Find(&payouts).Error
+	if err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+func (r *payoutRepository) FindPayoutsBySellerID(sellerID uint, query dto.PaginationParams) ([]domain.Payout, int64, error) {
+	var payouts []domain.Payout
+	var total int64
+
+	db := r.DB.Model(&domain.Payout{}).Where("seller_id = ?", sellerID)
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("created_at DESC").Offset(query.GetOffset()).Limit(query.GetLimit()).Find(&payouts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return payouts, total, nil
+}
+
+// postLedgerEntries writes the legs of a double-entry posting inside the caller's transaction
+func postLedgerEntries(tx *gorm.DB, entries []domain.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if !domain.LedgerEntriesBalance(entries) {
+		return fmt.Errorf("%w: %s", domain.ErrUnbalancedLedger, entries[0].EntryRef)
+	}
+	return tx.Create(&entries).Error
+}
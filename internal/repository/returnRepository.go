@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepository interface {
+	FindOrderItemByID(id uint) (*domain.OrderItem, error)
+	CreateReturn(returnRequest *domain.ReturnRequest) (*domain.ReturnRequest, error)
+	FindReturnByID(id uint) (*domain.ReturnRequest, error)
+	FindReturnsByUserID(userID uint, query dto.ReturnQuery) ([]domain.ReturnRequest, int64, error)
+	FindReturnsBySellerID(sellerID uint, query dto.ReturnQuery) ([]domain.ReturnRequest, int64, error)
+	ApproveReturn(returnRequest *domain.ReturnRequest, refundAmount float64, note string) error
+	RejectReturn(returnRequest *domain.ReturnRequest, note string) error
+	CompleteRefund(returnRequest *domain.ReturnRequest, providerRefundID string, changedBy uint) error
+	MarkRefundPending(returnRequest *domain.ReturnRequest, reason string) error
+	MarkRefundFailed(returnRequest *domain.ReturnRequest, reason string) error
+	FindPendingRefunds(updatedBefore time.Time) ([]domain.ReturnRequest, error)
+}
+
+type returnRepository struct {
+	DB *gorm.DB
+}
+
+func NewReturnRepository(db *gorm.DB) ReturnRepository {
+	return &returnRepository{DB: db}
+}
+
+func (r *returnRepository) FindOrderItemByID(id uint) (*domain.OrderItem, error) {
+	var item domain.OrderItem
+	err := r.DB.First(&item, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateReturn records a return request. The order item is locked while the quantity already
+// under return is counted, so concurrent requests cannot return more than was bought.
+func (r *returnRepository) CreateReturn(returnRequest *domain.ReturnRequest) (*domain.ReturnRequest, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var item domain.OrderItem
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, returnRequest.OrderItemID).Error
+		if err != nil {
+			return err
+		}
+
+		var returned int64
+		err = tx.Model(&domain.ReturnRequest{}).
+			Select("COALESCE(SUM(quantity), 0)").
+			Where("order_item_id = ? AND status <> ?", item.ID, domain.RETURN_REJECTED).
+			Scan(&returned).Error
+		if err != nil {
+			return err
+		}
+
+		if int64(returnRequest.Quantity)+returned > int64(item.Quantity) {
+			return fmt.Errorf("%w: %d of %d left", domain.ErrReturnQuantity, int64(item.Quantity)-returned, item.Quantity)
+		}
+
+		return tx.Create(returnRequest).Error
+	})
+	if err != nil {
+		log.Printf("Failed to create return request: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Return %s requested for order item %d", returnRequest.Reference, returnRequest.OrderItemID)
+	return returnRequest, nil
+}
+
+func (r *returnRepository) FindReturnByID(id uint) (*domain.ReturnRequest, error) {
+	var returnRequest domain.ReturnRequest
+	err := r.DB.Scopes(returnImagesScope).First(&returnRequest, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &returnRequest, nil
+}
+
+func (r *returnRepository) FindReturnsByUserID(userID uint, query dto.ReturnQuery) ([]domain.ReturnRequest, int64, error) {
+	return r.findReturns(r.DB.Where("user_id = ?", userID), query)
+}
+
+func (r *returnRepository) FindReturnsBySellerID(sellerID uint, query dto.ReturnQuery) ([]domain.ReturnRequest, int64, error) {
+	return r.findReturns(r.DB.Where("seller_id = ?", sellerID), query)
+}
+
+func (r *returnRepository) findReturns(db *gorm.DB, query dto.ReturnQuery) ([]domain.ReturnRequest, int64, error) {
+	var returns []domain.ReturnRequest
+	var total int64
+
+	db = db.Model(&domain.ReturnRequest{})
+
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Scopes(returnImagesScope).
+		Order("created_at DESC").
+		Offset(query.GetOffset()).
+		Limit(query.GetLimit()).
+		Find(&returns).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return returns, total, nil
+}
+
+// ApproveReturn accepts a requested return, or retries one whose refund failed, and fixes
+// the amount to refund before the provider is called
+func (r *returnRepository) ApproveReturn(returnRequest *domain.ReturnRequest, refundAmount float64, note string) error {
+	result := r.DB.Model(&domain.ReturnRequest{}).
+		Where("id = ? AND status IN ?", returnRequest.ID, []string{domain.RETURN_REQUESTED, domain.RETURN_REFUND_FAILED}).
+		Updates(map[string]interface{}{
+			"status":         domain.RETURN_APPROVED,
+			"refund_amount":  refundAmount,
+			"seller_note":    note,
+			"failure_reason": "",
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrInvalidReturnState
+	}
+
+	returnRequest.Status = domain.RETURN_APPROVED
+	returnRequest.RefundAmount = refundAmount
+	returnRequest.SellerNote = note
+	returnRequest.FailureReason = ""
+	return nil
+}
+
+func (r *returnRepository) RejectReturn(returnRequest *domain.ReturnRequest, note string) error {
+	result := r.DB.Model(&domain.ReturnRequest{}).
+		Where("id = ? AND status = ?", returnRequest.ID, domain.RETURN_REQUESTED).
+		Updates(map[string]interface{}{
+			"status":      domain.RETURN_REJECTED,
+			"seller_note": note,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrInvalidReturnState
+	}
+
+	returnRequest.Status = domain.RETURN_REJECTED
+	returnRequest.SellerNote = note
+	return nil
+}
+
+// CompleteRefund marks an approved return refunded, puts the returned units back in stock
+// and reverses the sale in the ledger, all in one transaction. Once every unit of the sub-order
+// has been refunded the sub-order, and with it the order, moves to refunded.
+func (r *returnRepository) CompleteRefund(returnRequest *domain.ReturnRequest, providerRefundID string, changedBy uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.ReturnRequest{}).
+			Where("id = ? AND status IN ?", returnRequest.ID, []string{domain.RETURN_APPROVED, domain.RETURN_REFUND_PENDING}).
+			Updates(map[string]interface{}{
+				"status":             domain.RETURN_REFUNDED,
+				"provider_refund_id": providerRefundID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrInvalidReturnState
+		}
+
+		var item domain.OrderItem
+		if err := tx.First(&item, returnRequest.OrderItemID).Error; err != nil {
+			return err
+		}
+		if err := restockItem(tx, item, returnRequest.Quantity); err != nil {
+			return err
+		}
+
+		var sale []domain.LedgerEntry
+		err := tx.Where("entry_ref = ?", domain.SaleEntryRef(returnRequest.SellerOrderID)).Find(&sale).Error
+		if err != nil {
+			return err
+		}
+
+		if err := postLedgerEntries(tx, domain.RefundEntries(returnRequest, sale)); err != nil {
+			return err
+		}
+
+		return refundSellerOrderIfReturned(tx, returnRequest.SellerOrderID, changedBy)
+	})
+	if err != nil {
+		log.Printf("Failed to complete refund for return %s: %v", returnRequest.Reference, err)
+		return err
+	}
+
+	returnRequest.Status = domain.RETURN_REFUNDED
+	returnRequest.ProviderRefundID = providerRefundID
+	return nil
+}
+
+// MarkRefundPending keeps an approved return waiting while the outcome of its refund is unknown
+func (r *returnRepository) MarkRefundPending(returnRequest *domain.ReturnRequest, reason string) error {
+	err := r.DB.Model(&domain.ReturnRequest{}).
+		Where("id = ? AND status = ?", returnRequest.ID, domain.RETURN_APPROVED).
+		Updates(map[string]interface{}{
+			"status":         domain.RETURN_REFUND_PENDING,
+			"failure_reason": reason,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	returnRequest.Status = domain.RETURN_REFUND_PENDING
+	returnRequest.FailureReason = reason
+	return nil
+}
+
+// MarkRefundFailed records that the provider declined the refund, so the return may be approved again
+func (r *returnRepository) MarkRefundFailed(returnRequest *domain.ReturnRequest, reason string) error {
+	err := r.DB.Model(&domain.ReturnRequest{}).
+		Where("id = ? AND status IN ?", returnRequest.ID, []string{domain.RETURN_APPROVED, domain.RETURN_REFUND_PENDING}).
+		Updates(map[string]interface{}{
+			"status":         domain.RETURN_REFUND_FAILED,
+			"failure_reason": reason,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	returnRequest.Status = domain.RETURN_REFUND_FAILED
+	returnRequest.FailureReason = reason
+	return nil
+}
+
+// FindPendingRefunds lists returns whose refund outcome is unknown and has not changed since updatedBefore
+func (r *returnRepository) FindPendingRefunds(updatedBefore time.Time) ([]domain.ReturnRequest, error) {
+	var returns []domain.ReturnRequest
+	err := r.DB.Where("status = ? AND updated_at < ?", domain.RETURN_REFUND_PENDING, updatedBefore).
+		Order("id").
+		Find(&returns).Error
+	if err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// refundSellerOrderIfReturned moves a delivered sub-order to refunded once refunds cover every
+// unit that was bought in it
+func refundSellerOrderIfReturned(tx *gorm.DB, sellerOrderID uint, changedBy uint) error {
+	var bought, refunded int64
+	err := tx.Model(&domain.OrderItem{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("seller_order_id = ?", sellerOrderID).
+		Scan(&bought).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&domain.ReturnRequest{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("seller_order_id = ? AND status = ?", sellerOrderID, domain.RETURN_REFUNDED).
+		Scan(&refunded).Error
+	if err != nil {
+		return err
+	}
+	if refunded < bought {
+		return nil
+	}
+
+	var sellerOrder domain.SellerOrder
+	if err := tx.First(&sellerOrder, sellerOrderID).Error; err != nil {
+		return err
+	}
+	if !domain.CanTransitionOrder(sellerOrder.Status, domain.ORDER_REFUNDED) {
+		return nil
+	}
+	if err := transitionSellerOrder(tx, &sellerOrder, domain.ORDER_REFUNDED, changedBy, "all items returned and refunded"); err != nil {
+		return err
+	}
+	return syncOrderStatus(tx, sellerOrder.OrderID)
+}
+
+func returnImagesScope(db *gorm.DB) *gorm.DB {
+	return db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	})
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	SeedRoles(rolePermissions map[string][]string) error
+	FindRoles() ([]domain.Role, error)
+	FindRoleByName(name string) (*domain.Role, error)
+	FindRolesByUserID(userID uint) ([]domain.Role, error)
+	FindPermissionsByUserID(userID uint) ([]string, error)
+	AssignRole(userRole *domain.UserRole) error
+	RemoveRole(userID uint, roleID uint) error
+}
+
+type roleRepository struct {
+	DB *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{DB: db}
+}
+
+// SeedRoles makes sure every role and permission exists and grants each role at least the
+// given permissions. Permissions added to a role by hand are left in place.
+func (r *roleRepository) SeedRoles(rolePermissions map[string][]string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for roleName, permissionNames := range rolePermissions {
+			role := domain.Role{Name: roleName}
+			if err := tx.Where("name = ?", roleName).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			permissions := make([]domain.Permission, len(permissionNames))
+			for i, permissionName := range permissionNames {
+				permissions[i] = domain.Permission{Name: permissionName}
+				if err := tx.Where("name = ?", permissionName).FirstOrCreate(&permissions[i]).Error; err != nil {
+					return err
+				}
+			}
+
+			if len(permissions) > 0 {
+				if err := tx.Model(&role).Association("Permissions").Append(permissions); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) FindRoles() ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.DB.Preload("Permissions").Order("name ASC").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindRoleByName(name string) (*domain.Role, error) {
+	var role domain.Role
+	err := r.DB.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindRolesByUserID returns the role implied by the user's UserType and every role granted to them
+func (r *roleRepository) FindRolesByUserID(userID uint) ([]domain.Role, error) {
+	var roles []domain.Role
+	err := r.DB.Preload("Permissions").
+		Where("name = (SELECT user_type FROM users WHERE id = ?)", userID).
+		Or("id IN (SELECT role_id FROM user_roles WHERE user_id = ?)", userID).
+		Order("name ASC").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindPermissionsByUserID(userID uint) ([]string, error) {
+	var permissions []string
+	err := r.DB.Model(&domain.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = (SELECT user_type FROM users WHERE id = ?)", userID).
+		Or("roles.id IN (SELECT role_id FROM user_roles WHERE user_id = ?)", userID).
+		Pluck("permissions.name", &permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepository) AssignRole(userRole *domain.UserRole) error {
+	err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(userRole).Error
+	if err != nil {
+		log.Printf("Failed to assign role: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *roleRepository) RemoveRole(userID uint, roleID uint) error {
+	result := r.DB.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&domain.UserRole{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
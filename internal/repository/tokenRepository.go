@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errRefreshTokenReused = errors.New("refresh token already rotated")
+
+type TokenRepository interface {
+	CreateRefreshToken(token *domain.RefreshToken) (*domain.RefreshToken, error)
+	FindRefreshTokenByHash(tokenHash string) (*domain.RefreshToken, error)
+	RotateRefreshToken(current *domain.RefreshToken, next *domain.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeSession(revoked *domain.RevokedToken, familyID string) error
+	RevokeAllUserTokens(userID uint) error
+	IsTokenRevoked(jti string, userID uint, tokenVersion int) (bool, error)
+	DeleteExpiredTokens() (int64, error)
+}
+
+type tokenRepository struct {
+	DB *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{DB: db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(token *domain.RefreshToken) (*domain.RefreshToken, error) {
+	err := r.DB.Create(token).Error
+	if err != nil {
+		log.Printf("Failed to create refresh token: %v", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *tokenRepository) FindRefreshTokenByHash(tokenHash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.DB.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken revokes current and stores next in its place. It reports false when current
+// had already been revoked, e.g. by a concurrent refresh, in which case nothing is stored.
+func (r *tokenRepository) RotateRefreshToken(current *domain.RefreshToken, next *domain.RefreshToken) (bool, error) {
+	rotated := false
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&domain.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     time.Now(),
+				"replaced_by_id": next.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// roll back the new token, the old one was used twice
+			return errRefreshTokenReused
+		}
+
+		rotated = true
+		return nil
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Failed to rotate refresh token: %v", err)
+		return false, err
+	}
+
+	return rotated, nil
+}
+
+func (r *tokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	return r.DB.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeSession denies the access token and revokes the refresh tokens of its session family
+// together, so neither outlives the other
+func (r *tokenRepository) RevokeSession(revoked *domain.RevokedToken, familyID string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if revoked.JTI != "" {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(revoked).Error; err != nil {
+				return err
+			}
+		}
+		if familyID == "" {
+			return nil
+		}
+		return tx.Model(&domain.RefreshToken{}).
+			Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", revoked.UserID, familyID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// RevokeAllUserTokens ends every session of a user: all refresh tokens are revoked and the
+// token version is bumped so that access tokens issued before now stop being accepted
+func (r *tokenRepository) RevokeAllUserTokens(userID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeUserTokens(tx, userID)
+	})
+}
+
+// IsTokenRevoked implements helper.TokenDenylist. Tokens of deleted users count as revoked.
+// It runs on every authenticated request, so the denylist and the token version are read in
+// a single query.
+func (r *tokenRepository) IsTokenRevoked(jti string, userID uint, tokenVersion int) (bool, error) {
+	var state struct {
+		TokenVersion int
+		Denied       bool
+	}
+	result := r.DB.Raw(`SELECT u.token_version, EXISTS (SELECT 1 FROM revoked_tokens r WHERE r.jti = ?) AS denied
+		FROM users u WHERE u.id = ?`, jti, userID).Scan(&state)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 0 || state.Denied || state.TokenVersion != tokenVersion, nil
+}
+
+// DeleteExpiredTokens drops denylist entries and refresh tokens that can no longer be used anyway
+func (r *tokenRepository) DeleteExpiredTokens() (int64, error) {
+	now := time.Now()
+
+	revoked := r.DB.Where("expires_at < ?", now).Delete(&domain.RevokedToken{})
+	if revoked.Error != nil {
+		return 0, revoked.Error
+	}
+
+	refresh := r.DB.Where("expires_at < ?", now).Delete(&domain.RefreshToken{})
+	if refresh.Error != nil {
+		return revoked.RowsAffected, refresh.Error
+	}
+
+	return revoked.RowsAffected + refresh.RowsAffected, nil
+}
+
+// revokeUserTokens ends every session of a user inside the caller's transaction
+func revokeUserTokens(tx *gorm.DB, userID uint) error {
+	err := tx.Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&domain.User{}).
+		Where("id = ?", userID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
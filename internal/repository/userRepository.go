@@ -4,6 +4,7 @@ import (
 	"errors"
 	"go-ecommerce-app/internal/domain"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -15,15 +16,18 @@ type UserRepository interface {
 	FindUserByID(id uint) (*domain.User, error)
 	FindAllUsers() ([]domain.User, error)
 	UpdateUser(id uint, u domain.User) (domain.User, error)
+	UpdateUserFields(id uint, updates map[string]interface{}) (domain.User, error)
 	DeleteUser(id uint) error
 	CreateBankAccount(bankAccount *domain.BankAccount) (*domain.BankAccount, error)
+	FindBankAccountByUserID(userID uint) (*domain.BankAccount, error)
+	SaveVerificationCode(id uint, code int, channel string, expiry time.Time, messages []domain.OutboxMessage) error
 
 	// Cart methods
 	CreateCart(cart *domain.Cart) (*domain.Cart, error)
 	FindCartByUserID(userID uint) ([]domain.Cart, error)
-	FindCartByUserIDAndProductID(userID uint, productID uint) (*domain.Cart, error)
+	FindCartByUserIDAndProductID(userID uint, productID uint, variantID *uint) (*domain.Cart, error)
 	UpdateCart(cart *domain.Cart) (*domain.Cart, error)
-	DeleteCartItem(userID uint, productID uint) error
+	DeleteCartItem(userID uint, productID uint, variantID *uint) error
 	DeleteAllCartItems(userID uint) error
 
 	//Profile methods
@@ -89,6 +93,18 @@ func (r *userRepository) UpdateUser(id uint, u domain.User) (domain.User, error)
 	return user, nil
 }
 
+// UpdateUserFields updates the named columns, including ones being set to false or empty, which
+// UpdateUser skips
+func (r *userRepository) UpdateUserFields(id uint, updates map[string]interface{}) (domain.User, error) {
+	var user domain.User
+	err := r.DB.Model(&user).Clauses(clause.Returning{}).Where("id=?", id).Updates(updates).Error
+	if err != nil {
+		log.Printf("error on update %v", err)
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
 func (r *userRepository) DeleteUser(id uint) error {
 	return r.DB.Delete(&domain.User{}, id).Error
 }
@@ -103,6 +119,35 @@ func (r *userRepository) CreateBankAccount(bankAccount *domain.BankAccount) (*do
 	return bankAccount, nil
 }
 
+func (r *userRepository) FindBankAccountByUserID(userID uint) (*domain.BankAccount, error) {
+	var bankAccount domain.BankAccount
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").First(&bankAccount).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bankAccount, nil
+}
+
+// SaveVerificationCode stores a contact verification code and queues the messages that deliver it
+func (r *userRepository) SaveVerificationCode(id uint, code int, channel string, expiry time.Time, messages []domain.OutboxMessage) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"code":         code,
+			"code_channel": channel,
+			"expiry":       expiry,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return enqueueOutbox(tx, messages)
+	})
+	if err != nil {
+		log.Printf("Failed to save verification code: %v", err)
+	}
+	return err
+}
+
 // Cart methods
 
 func (r *userRepository) CreateCart(cart *domain.Cart) (*domain.Cart, error) {
@@ -124,9 +169,10 @@ func (r *userRepository) FindCartByUserID(userID uint) ([]domain.Cart, error) {
 	return cartItems, nil
 }
 
-func (r *userRepository) FindCartByUserIDAndProductID(userID uint, productID uint) (*domain.Cart, error) {
+// FindCartByUserIDAndProductID finds the cart line for a product, or for one of its SKUs when variantID is set
+func (r *userRepository) FindCartByUserIDAndProductID(userID uint, productID uint, variantID *uint) (*domain.Cart, error) {
 	var cartItem domain.Cart
-	err := r.DB.Where("user_id = ? AND product_id = ?", userID, productID).First(&cartItem).Error
+	err := r.DB.Where("user_id = ? AND product_id = ?", userID, productID).Scopes(cartVariantScope(variantID)).First(&cartItem).Error
 	if err != nil {
 		return nil, err
 	}
@@ -143,8 +189,8 @@ func (r *userRepository) UpdateCart(cart *domain.Cart) (*domain.Cart, error) {
 	return &updatedCart, nil
 }
 
-func (r *userRepository) DeleteCartItem(userID uint, productID uint) error {
-	result := r.DB.Where("user_id = ? AND product_id = ?", userID, productID).Delete(&domain.Cart{})
+func (r *userRepository) DeleteCartItem(userID uint, productID uint, variantID *uint) error {
+	result := r.DB.Where("user_id = ? AND product_id = ?", userID, productID).Scopes(cartVariantScope(variantID)).Delete(&domain.Cart{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -154,6 +200,15 @@ func (r *userRepository) DeleteCartItem(userID uint, productID uint) error {
 	return nil
 }
 
+func cartVariantScope(variantID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if variantID == nil {
+			return db.Where("variant_id IS NULL")
+		}
+		return db.Where("variant_id = ?", *variantID)
+	}
+}
+
 func (r *userRepository) DeleteAllCartItems(userID uint) error {
 	result := r.DB.Where("user_id = ?", userID).Delete(&domain.Cart{})
 	if result.Error != nil {
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WishlistRepository interface {
+	AddItem(item *domain.WishlistItem) (*domain.WishlistItem, error)
+	FindItems(userID uint, params dto.PaginationParams) ([]domain.WishlistItem, dto.PageInfo, error)
+	FindItem(userID uint, productID uint) (*domain.WishlistItem, error)
+	UpdateItem(item *domain.WishlistItem, updates map[string]interface{}) (*domain.WishlistItem, error)
+	DeleteItem(userID uint, productID uint) error
+
+	// Alert methods
+	FindWatchers(productID uint, alert string, price float64) ([]domain.WishlistItem, error)
+	RecordAlerts(alert string, items []domain.WishlistItem, price float64, messages []domain.OutboxMessage) error
+
+	CountBySeller(sellerID uint) ([]domain.WishlistCount, error)
+}
+
+type wishlistRepository struct {
+	DB *gorm.DB
+}
+
+func NewWishlistRepository(db *gorm.DB) WishlistRepository {
+	return &wishlistRepository{DB: db}
+}
+
+// AddItem saves a product to the user's wishlist, failing with ErrAlreadyInWishlist when it is
+// already there
+func (r *wishlistRepository) AddItem(item *domain.WishlistItem) (*domain.WishlistItem, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(item)
+	if result.Error != nil {
+		log.Printf("Failed to add wishlist item: %v", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrAlreadyInWishlist
+	}
+	return r.FindItem(item.UserID, item.ProductID)
+}
+
+func (r *wishlistRepository) FindItems(userID uint, params dto.PaginationParams) ([]domain.WishlistItem, dto.PageInfo, error) {
+	db := r.DB.Model(&domain.WishlistItem{}).Where("user_id = ?", userID)
+
+	return paginate(db, params, listing[domain.WishlistItem]{
+		sort: "newest",
+		keys: []sortKey{
+			{expr: "created_at", cast: "timestamptz", desc: true},
+			{expr: "id", cast: "bigint", desc: true},
+		},
+		values: func(item domain.WishlistItem) []interface{} {
+			return []interface{}{item.CreatedAt, item.ID}
+		},
+		query: func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Product")
+		},
+	})
+}
+
+func (r *wishlistRepository) FindItem(userID uint, productID uint) (*domain.WishlistItem, error) {
+	var item domain.WishlistItem
+	err := r.DB.Preload("Product").Where("user_id = ? AND product_id = ?", userID, productID).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *wishlistRepository) UpdateItem(item *domain.WishlistItem, updates map[string]interface{}) (*domain.WishlistItem, error) {
+	err := r.DB.Model(&domain.WishlistItem{}).Where("id = ?", item.ID).Updates(updates).Error
+	if err != nil {
+		log.Printf("Failed to update wishlist item: %v", err)
+		return nil, err
+	}
+	return r.FindItem(item.UserID, item.ProductID)
+}
+
+func (r *wishlistRepository) DeleteItem(userID uint, productID uint) error {
+	result := r.DB.Where("user_id = ? AND product_id = ?", userID, productID).Delete(&domain.WishlistItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Alert methods
+
+// FindWatchers returns the wishlist items of a product that asked for the alert. For a price
+// drop only items that have not yet been told about this price or a lower one are returned.
+func (r *wishlistRepository) FindWatchers(productID uint, alert string, price float64) ([]domain.WishlistItem, error) {
+	db := r.DB.Where("product_id = ?", productID)
+	switch alert {
+	case domain.WISHLIST_PRICE_DROP:
+		db = db.Where("notify_price_drop AND alert_price > ?", price)
+	case domain.WISHLIST_BACK_IN_STOCK:
+		db = db.Where("notify_back_in_stock")
+	}
+
+	var items []domain.WishlistItem
+	if err := db.Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// RecordAlerts queues the alert messages and, for a price drop, remembers the price the items
+// were told about, in one transaction
+func (r *wishlistRepository) RecordAlerts(alert string, items []domain.WishlistItem, price float64, messages []domain.OutboxMessage) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if alert == domain.WISHLIST_PRICE_DROP && len(items) > 0 {
+			ids := make([]uint, len(items))
+			for i, item := range items {
+				ids[i] = item.ID
+			}
+			if err := tx.Model(&domain.WishlistItem{}).Where("id IN ?", ids).Update("alert_price", price).Error; err != nil {
+				return err
+			}
+		}
+		return enqueueOutbox(tx, messages)
+	})
+	if err != nil {
+		log.Printf("Failed to record %s alerts: %v", alert, err)
+	}
+	return err
+}
+
+// CountBySeller totals the wishlists each of a seller's products is on, most wished for first
+func (r *wishlistRepository) CountBySeller(sellerID uint) ([]domain.WishlistCount, error) {
+	var counts []domain.WishlistCount
+	err := r.DB.Table("wishlist_items AS w").
+		Select(`p.id AS product_id, p.name AS name, COUNT(*) AS count,
+			COUNT(*) FILTER (WHERE w.notify_price_drop) AS price_drop,
+			COUNT(*) FILTER (WHERE w.notify_back_in_stock) AS back_in_stock`).
+		Joins("JOIN products p ON p.id = w.product_id").
+		Where("p.seller_id = ?", sellerID).
+		Group("p.id, p.name").
+		Order("count DESC, p.id ASC").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
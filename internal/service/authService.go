@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuthService struct {
+	Repo     repository.TokenRepository
+	UserRepo repository.UserRepository
+	Auth     helper.Auth
+	Config   config.AppConfig
+}
+
+func NewAuthService(repo repository.TokenRepository, userRepo repository.UserRepository, auth helper.Auth, config config.AppConfig) AuthService {
+	return AuthService{
+		Repo:     repo,
+		UserRepo: userRepo,
+		Auth:     auth,
+		Config:   config,
+	}
+}
+
+// IssueTokens opens a new session for the user with a fresh access and refresh token
+func (s AuthService) IssueTokens(user *domain.User, client dto.ClientInfo) (*dto.AuthTokens, error) {
+	familyID, err := helper.RandomToken(16)
+	if err != nil {
+		return nil, errors.New("failed to start session")
+	}
+	return s.issueTokens(user, familyID, client, nil)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token. A refresh
+// token that was already exchanged means it leaked, so the whole session family is revoked.
+func (s AuthService) Refresh(refreshToken string, client dto.ClientInfo) (*dto.AuthTokens, error) {
+	current, err := s.Repo.FindRefreshTokenByHash(helper.HashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedByID != nil {
+			log.Printf("Refresh token reuse detected for user %d, revoking session family %s", current.UserID, current.FamilyID)
+			if err := s.Repo.RevokeRefreshTokenFamily(current.FamilyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	user, err := s.UserRepo.FindUserByID(current.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, current.FamilyID, client, current)
+}
+
+// Logout ends the session the access token belongs to: the access token is denied until it
+// expires and the refresh tokens of its family can no longer be exchanged
+func (s AuthService) Logout(claims helper.TokenClaims) error {
+	return s.Repo.RevokeSession(&domain.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.User.ID,
+		ExpiresAt: claims.ExpiresAt,
+	}, claims.SessionID)
+}
+
+// LogoutAll ends every session of the user on every device
+func (s AuthService) LogoutAll(userID uint) error {
+	return s.Repo.RevokeAllUserTokens(userID)
+}
+
+// CleanupExpiredTokens removes tokens past their expiry; it runs as a background job
+func (s AuthService) CleanupExpiredTokens() error {
+	deleted, err := s.Repo.DeleteExpiredTokens()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d expired tokens", deleted)
+	}
+	return nil
+}
+
+func (s AuthService) issueTokens(user *domain.User, familyID string, client dto.ClientInfo, current *domain.RefreshToken) (*dto.AuthTokens, error) {
+	accessToken, err := s.Auth.GenerateToken(user.ID, user.Email, user.UserType, user.TokenVersion, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := s.Auth.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &domain.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: refreshHash,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(s.Config.RefreshTokenTTL),
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+	}
+
+	if current == nil {
+		if _, err := s.Repo.CreateRefreshToken(next); err != nil {
+			return nil, err
+		}
+	} else {
+		rotated, err := s.Repo.RotateRefreshToken(current, next)
+		if err != nil {
+			return nil, err
+		}
+		if !rotated {
+			// lost a race with another refresh of the same token, treat it like reuse
+			if err := s.Repo.RevokeRefreshTokenFamily(familyID); err != nil {
+				return nil, err
+			}
+			return nil, domain.ErrInvalidRefreshToken
+		}
+	}
+
+	return &dto.AuthTokens{
+		Token:            accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(helper.AccessTokenTTL.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: next.ExpiresAt,
+	}, nil
+}
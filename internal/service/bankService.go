@@ -1,11 +1,11 @@
 package service
 
 import (
-	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/payment"
 )
 
 type BankService struct {
-	flutterwaveClient *flutterwave.Client
+	verifier payment.BankVerifier
 }
 
 type Bank struct {
@@ -19,21 +19,21 @@ type VerifyAccountResult struct {
 	BankCode      string `json:"bank_code"`
 }
 
-func NewBankService(flutterwaveClient *flutterwave.Client) *BankService {
+func NewBankService(verifier payment.BankVerifier) *BankService {
 	return &BankService{
-		flutterwaveClient: flutterwaveClient,
+		verifier: verifier,
 	}
 }
 
 func (s *BankService) GetBanks() ([]Bank, error) {
-	flutterwaveBanks, err := s.flutterwaveClient.GetBanks("NG")
+	providerBanks, err := s.verifier.GetBanks()
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert Flutterwave bank format to our format
-	banks := make([]Bank, len(flutterwaveBanks))
-	for i, bank := range flutterwaveBanks {
+	// Convert provider bank format to our format
+	banks := make([]Bank, len(providerBanks))
+	for i, bank := range providerBanks {
 		banks[i] = Bank{
 			Code: bank.Code,
 			Name: bank.Name,
@@ -44,14 +44,14 @@ func (s *BankService) GetBanks() ([]Bank, error) {
 }
 
 func (s *BankService) VerifyAccount(accountNumber, bankCode string) (*VerifyAccountResult, error) {
-	result, err := s.flutterwaveClient.VerifyAccount(accountNumber, bankCode)
+	result, err := s.verifier.VerifyAccount(accountNumber, bankCode)
 	if err != nil {
 		return nil, err
 	}
 
 	return &VerifyAccountResult{
-		AccountNumber: result.Data.AccountNumber,
-		AccountName:   result.Data.AccountName,
+		AccountNumber: result.AccountNumber,
+		AccountName:   result.AccountName,
 		BankCode:      bankCode,
 	}, nil
 }
@@ -1,25 +1,112 @@
 package service
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/storage"
+	"log"
+)
+
+const (
+	// maxProductImages caps the size of a product gallery
+	maxProductImages = 10
+	// thumbnailSize is the longest side, in pixels, of a gallery thumbnail
+	thumbnailSize = 320
 )
 
 type CatalogueService struct {
-	Repo   repository.CatalogueRepository
-	Auth   helper.Auth
-	Config config.AppConfig
+	Repo        repository.CatalogueRepository
+	AuditRepo   repository.AuditRepository
+	Permissions helper.PermissionFinder
+	Wishlists   WishlistService
+	Auth        helper.Auth
+	Config      config.AppConfig
+	Storage     storage.Storage
 }
 
-func NewCatalogueService(repo repository.CatalogueRepository, auth helper.Auth, config config.AppConfig) CatalogueService {
+func NewCatalogueService(repo repository.CatalogueRepository, auditRepo repository.AuditRepository, permissions helper.PermissionFinder, wishlists WishlistService, auth helper.Auth, config config.AppConfig, fileStorage storage.Storage) CatalogueService {
 	return CatalogueService{
-		Repo:   repo,
-		Auth:   auth,
-		Config: config,
+		Repo:        repo,
+		AuditRepo:   auditRepo,
+		Permissions: permissions,
+		Wishlists:   wishlists,
+		Auth:        auth,
+		Config:      config,
+		Storage:     fileStorage,
+	}
+}
+
+// authorizeChange is the catalogue's ownership policy: sellers may only change the products and
+// categories they created, while users holding PERMISSION_CATALOGUE_MODERATE may change any of
+// them. Both refusals and moderator overrides are written to the audit trail.
+func (s CatalogueService) authorizeChange(userID uint, action string, resourceType string, resourceID uint, ownerID uint) error {
+	if userID != 0 && userID == ownerID {
+		return nil
+	}
+
+	permissions, err := s.Permissions.FindPermissionsByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	outcome := domain.AUDIT_DENIED
+	for _, permission := range permissions {
+		if permission == domain.PERMISSION_CATALOGUE_MODERATE {
+			outcome = domain.AUDIT_OVERRIDE
+			break
+		}
+	}
+
+	// A failed audit write is logged by the repository and does not change the decision
+	_ = s.AuditRepo.RecordEntry(&domain.AuditEntry{
+		ActorID:      userID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		OwnerID:      ownerID,
+		Outcome:      outcome,
+	})
+
+	if outcome == domain.AUDIT_DENIED {
+		return fmt.Errorf("%w: you can only change your own %ss", domain.ErrForbidden, resourceType)
 	}
+	return nil
+}
+
+// GetAuditEntries lists the audit trail of the ownership policy
+func (s CatalogueService) GetAuditEntries(query dto.AuditQuery) (*dto.PaginatedResponse, error) {
+	entries, page, err := s.AuditRepo.FindEntries(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		result[i] = entry
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: dto.NewPaginationMeta(query.PaginationParams, page),
+	}, nil
+}
+
+// ownedCategory loads a category that the user may change under the ownership policy
+func (s CatalogueService) ownedCategory(categoryID uint, userID uint, action string) (*domain.Category, error) {
+	category, err := s.Repo.GetCategoryByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeChange(userID, action, domain.AUDIT_RESOURCE_CATEGORY, category.ID, category.SellerID); err != nil {
+		return nil, err
+	}
+	return category, nil
 }
 
 // Category methods - to be implemented
@@ -37,7 +124,7 @@ func (s CatalogueService) CreateCategory(sellerID uint, category dto.Category) (
 }
 
 func (s CatalogueService) GetCategories(query dto.CategoryQuery) (*dto.PaginatedResponse, error) {
-	categories, total, err := s.Repo.GetCategories(query)
+	categories, page, err := s.Repo.GetCategories(query)
 	if err != nil {
 		return nil, err
 	}
@@ -47,11 +134,7 @@ func (s CatalogueService) GetCategories(query dto.CategoryQuery) (*dto.Paginated
 		result[i] = category
 	}
 
-	pagination := dto.PaginationMeta{
-		Take:  query.GetLimit(),
-		Skip:  query.GetOffset(),
-		Total: total,
-	}
+	pagination := dto.NewPaginationMeta(query.PaginationParams, page)
 
 	return &dto.PaginatedResponse{
 		Data:       result,
@@ -67,7 +150,86 @@ func (s CatalogueService) GetCategoryByID(id uint) (interface{}, error) {
 	return category, nil
 }
 
-func (s CatalogueService) UpdateCategory(id uint, category dto.Category) (interface{}, error) {
+// GetCategoryTree nests the categories under rootID, or the whole catalogue when it is nil.
+// Each node's product count includes the products of every category below it.
+func (s CatalogueService) GetCategoryTree(rootID *uint) ([]*dto.CategoryNode, error) {
+	if rootID != nil {
+		if _, err := s.Repo.GetCategoryByID(*rootID); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.Repo.GetCategoryTree(rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows come parents first, so every parent is in the map before its children
+	nodes := make(map[uint]*dto.CategoryNode, len(rows))
+	ordered := make([]*dto.CategoryNode, len(rows))
+	roots := []*dto.CategoryNode{}
+	for i := range rows {
+		node := &rows[i]
+		node.Children = []*dto.CategoryNode{}
+		nodes[node.ID] = node
+		ordered[i] = node
+
+		if node.Depth == 0 {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	// Deepest first, so a child's total is complete before it is added to its parent
+	for i := len(ordered) - 1; i >= 0; i-- {
+		node := ordered[i]
+		if node.Depth == 0 {
+			continue
+		}
+		if parent, ok := nodes[*node.ParentID]; ok {
+			parent.ProductCount += node.ProductCount
+		}
+	}
+
+	return roots, nil
+}
+
+// GetBreadcrumbs returns the path from the top of the tree down to the category
+func (s CatalogueService) GetBreadcrumbs(id uint) ([]dto.Breadcrumb, error) {
+	ancestors, err := s.Repo.GetCategoryAncestors(id)
+	if err != nil {
+		return nil, err
+	}
+
+	breadcrumbs := make([]dto.Breadcrumb, len(ancestors))
+	for i, category := range ancestors {
+		breadcrumbs[i] = dto.Breadcrumb{ID: category.ID, Name: category.Name}
+	}
+	return breadcrumbs, nil
+}
+
+// CountSubtreeProducts counts the products in a category and all of its descendants
+func (s CatalogueService) CountSubtreeProducts(id uint) (int64, error) {
+	if _, err := s.Repo.GetCategoryByID(id); err != nil {
+		return 0, err
+	}
+	return s.Repo.CountSubtreeProducts(id)
+}
+
+// MoveCategory re-parents a category and everything below it
+func (s CatalogueService) MoveCategory(id uint, userID uint, request dto.MoveCategoryRequest) (*domain.Category, error) {
+	if _, err := s.ownedCategory(id, userID, "category.move"); err != nil {
+		return nil, err
+	}
+	return s.Repo.MoveCategory(id, request.ParentID)
+}
+
+func (s CatalogueService) UpdateCategory(id uint, userID uint, category dto.Category) (interface{}, error) {
+	if _, err := s.ownedCategory(id, userID, "category.update"); err != nil {
+		return nil, err
+	}
+
 	updatedCategory, err := s.Repo.UpdateCategory(id, category)
 	if err != nil {
 		return nil, err
@@ -75,17 +237,21 @@ func (s CatalogueService) UpdateCategory(id uint, category dto.Category) (interf
 	return updatedCategory, nil
 }
 
-func (s CatalogueService) DeleteCategory(id uint) error {
-	productCount, err := s.Repo.CountProductsByCategoryID(id)
-	if err != nil {
-		return err
+func (s CatalogueService) DeleteCategory(id uint, userID uint, options dto.DeleteCategoryOptions) error {
+	switch options.Children {
+	case "", dto.CATEGORY_CHILDREN_CASCADE, dto.CATEGORY_CHILDREN_REASSIGN:
+	default:
+		return fmt.Errorf("%w: children must be %s or %s", domain.ErrInvalidDeleteOption, dto.CATEGORY_CHILDREN_CASCADE, dto.CATEGORY_CHILDREN_REASSIGN)
+	}
+	if options.ReassignTo != nil && options.Children != dto.CATEGORY_CHILDREN_REASSIGN {
+		return fmt.Errorf("%w: reassign_to needs children=%s", domain.ErrInvalidDeleteOption, dto.CATEGORY_CHILDREN_REASSIGN)
 	}
 
-	if productCount > 0 {
-		return errors.New("cannot delete category: category has associated products. Please remove or reassign products before deleting the category")
+	if _, err := s.ownedCategory(id, userID, "category.delete"); err != nil {
+		return err
 	}
 
-	return s.Repo.DeleteCategory(id)
+	return s.Repo.DeleteCategory(id, options)
 }
 
 // Product methods
@@ -94,6 +260,15 @@ func (s CatalogueService) CreateProduct(sellerID uint, product dto.Product) (int
 		return nil, errors.New("seller ID is required")
 	}
 
+	if len(product.Options) > 0 || len(product.Variants) > 0 {
+		if len(product.Variants) == 0 {
+			return nil, fmt.Errorf("%w: options need at least one variant", domain.ErrInvalidVariant)
+		}
+		if err := domain.ValidateVariants(optionsFromDto(product.Options), variantsFromDto(product.Variants)); err != nil {
+			return nil, err
+		}
+	}
+
 	createdProduct, err := s.Repo.CreateProduct(sellerID, product)
 	if err != nil {
 		return nil, err
@@ -102,7 +277,7 @@ func (s CatalogueService) CreateProduct(sellerID uint, product dto.Product) (int
 }
 
 func (s CatalogueService) GetProducts(query dto.ProductQuery) (*dto.PaginatedResponse, error) {
-	products, total, err := s.Repo.GetProducts(query)
+	products, page, err := s.Repo.GetProducts(query)
 	if err != nil {
 		return nil, err
 	}
@@ -112,16 +287,20 @@ func (s CatalogueService) GetProducts(query dto.ProductQuery) (*dto.PaginatedRes
 		result[i] = product
 	}
 
-	pagination := dto.PaginationMeta{
-		Take:  query.GetLimit(),
-		Skip:  query.GetOffset(),
-		Total: total,
+	response := &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: dto.NewPaginationMeta(query.PaginationParams, page),
 	}
 
-	return &dto.PaginatedResponse{
-		Data:       result,
-		Pagination: pagination,
-	}, nil
+	if query.ShouldComputeFacets() {
+		facets, err := s.Repo.GetProductFacets(query)
+		if err != nil {
+			return nil, err
+		}
+		response.Facets = facets
+	}
+
+	return response, nil
 }
 
 func (s CatalogueService) GetProductByID(id uint) (interface{}, error) {
@@ -132,23 +311,300 @@ func (s CatalogueService) GetProductByID(id uint) (interface{}, error) {
 	return product, nil
 }
 
+// UpdateProduct changes a product and alerts the users who wished for it when it got cheaper
+// or came back in stock
 func (s CatalogueService) UpdateProduct(productID uint, sellerID uint, product dto.Product) (interface{}, error) {
-	existingProduct, err := s.Repo.GetProductByID(productID)
+	current, err := s.ownedProduct(productID, sellerID, "product.update")
 	if err != nil {
 		return nil, err
 	}
 
-	if existingProduct.SellerID != sellerID {
-		return nil, errors.New("unauthorized: you can only update your own products")
+	updatedProduct, err := s.Repo.UpdateProduct(productID, product)
+	if err != nil {
+		return nil, err
 	}
+	s.Wishlists.NotifyProductChange(*current, *updatedProduct)
+	return updatedProduct, nil
+}
 
-	updatedProduct, err := s.Repo.UpdateProduct(productID, product)
+func (s CatalogueService) DeleteProduct(id uint, userID uint) error {
+	if _, err := s.ownedProduct(id, userID, "product.delete"); err != nil {
+		return err
+	}
+
+	images, err := s.Repo.GetProductImages(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.DeleteProduct(id); err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		s.removeImageFiles(image)
+	}
+	return nil
+}
+
+// Variant methods
+
+// ownedProduct loads a product that the user may change under the ownership policy
+func (s CatalogueService) ownedProduct(productID uint, userID uint, action string) (*domain.Product, error) {
+	product, err := s.Repo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeChange(userID, action, domain.AUDIT_RESOURCE_PRODUCT, product.ID, product.SellerID); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// CreateVariant adds a SKU to a product; the product's options must already cover its attributes
+func (s CatalogueService) CreateVariant(productID uint, sellerID uint, input dto.Variant) (*domain.ProductVariant, error) {
+	product, err := s.ownedProduct(productID, sellerID, "variant.create")
+	if err != nil {
+		return nil, err
+	}
+
+	variant := domain.ProductVariant{
+		ProductID:  product.ID,
+		SKU:        input.SKU,
+		Attributes: input.Attributes,
+		Price:      input.Price,
+		Stock:      input.Stock,
+		ImageURL:   input.ImageURL,
+	}
+	if err := domain.ValidateVariants(product.Options, append(product.Variants, variant)); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.CreateVariant(&variant)
+}
+
+func (s CatalogueService) UpdateVariant(productID uint, variantID uint, sellerID uint, input dto.UpdateVariantRequest) (*domain.ProductVariant, error) {
+	product, err := s.ownedProduct(productID, sellerID, "variant.update")
 	if err != nil {
 		return nil, err
 	}
+
+	variant, err := s.Repo.GetVariantByID(productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if input.Price != nil {
+		if *input.Price <= 0 {
+			return nil, fmt.Errorf("%w: price must be greater than 0", domain.ErrInvalidVariant)
+		}
+		updates["price"] = *input.Price
+	}
+	if input.Stock != nil {
+		if *input.Stock < 0 {
+			return nil, fmt.Errorf("%w: stock cannot be negative", domain.ErrInvalidVariant)
+		}
+		updates["stock"] = *input.Stock
+	}
+	if input.ImageURL != nil {
+		updates["image_url"] = *input.ImageURL
+	}
+	if len(updates) == 0 {
+		return variant, nil
+	}
+
+	updatedVariant, err := s.Repo.UpdateVariant(variant, updates)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyWishlists(*product)
+	return updatedVariant, nil
+}
+
+func (s CatalogueService) DeleteVariant(productID uint, variantID uint, sellerID uint) error {
+	if _, err := s.ownedProduct(productID, sellerID, "variant.delete"); err != nil {
+		return err
+	}
+
+	variant, err := s.Repo.GetVariantByID(productID, variantID)
+	if err != nil {
+		return err
+	}
+	return s.Repo.DeleteVariant(variant)
+}
+
+// UpdateStock sets the stock of a product without variants, or of the SKU named in the request
+func (s CatalogueService) UpdateStock(productID uint, sellerID uint, request dto.UpdateStockRequest) (*domain.Product, error) {
+	product, err := s.ownedProduct(productID, sellerID, "stock.update")
+	if err != nil {
+		return nil, err
+	}
+
+	if request.SKU == "" {
+		if product.HasVariants {
+			return nil, fmt.Errorf("%w: stock of a product with variants is set per sku", domain.ErrInvalidVariant)
+		}
+		updatedProduct, err := s.Repo.UpdateProduct(productID, dto.Product{Stock: &request.Stock})
+		if err != nil {
+			return nil, err
+		}
+		s.Wishlists.NotifyProductChange(*product, *updatedProduct)
+		return updatedProduct, nil
+	}
+
+	variant, err := s.Repo.GetVariantBySKU(productID, request.SKU)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Repo.UpdateVariant(variant, map[string]interface{}{"stock": request.Stock}); err != nil {
+		return nil, err
+	}
+	updatedProduct, err := s.Repo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	s.Wishlists.NotifyProductChange(*product, *updatedProduct)
 	return updatedProduct, nil
 }
 
-func (s CatalogueService) DeleteProduct(id uint) error {
-	return s.Repo.DeleteProduct(id)
+// notifyWishlists compares a product with how it was before a change to one of its SKUs
+func (s CatalogueService) notifyWishlists(before domain.Product) {
+	after, err := s.Repo.GetProductByID(before.ID)
+	if err != nil {
+		log.Printf("Failed to reload product %d for wishlist alerts: %v", before.ID, err)
+		return
+	}
+	s.Wishlists.NotifyProductChange(before, *after)
+}
+
+func optionsFromDto(options []dto.ProductOption) []domain.ProductOption {
+	result := make([]domain.ProductOption, len(options))
+	for i, option := range options {
+		result[i] = domain.ProductOption{Name: option.Name, Values: option.Values}
+	}
+	return result
+}
+
+func variantsFromDto(variants []dto.Variant) []domain.ProductVariant {
+	result := make([]domain.ProductVariant, len(variants))
+	for i, variant := range variants {
+		result[i] = domain.ProductVariant{
+			SKU:        variant.SKU,
+			Attributes: variant.Attributes,
+			Price:      variant.Price,
+			Stock:      variant.Stock,
+		}
+	}
+	return result
+}
+
+// Image methods
+
+// AddProductImages stores uploaded images with their thumbnails and appends them to the gallery
+func (s CatalogueService) AddProductImages(productID uint, sellerID uint, uploads []dto.ImageUpload) ([]domain.ProductImage, error) {
+	product, err := s.ownedProduct(productID, sellerID, "image.upload")
+	if err != nil {
+		return nil, err
+	}
+	if len(product.Images)+len(uploads) > maxProductImages {
+		return nil, fmt.Errorf("%w: a product can have at most %d images", domain.ErrTooManyImages, maxProductImages)
+	}
+
+	images := make([]domain.ProductImage, 0, len(uploads))
+	for _, upload := range uploads {
+		image, err := s.storeImage(product.ID, upload)
+		if err != nil {
+			for _, stored := range images {
+				s.removeImageFiles(stored)
+			}
+			return nil, err
+		}
+		images = append(images, *image)
+	}
+
+	created, err := s.Repo.AddProductImages(product.ID, images, maxProductImages)
+	if err != nil {
+		for _, stored := range images {
+			s.removeImageFiles(stored)
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// ReorderProductImages sets the gallery order; the first image becomes the product's cover
+func (s CatalogueService) ReorderProductImages(productID uint, sellerID uint, request dto.ReorderImagesRequest) ([]domain.ProductImage, error) {
+	if _, err := s.ownedProduct(productID, sellerID, "image.reorder"); err != nil {
+		return nil, err
+	}
+	return s.Repo.ReorderProductImages(productID, request.ImageIDs)
+}
+
+func (s CatalogueService) DeleteProductImage(productID uint, imageID uint, sellerID uint) error {
+	if _, err := s.ownedProduct(productID, sellerID, "image.delete"); err != nil {
+		return err
+	}
+
+	image, err := s.Repo.GetProductImageByID(productID, imageID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.DeleteProductImage(image); err != nil {
+		return err
+	}
+
+	s.removeImageFiles(*image)
+	return nil
+}
+
+// storeImage checks the file really is a supported image, then saves it and its thumbnail
+func (s CatalogueService) storeImage(productID uint, upload dto.ImageUpload) (*domain.ProductImage, error) {
+	contentType, err := storage.DetectImageType(upload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, upload.Filename)
+	}
+
+	thumbnail, thumbnailType, err := storage.Thumbnail(upload.Data, contentType, thumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := helper.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("products/%d/%s", productID, name)
+
+	image := domain.ProductImage{
+		StorageKey:   prefix + storage.Extensions[contentType],
+		ThumbnailKey: prefix + "_thumb" + storage.Extensions[thumbnailType],
+		ContentType:  contentType,
+		Size:         int64(len(upload.Data)),
+	}
+
+	image.URL, err = s.Storage.Save(image.StorageKey, bytes.NewReader(upload.Data))
+	if err != nil {
+		log.Printf("Failed to store product image: %v", err)
+		return nil, err
+	}
+	image.ThumbnailURL, err = s.Storage.Save(image.ThumbnailKey, bytes.NewReader(thumbnail))
+	if err != nil {
+		log.Printf("Failed to store product thumbnail: %v", err)
+		s.removeImageFiles(image)
+		return nil, err
+	}
+	return &image, nil
+}
+
+// removeImageFiles deletes stored files once their rows are gone; a leftover file is only logged
+func (s CatalogueService) removeImageFiles(image domain.ProductImage) {
+	for _, key := range []string{image.StorageKey, image.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.Storage.Delete(key); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, err)
+		}
+	}
 }
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+type CouponService struct {
+	Repo          repository.CouponRepository
+	CatalogueRepo repository.CatalogueRepository
+	Permissions   helper.PermissionFinder
+}
+
+func NewCouponService(repo repository.CouponRepository, catalogueRepo repository.CatalogueRepository, permissions helper.PermissionFinder) CouponService {
+	return CouponService{
+		Repo:          repo,
+		CatalogueRepo: catalogueRepo,
+		Permissions:   permissions,
+	}
+}
+
+// managesAll reports whether the user administers promotions for the whole store. Everyone
+// else is treated as a seller managing their own coupons.
+func (s CouponService) managesAll(userID uint) (bool, error) {
+	permissions, err := s.Permissions.FindPermissionsByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if permission == domain.PERMISSION_PROMOTIONS_MANAGE {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateCoupon sets up a discount code. A seller's coupon is always limited to their own items
+// and comes off their sales; an admin's is paid for by the platform unless they say otherwise.
+func (s CouponService) CreateCoupon(userID uint, request dto.CreateCouponRequest) (*domain.Coupon, error) {
+	admin, err := s.managesAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon := &domain.Coupon{
+		Code:           domain.NormalizeCouponCode(request.Code),
+		Description:    request.Description,
+		Type:           strings.ToLower(strings.TrimSpace(request.Type)),
+		Percent:        domain.RateFromPercent(request.Percent),
+		AmountOff:      domain.MoneyFromAmount(request.AmountOff),
+		MinimumSpend:   domain.MoneyFromAmount(request.MinimumSpend),
+		SellerID:       request.SellerID,
+		ProductID:      request.ProductID,
+		CategoryID:     request.CategoryID,
+		StartsAt:       request.StartsAt,
+		EndsAt:         request.EndsAt,
+		MaxRedemptions: request.MaxRedemptions,
+		MaxPerUser:     request.MaxPerUser,
+		FundedBy:       strings.ToLower(strings.TrimSpace(request.FundedBy)),
+		Active:         true,
+		CreatedBy:      userID,
+	}
+	if !admin {
+		coupon.SellerID = &userID
+		coupon.FundedBy = domain.COUPON_FUNDED_BY_SELLER
+	} else if coupon.FundedBy == "" {
+		coupon.FundedBy = domain.COUPON_FUNDED_BY_PLATFORM
+	}
+	if err := domain.ValidateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if coupon.ProductID != nil {
+		product, err := s.CatalogueRepo.GetProductByID(*coupon.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if coupon.SellerID != nil && product.SellerID != *coupon.SellerID {
+			return nil, fmt.Errorf("%w: the product belongs to another seller", domain.ErrInvalidCoupon)
+		}
+	}
+	if coupon.CategoryID != nil {
+		if _, err := s.CatalogueRepo.GetCategoryByID(*coupon.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Repo.CreateCoupon(coupon)
+}
+
+// GetCoupons lists every coupon for promotion admins and a seller's own coupons otherwise
+func (s CouponService) GetCoupons(userID uint, query dto.CouponQuery) (*dto.PaginatedResponse, error) {
+	admin, err := s.managesAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		query.SellerID = userID
+	}
+
+	coupons, page, err := s.Repo.FindCoupons(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(coupons))
+	for i, coupon := range coupons {
+		result[i] = coupon
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: dto.NewPaginationMeta(query.PaginationParams, page),
+	}, nil
+}
+
+// GetCoupon loads a coupon the user may manage
+func (s CouponService) GetCoupon(id uint, userID uint) (*domain.Coupon, error) {
+	coupon, err := s.Repo.FindCouponByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.managesAll(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin && (coupon.SellerID == nil || *coupon.SellerID != userID) {
+		return nil, fmt.Errorf("%w: you can only manage your own coupons", domain.ErrForbidden)
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon changes what can safely change on a live coupon: its description, whether it
+// is active, when it ends and its usage caps
+func (s CouponService) UpdateCoupon(id uint, userID uint, request dto.UpdateCouponRequest) (*domain.Coupon, error) {
+	coupon, err := s.GetCoupon(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if request.Description != nil {
+		updates["description"] = *request.Description
+	}
+	if request.Active != nil {
+		updates["active"] = *request.Active
+	}
+	if request.EndsAt != nil {
+		if coupon.StartsAt != nil && !request.EndsAt.After(*coupon.StartsAt) {
+			return nil, fmt.Errorf("%w: ends_at must be after starts_at", domain.ErrInvalidCoupon)
+		}
+		updates["ends_at"] = *request.EndsAt
+	}
+	if request.MaxRedemptions != nil {
+		if *request.MaxRedemptions < 0 {
+			return nil, fmt.Errorf("%w: max_redemptions cannot be negative", domain.ErrInvalidCoupon)
+		}
+		updates["max_redemptions"] = *request.MaxRedemptions
+	}
+	if request.MaxPerUser != nil {
+		if *request.MaxPerUser < 0 {
+			return nil, fmt.Errorf("%w: max_per_user cannot be negative", domain.ErrInvalidCoupon)
+		}
+		updates["max_per_user"] = *request.MaxPerUser
+	}
+	if len(updates) == 0 {
+		return coupon, nil
+	}
+
+	return s.Repo.UpdateCoupon(coupon, updates)
+}
+
+func (s CouponService) DeleteCoupon(id uint, userID uint) error {
+	if _, err := s.GetCoupon(id, userID); err != nil {
+		return err
+	}
+	return s.Repo.DeleteCoupon(id)
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GuestCartService keeps carts for visitors who have not logged in. A visitor is known only
+// by the signed cart token handed out with their first item.
+type GuestCartService struct {
+	Repo          repository.GuestCartRepository
+	UserRepo      repository.UserRepository
+	CatalogueRepo repository.CatalogueRepository
+	Auth          helper.Auth
+	Config        config.AppConfig
+}
+
+func NewGuestCartService(repo repository.GuestCartRepository, userRepo repository.UserRepository, catalogueRepo repository.CatalogueRepository, auth helper.Auth, config config.AppConfig) GuestCartService {
+	return GuestCartService{
+		Repo:          repo,
+		UserRepo:      userRepo,
+		CatalogueRepo: catalogueRepo,
+		Auth:          auth,
+		Config:        config,
+	}
+}
+
+// findCart resolves a cart token. Forged tokens, and tokens for carts that were merged or
+// have expired, are all reported as ErrInvalidCartToken.
+func (s GuestCartService) findCart(token string) (*domain.GuestCart, error) {
+	key, err := s.Auth.VerifyCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.Repo.FindGuestCartByKey(key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrInvalidCartToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(cart.UpdatedAt) > s.Config.GuestCartTTL {
+		return nil, domain.ErrInvalidCartToken
+	}
+	return cart, nil
+}
+
+// AddItem adds a product to the guest cart, starting a new cart when there is no valid token.
+// It returns the token to use from then on.
+func (s GuestCartService) AddItem(token string, request dto.CreateCartRequest) (string, *domain.Cart, error) {
+	if request.Quantity < 1 {
+		return "", nil, domain.ErrInvalidQuantity
+	}
+
+	cartItem, err := newCartItem(s.CatalogueRepo, request)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// a visitor whose cart has expired simply starts a new one
+	var cart *domain.GuestCart
+	if token != "" {
+		cart, err = s.findCart(token)
+		if err != nil && !errors.Is(err, domain.ErrInvalidCartToken) {
+			return "", nil, err
+		}
+	}
+	if cart == nil {
+		key, newToken, err := s.Auth.GenerateCartToken()
+		if err != nil {
+			return "", nil, err
+		}
+		if cart, err = s.Repo.CreateGuestCart(key); err != nil {
+			return "", nil, err
+		}
+		token = newToken
+	}
+
+	existing, err := s.Repo.FindGuestCartItem(cart.ID, request.ProductID, request.VariantID)
+	if err == nil {
+		existing.Quantity += request.Quantity
+		cartItem = existing
+	} else {
+		cartItem.GuestCartID = &cart.ID
+	}
+
+	savedItem, err := s.Repo.SaveGuestCartItem(cartItem)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, savedItem, nil
+}
+
+// GetCart prices the guest cart like a user's cart, without changing it: lines that can no
+// longer be bought are returned separately and price changes are warned about until acknowledged
+func (s GuestCartService) GetCart(token string) ([]domain.CartLine, []domain.CartLine, error) {
+	cart, err := s.findCart(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cartItems, err := s.Repo.FindGuestCartItems(cart.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines, _, err := priceCart(s.CatalogueRepo, cartItems)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, unavailable := splitUnavailable(lines)
+	return current, unavailable, nil
+}
+
+// AcknowledgeChanges takes the current prices into the guest cart and removes the lines that
+// can no longer be bought, like UserService.AcknowledgeCartChanges
+func (s GuestCartService) AcknowledgeChanges(token string) ([]domain.CartLine, error) {
+	cart, err := s.findCart(token)
+	if err != nil {
+		return nil, err
+	}
+
+	cartItems, err := s.Repo.FindGuestCartItems(cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, _, err := priceCart(s.CatalogueRepo, cartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, line := range lines {
+		if line.Unavailable() {
+			if err := s.Repo.DeleteGuestCartItem(cart.ID, line.ProductID, line.VariantID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if line.Price != cartItems[i].Price {
+			if _, err := s.Repo.SaveGuestCartItem(&line.Cart); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	current, _, err := s.GetCart(token)
+	return current, err
+}
+
+func (s GuestCartService) UpdateItem(token string, request dto.UpdateCartRequest) (*domain.Cart, error) {
+	if request.ProductID == nil {
+		return nil, errors.New("product ID is required")
+	}
+
+	cart, err := s.findCart(token)
+	if err != nil {
+		return nil, err
+	}
+
+	cartItem, err := s.Repo.FindGuestCartItem(cart.ID, *request.ProductID, request.VariantID)
+	if err != nil {
+		return nil, errors.New("cart item not found")
+	}
+
+	if request.Quantity != nil {
+		if *request.Quantity < 1 {
+			return nil, domain.ErrInvalidQuantity
+		}
+		cartItem.Quantity = *request.Quantity
+	}
+
+	return s.Repo.SaveGuestCartItem(cartItem)
+}
+
+func (s GuestCartService) DeleteItem(token string, productID uint, variantID *uint) error {
+	cart, err := s.findCart(token)
+	if err != nil {
+		return err
+	}
+	return s.Repo.DeleteGuestCartItem(cart.ID, productID, variantID)
+}
+
+// MergeIntoUser moves a guest cart into the user's cart once they log in or register, and
+// deletes the guest cart. Quantities of the same product and SKU are added together, but not
+// past the stock left; lines that cannot be bought are left out. The lines that did not merge
+// in full are returned with the reason in their warnings.
+func (s GuestCartService) MergeIntoUser(token string, userID uint) ([]domain.CartLine, error) {
+	cart, err := s.findCart(token)
+	if err != nil {
+		return nil, err
+	}
+
+	guestItems, err := s.Repo.FindGuestCartItems(cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	userItems, err := s.UserRepo.FindCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]uint, 0, len(guestItems))
+	for _, item := range guestItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	products, err := s.CatalogueRepo.GetProductsByIDs(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	productsByID := make(map[uint]*domain.Product, len(products))
+	for i := range products {
+		productsByID[products[i].ID] = &products[i]
+	}
+
+	owned := make(map[string]domain.Cart, len(userItems))
+	for _, item := range userItems {
+		owned[cartLineKey(item)] = item
+	}
+
+	merged := []domain.Cart{}
+	adjusted := []domain.CartLine{}
+	for _, item := range guestItems {
+		line := item
+		line.ID = 0
+		ownedQuantity := 0
+		if existing, ok := owned[cartLineKey(item)]; ok {
+			line = existing
+			ownedQuantity = existing.Quantity
+			line.Quantity = existing.Quantity + item.Quantity
+		}
+
+		// the line keeps the price it was added at, the user's cart warns about a new one
+		priced := domain.PriceCartLine(line, productsByID[item.ProductID])
+		if priced.Unavailable() || priced.Warning(domain.CART_OUT_OF_STOCK) != nil {
+			adjusted = append(adjusted, priced)
+			continue
+		}
+		if short := priced.Warning(domain.CART_INSUFFICIENT_STOCK); short != nil {
+			adjusted = append(adjusted, priced)
+			// stock only limits what the guest cart adds, the user's own quantity is kept
+			line.Quantity = max(*short.Available, ownedQuantity)
+			if line.Quantity == ownedQuantity {
+				continue
+			}
+		}
+		merged = append(merged, line)
+	}
+
+	if err := s.Repo.MergeGuestCart(cart.ID, userID, merged); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidCartToken
+		}
+		return nil, err
+	}
+	return adjusted, nil
+}
+
+// CleanupExpiredCarts deletes guest carts left untouched for longer than the configured TTL
+func (s GuestCartService) CleanupExpiredCarts() error {
+	deleted, err := s.Repo.DeleteExpiredGuestCarts(time.Now().Add(-s.Config.GuestCartTTL))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d expired guest carts", deleted)
+	}
+	return nil
+}
+
+func cartLineKey(item domain.Cart) string {
+	if item.VariantID == nil {
+		return strconv.FormatUint(uint64(item.ProductID), 10)
+	}
+	return strconv.FormatUint(uint64(item.ProductID), 10) + ":" + strconv.FormatUint(uint64(*item.VariantID), 10)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/notification"
+	"log"
+	"strings"
+)
+
+// NotificationService renders templated messages for the channel that suits each user. The
+// messages are queued in the outbox with the change they report and delivered by OutboxService.
+type NotificationService struct {
+	Client   notification.NotificationClient
+	UserRepo repository.UserRepository
+}
+
+func NewNotificationService(client notification.NotificationClient, userRepo repository.UserRepository) NotificationService {
+	return NotificationService{
+		Client:   client,
+		UserRepo: userRepo,
+	}
+}
+
+// CanSend reports whether channel is configured and the user has the contact detail for it
+func (s NotificationService) CanSend(user *domain.User, channel string) bool {
+	switch channel {
+	case domain.CHANNEL_EMAIL:
+		return s.Client != nil && s.Client.EmailEnabled() && user.Email != ""
+	case domain.CHANNEL_SMS:
+		return s.Client != nil && user.Phone != ""
+	}
+	return false
+}
+
+// VerifiedChannelFor picks the user's preferred channel when its contact detail is verified,
+// then the other one. One-time codes are only ever sent this way.
+func (s NotificationService) VerifiedChannelFor(user *domain.User) (string, bool) {
+	for _, channel := range channelPreference(user) {
+		if s.CanSend(user, channel) && user.ContactVerified(channel) {
+			return channel, true
+		}
+	}
+	return "", false
+}
+
+// ChannelFor picks a verified channel like VerifiedChannelFor and only then falls back to an
+// unverified one, which is good enough for notices that carry no secret
+func (s NotificationService) ChannelFor(user *domain.User) string {
+	if channel, ok := s.VerifiedChannelFor(user); ok {
+		return channel
+	}
+	channels := channelPreference(user)
+	if s.CanSend(user, channels[0]) {
+		return channels[0]
+	}
+	return channels[1]
+}
+
+// channelPreference lists the user's preferred channel first
+func channelPreference(user *domain.User) []string {
+	if user.NotificationChannel == domain.CHANNEL_EMAIL {
+		return []string{domain.CHANNEL_EMAIL, domain.CHANNEL_SMS}
+	}
+	return []string{domain.CHANNEL_SMS, domain.CHANNEL_EMAIL}
+}
+
+// Compose renders template for the user into a message for the outbox, addressed on channel
+func (s NotificationService) Compose(user *domain.User, channel string, template string, data interface{}) (*domain.OutboxMessage, error) {
+	message, err := notification.Render(template, data)
+	if err != nil {
+		return nil, err
+	}
+
+	outboxMessage := &domain.OutboxMessage{
+		UserID:   user.ID,
+		Channel:  channel,
+		Template: template,
+	}
+	switch channel {
+	case domain.CHANNEL_EMAIL:
+		outboxMessage.Recipient = user.Email
+		outboxMessage.Subject = message.Subject
+		outboxMessage.Body = message.Body
+	case domain.CHANNEL_SMS:
+		outboxMessage.Recipient = helper.FormatPhoneToE164(user.Phone)
+		outboxMessage.Body = message.SMS
+	default:
+		return nil, fmt.Errorf("unknown notification channel %q", channel)
+	}
+
+	return outboxMessage, nil
+}
+
+// ComposeForUser addresses template to a user on the channel chosen by ChannelFor. A notice that
+// cannot be composed is logged and left out, it never blocks the change it reports.
+func (s NotificationService) ComposeForUser(userID uint, template string, data func(user *domain.User) interface{}) []domain.OutboxMessage {
+	user, err := s.UserRepo.FindUserByID(userID)
+	if err != nil {
+		log.Printf("Failed to load user %d for %s notification: %v", userID, template, err)
+		return nil
+	}
+
+	message, err := s.Compose(user, s.ChannelFor(user), template, data(user))
+	if err != nil {
+		log.Printf("Failed to compose %s notification for user %d: %v", template, userID, err)
+		return nil
+	}
+	return []domain.OutboxMessage{*message}
+}
+
+func (s NotificationService) OrderConfirmationMessages(order *domain.Order, currency string) []domain.OutboxMessage {
+	items := []notification.OrderLine{}
+	for _, sellerOrder := range order.SellerOrders {
+		items = append(items, orderLines(sellerOrder.Items)...)
+	}
+
+	return s.ComposeForUser(order.UserID, notification.TemplateOrderConfirmation, func(user *domain.User) interface{} {
+		return notification.OrderConfirmationData{
+			Name:     user.FirstName,
+			OrderRef: order.OrderRef,
+			Total:    order.TotalAmount.Amount(),
+			Currency: currency,
+			Items:    items,
+		}
+	})
+}
+
+// ShippingUpdateMessages tells the buyer that a seller's part of their order moved to status
+func (s NotificationService) ShippingUpdateMessages(order *domain.Order, sellerOrder *domain.SellerOrder, status string) []domain.OutboxMessage {
+	return s.ComposeForUser(order.UserID, notification.TemplateShippingUpdate, func(user *domain.User) interface{} {
+		return notification.ShippingUpdateData{
+			Name:     user.FirstName,
+			OrderRef: order.OrderRef,
+			Status:   status,
+			Items:    orderLines(sellerOrder.Items),
+		}
+	})
+}
+
+func (s NotificationService) PayoutReceiptMessages(payout *domain.Payout) []domain.OutboxMessage {
+	return s.ComposeForUser(payout.SellerID, notification.TemplatePayoutReceipt, func(user *domain.User) interface{} {
+		return notification.PayoutReceiptData{
+			Name:          user.FirstName,
+			Reference:     payout.Reference,
+			Amount:        payout.Amount,
+			Currency:      payout.Currency,
+			AccountEnding: accountEnding(payout.BankAccountNumber),
+		}
+	})
+}
+
+func orderLines(items []domain.OrderItem) []notification.OrderLine {
+	lines := make([]notification.OrderLine, len(items))
+	for i, item := range items {
+		lines[i] = notification.OrderLine{
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    item.Price.Amount(),
+		}
+	}
+	return lines
+}
+
+// accountEnding keeps only the last four digits of a bank account number
+func accountEnding(accountNumber string) string {
+	accountNumber = strings.TrimSpace(accountNumber)
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return accountNumber[len(accountNumber)-4:]
+}
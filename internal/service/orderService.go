@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"log"
+)
+
+// sellerOrderStatuses are the statuses a seller may set; paid is only set by payment confirmation
+// and refunded only by a refund that went through the return flow. Cancelling is only allowed
+// while the sub-order is unpaid, see domain.CanTransitionOrder.
+var sellerOrderStatuses = map[string]bool{
+	domain.ORDER_PROCESSING: true,
+	domain.ORDER_SHIPPED:    true,
+	domain.ORDER_DELIVERED:  true,
+	domain.ORDER_CANCELLED:  true,
+}
+
+type OrderService struct {
+	Repo     repository.OrderRepository
+	Auth     helper.Auth
+	Config   config.AppConfig
+	Notifier NotificationService
+}
+
+func NewOrderService(repo repository.OrderRepository, auth helper.Auth, config config.AppConfig, notifier NotificationService) OrderService {
+	return OrderService{
+		Repo:     repo,
+		Auth:     auth,
+		Config:   config,
+		Notifier: notifier,
+	}
+}
+
+func (s OrderService) GetSellerOrders(sellerID uint, query dto.OrderQuery) (*dto.PaginatedResponse, error) {
+	sellerOrders, page, err := s.Repo.FindSellerOrdersBySellerID(sellerID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(sellerOrders))
+	for i, sellerOrder := range sellerOrders {
+		result[i] = sellerOrder
+	}
+
+	pagination := dto.NewPaginationMeta(query.PaginationParams, page)
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: pagination,
+	}, nil
+}
+
+// GetAllOrders lists orders across every buyer, for staff with access to all orders
+func (s OrderService) GetAllOrders(query dto.OrderQuery) (*dto.PaginatedResponse, error) {
+	orders, page, err := s.Repo.FindAllOrders(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(orders))
+	for i, order := range orders {
+		result[i] = order
+	}
+
+	pagination := dto.NewPaginationMeta(query.PaginationParams, page)
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: pagination,
+	}, nil
+}
+
+func (s OrderService) GetOrder(id uint) (*domain.Order, error) {
+	return s.Repo.FindOrderByID(id)
+}
+
+func (s OrderService) GetSellerOrder(sellerID uint, id uint) (*domain.SellerOrder, error) {
+	sellerOrder, err := s.Repo.FindSellerOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sellerOrder.SellerID != sellerID {
+		return nil, domain.ErrForbidden
+	}
+
+	return sellerOrder, nil
+}
+
+func (s OrderService) UpdateOrderStatus(sellerID uint, id uint, request dto.UpdateOrderStatusRequest) (*domain.SellerOrder, error) {
+	if !sellerOrderStatuses[request.Status] {
+		return nil, fmt.Errorf("%w: sellers cannot set status %q", domain.ErrInvalidTransition, request.Status)
+	}
+
+	sellerOrder, err := s.GetSellerOrder(sellerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !domain.CanTransitionOrder(sellerOrder.Status, request.Status) {
+		return nil, fmt.Errorf("%w: cannot move order from %s to %s", domain.ErrInvalidTransition, sellerOrder.Status, request.Status)
+	}
+
+	// delivery releases the sale from clearing into the seller's balance
+	var entries []domain.LedgerEntry
+	if request.Status == domain.ORDER_DELIVERED {
+		entries = domain.SaleSettledEntries(sellerOrder, s.Config.CommissionPercent)
+	}
+
+	// the buyer hears about shipping progress in the same transaction as the change
+	var messages []domain.OutboxMessage
+	if request.Status == domain.ORDER_SHIPPED || request.Status == domain.ORDER_DELIVERED {
+		order, err := s.Repo.FindOrderByID(sellerOrder.OrderID)
+		if err != nil {
+			log.Printf("Failed to load order %d for shipping update: %v", sellerOrder.OrderID, err)
+		} else {
+			messages = s.Notifier.ShippingUpdateMessages(order, sellerOrder, request.Status)
+		}
+	}
+
+	return s.Repo.UpdateSellerOrderStatus(sellerOrder, request.Status, sellerID, request.Note, entries, messages)
+}
+
+// CancelOrder lets a buyer call off their own order while it is still awaiting payment
+func (s OrderService) CancelOrder(userID uint, id uint) (*domain.Order, error) {
+	order, err := s.Repo.FindOrderByUserIDAndID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != domain.ORDER_PENDING {
+		return nil, fmt.Errorf("%w: only pending orders can be cancelled, order is %s", domain.ErrInvalidTransition, order.Status)
+	}
+
+	return s.Repo.UpdateOrderStatus(order, domain.ORDER_CANCELLED, userID, "cancelled by buyer")
+}
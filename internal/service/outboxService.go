@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/notification"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// outboxBatchSize is how many messages a worker claims at a time
+	outboxBatchSize = 50
+	// outboxLease hides a claimed message from other workers while it is being delivered
+	outboxLease = 2 * time.Minute
+	// outboxLeaseRenewal is how often the leases of a batch still being delivered are extended
+	outboxLeaseRenewal = outboxLease / 4
+	// outboxRetryBase is the delay before the first retry, doubled for every attempt after it
+	outboxRetryBase = 30 * time.Second
+	// outboxRetryMax caps the delay between retries
+	outboxRetryMax = time.Hour
+)
+
+// OutboxService delivers queued notifications through the NotificationClient in the background
+type OutboxService struct {
+	Repo   repository.OutboxRepository
+	Client notification.NotificationClient
+	Config config.AppConfig
+}
+
+func NewOutboxService(repo repository.OutboxRepository, client notification.NotificationClient, config config.AppConfig) OutboxService {
+	return OutboxService{
+		Repo:   repo,
+		Client: client,
+		Config: config,
+	}
+}
+
+// RetryDelay is the exponential backoff after a message's nth failed attempt
+func RetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxRetryMax {
+			return outboxRetryMax
+		}
+	}
+	return delay
+}
+
+// DeliverDue sends every message that is due, batch by batch, until the queue is drained
+func (s OutboxService) DeliverDue() error {
+	for {
+		messages, err := s.Repo.ClaimDueMessages(outboxBatchSize, outboxLease)
+		if err != nil {
+			return err
+		}
+
+		leases := keepLeases(s.Repo, messages)
+		for i := range messages {
+			s.deliver(&messages[i], leases)
+		}
+		leases.stop()
+
+		if len(messages) < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver sends one message and records the outcome. The lease is released before the outcome
+// is written, so a renewal running at the same time cannot overwrite a retry schedule.
+func (s OutboxService) deliver(message *domain.OutboxMessage, leases *leaseKeeper) {
+	if message.ExpiresAt != nil && message.ExpiresAt.Before(time.Now()) {
+		leases.release(message.ID)
+		if err := s.Repo.MarkDead(message, "expired before it could be delivered"); err != nil {
+			log.Printf("Failed to expire outbox message %d: %v", message.ID, err)
+		}
+		return
+	}
+
+	err := s.send(message)
+	leases.release(message.ID)
+	if err == nil {
+		if err := s.Repo.MarkSent(message); err != nil {
+			log.Printf("Failed to mark outbox message %d as sent: %v", message.ID, err)
+		}
+		return
+	}
+
+	if message.Attempts >= s.Config.NotificationMaxAttempts {
+		log.Printf("Outbox message %d failed %d times, moving it to dead letters: %v", message.ID, message.Attempts, err)
+		if err := s.Repo.MarkDead(message, err.Error()); err != nil {
+			log.Printf("Failed to mark outbox message %d as dead: %v", message.ID, err)
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(RetryDelay(message.Attempts))
+	log.Printf("Outbox message %d failed, retrying at %s: %v", message.ID, nextAttemptAt.Format(time.RFC3339), err)
+	if err := s.Repo.ScheduleRetry(message, nextAttemptAt, err.Error()); err != nil {
+		log.Printf("Failed to schedule retry of outbox message %d: %v", message.ID, err)
+	}
+}
+
+// leaseKeeper extends the leases of a claimed batch until each message is delivered, so a slow
+// send neither lets another worker claim the messages still waiting behind it nor the one
+// being sent, which would deliver them twice
+type leaseKeeper struct {
+	repo    repository.OutboxRepository
+	mu      sync.Mutex
+	held    map[uint]bool
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func keepLeases(repo repository.OutboxRepository, messages []domain.OutboxMessage) *leaseKeeper {
+	k := &leaseKeeper{
+		repo:    repo,
+		held:    make(map[uint]bool, len(messages)),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for _, message := range messages {
+		k.held[message.ID] = true
+	}
+	go k.run()
+	return k
+}
+
+func (k *leaseKeeper) run() {
+	defer close(k.stopped)
+	ticker := time.NewTicker(outboxLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.done:
+			return
+		case <-ticker.C:
+			k.renew()
+		}
+	}
+}
+
+func (k *leaseKeeper) renew() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	ids := make([]uint, 0, len(k.held))
+	for id := range k.held {
+		ids = append(ids, id)
+	}
+	if err := k.repo.ExtendLease(ids, time.Now().Add(outboxLease)); err != nil {
+		log.Printf("Failed to extend outbox leases: %v", err)
+	}
+}
+
+// release stops renewing a message's lease, waiting for a renewal in progress to finish
+func (k *leaseKeeper) release(id uint) {
+	k.mu.Lock()
+	delete(k.held, id)
+	k.mu.Unlock()
+}
+
+func (k *leaseKeeper) stop() {
+	close(k.done)
+	<-k.stopped
+}
+
+func (s OutboxService) send(message *domain.OutboxMessage) error {
+	if s.Client == nil {
+		return errors.New("notifications are not configured")
+	}
+
+	switch message.Channel {
+	case domain.CHANNEL_EMAIL:
+		return s.Client.SendEmail(message.Recipient, message.Subject, message.Body)
+	case domain.CHANNEL_SMS:
+		return s.Client.SendSMS(message.Recipient, message.Body)
+	}
+	return errors.New("unknown notification channel " + message.Channel)
+}
+
+func (s OutboxService) GetMessages(query dto.OutboxQuery) (*dto.PaginatedResponse, error) {
+	messages, total, err := s.Repo.FindMessages(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(messages))
+	for i, message := range messages {
+		result[i] = message.Redacted()
+	}
+
+	pagination := dto.PaginationMeta{
+		Take:  query.GetLimit(),
+		Skip:  query.GetOffset(),
+		Total: &total,
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: pagination,
+	}, nil
+}
+
+func (s OutboxService) GetMessage(id uint) (*domain.OutboxMessage, error) {
+	message, err := s.Repo.FindMessageByID(id)
+	if err != nil {
+		return nil, err
+	}
+	redacted := message.Redacted()
+	return &redacted, nil
+}
+
+// ReplayMessage queues a dead message again; the worker picks it up on its next run
+func (s OutboxService) ReplayMessage(id uint) (*domain.OutboxMessage, error) {
+	message, err := s.Repo.ReplayMessage(id)
+	if err != nil {
+		return nil, err
+	}
+	redacted := message.Redacted()
+	return &redacted, nil
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/payment"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrPaymentServiceUnavailable = errors.New("payment service is not available")
+
+type PaymentService struct {
+	Repo      repository.PaymentRepository
+	OrderRepo repository.OrderRepository
+	UserRepo  repository.UserRepository
+	Config    config.AppConfig
+	Notifier  NotificationService
+	provider  payment.PaymentProvider
+}
+
+func NewPaymentService(repo repository.PaymentRepository, orderRepo repository.OrderRepository, userRepo repository.UserRepository, config config.AppConfig, notifier NotificationService, provider payment.PaymentProvider) PaymentService {
+	return PaymentService{
+		Repo:      repo,
+		OrderRepo: orderRepo,
+		UserRepo:  userRepo,
+		Config:    config,
+		Notifier:  notifier,
+		provider:  provider,
+	}
+}
+
+// InitiatePayment opens a hosted checkout for a pending order and returns the payment with its
+// link. The order's open payment is handed back while it is still for the order's total, so
+// opening checkout twice does not give the buyer two links to pay.
+func (s PaymentService) InitiatePayment(userID uint, orderID uint) (*domain.Payment, error) {
+	if s.provider == nil {
+		return nil, ErrPaymentServiceUnavailable
+	}
+
+	order, err := s.OrderRepo.FindOrderByUserIDAndID(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != domain.ORDER_PENDING {
+		return nil, domain.ErrOrderNotPayable
+	}
+
+	open, err := s.Repo.FindPendingPaymentByOrderID(order.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if open != nil && open.PaymentLink != "" && open.Provider == s.provider.Name() &&
+		domain.MoneyFromAmount(open.Amount) == order.TotalAmount {
+		return open, nil
+	}
+
+	user, err := s.UserRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	txRef, err := helper.GenerateReference("PAY")
+	if err != nil {
+		return nil, errors.New("failed to generate payment reference")
+	}
+
+	createdPayment, err := s.Repo.CreatePayment(&domain.Payment{
+		OrderID:  order.ID,
+		UserID:   userID,
+		TxRef:    txRef,
+		Provider: s.provider.Name(),
+		Amount:   order.TotalAmount.Amount(),
+		Currency: s.Config.PaymentCurrency,
+		Status:   domain.PAYMENT_PENDING,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.provider.InitiatePayment(payment.PaymentRequest{
+		Reference:   createdPayment.TxRef,
+		Amount:      createdPayment.Amount,
+		Currency:    createdPayment.Currency,
+		RedirectURL: s.Config.PaymentRedirectURL,
+		Title:       "Order " + order.OrderRef,
+		Description: fmt.Sprintf("Payment for order %s", order.OrderRef),
+		Customer: payment.Customer{
+			Email: user.Email,
+			Phone: user.Phone,
+			Name:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+		},
+		Meta: map[string]interface{}{
+			"order_id": order.ID,
+		},
+	})
+	if err != nil {
+		if failErr := s.Repo.MarkPaymentFailed(createdPayment, ""); failErr != nil {
+			log.Printf("Failed to mark payment %s as failed: %v", createdPayment.TxRef, failErr)
+		}
+		return nil, errors.New("failed to initiate payment: " + err.Error())
+	}
+
+	createdPayment.PaymentLink = session.Link
+	if err := s.Repo.UpdatePaymentLink(createdPayment.ID, createdPayment.PaymentLink); err != nil {
+		return nil, err
+	}
+
+	return createdPayment, nil
+}
+
+// HandleFlutterwaveWebhook confirms charge.completed events; other events are acknowledged without effect
+func (s PaymentService) HandleFlutterwaveWebhook(event flutterwave.WebhookEvent) error {
+	if event.Event != flutterwave.EventChargeCompleted {
+		log.Printf("Ignoring Flutterwave webhook event %q", event.Event)
+		return nil
+	}
+
+	// a success event may be for a retry under a reference an earlier attempt failed on
+	recheckFailed := strings.EqualFold(event.Data.Status, "successful")
+	_, err := s.confirmPayment(event.Data.TxRef, recheckFailed)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Ignoring Flutterwave webhook for unknown tx_ref %q", event.Data.TxRef)
+		return nil
+	}
+	return err
+}
+
+// VerifyPayment lets a buyer returning from the hosted checkout confirm their own payment
+func (s PaymentService) VerifyPayment(userID uint, txRef string) (*domain.Payment, error) {
+	existingPayment, err := s.Repo.FindPaymentByTxRef(txRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingPayment.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+
+	return s.ConfirmPayment(txRef)
+}
+
+// ConfirmPayment asks the provider for the authoritative state of a payment before marking
+// the order paid. Payments that were already settled are returned unchanged, so replays are a no-op.
+func (s PaymentService) ConfirmPayment(txRef string) (*domain.Payment, error) {
+	return s.confirmPayment(txRef, false)
+}
+
+// confirmPayment does the work of ConfirmPayment. With recheckFailed a payment already marked
+// failed is verified again, since the buyer may have retried under the same reference and paid.
+// Cancelled payments are always verified, as the buyer may still have paid their link. Whether
+// the money is applied to the order is decided against the order's current status and total.
+func (s PaymentService) confirmPayment(txRef string, recheckFailed bool) (*domain.Payment, error) {
+	if s.provider == nil {
+		return nil, ErrPaymentServiceUnavailable
+	}
+
+	existingPayment, err := s.Repo.FindPaymentByTxRef(txRef)
+	if err != nil {
+		return nil, err
+	}
+
+	recheck := (recheckFailed && existingPayment.Status == domain.PAYMENT_FAILED) || existingPayment.Status == domain.PAYMENT_CANCELLED
+	if existingPayment.Status != domain.PAYMENT_PENDING && !recheck {
+		log.Printf("Payment %s already %s, skipping confirmation", existingPayment.TxRef, existingPayment.Status)
+		return existingPayment, nil
+	}
+
+	// never trust the caller alone, ask the provider for the transaction state
+	transaction, err := s.provider.VerifyTransaction(existingPayment.TxRef)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case transaction.Status == payment.TransactionPending:
+		return existingPayment, nil
+	case transaction.Status != payment.TransactionSuccessful:
+		log.Printf("Payment %s reported as %s", existingPayment.TxRef, transaction.Status)
+		if err := s.Repo.MarkPaymentFailed(existingPayment, transaction.ID); err != nil {
+			return nil, err
+		}
+	case transaction.Reference != existingPayment.TxRef ||
+		!strings.EqualFold(transaction.Currency, existingPayment.Currency) ||
+		transaction.Amount < existingPayment.Amount:
+		log.Printf("Payment %s does not match verified transaction %s: amount %.2f %s", existingPayment.TxRef, transaction.ID, transaction.Amount, transaction.Currency)
+		if err := s.Repo.MarkPaymentFailed(existingPayment, transaction.ID); err != nil {
+			return nil, err
+		}
+	default:
+		applied, err := s.Repo.MarkPaymentSuccessful(existingPayment, transaction.ID, s.orderConfirmationMessages(existingPayment))
+		if err != nil {
+			return nil, err
+		}
+		if applied {
+			log.Printf("Payment %s confirmed for order %d", existingPayment.TxRef, existingPayment.OrderID)
+		}
+	}
+
+	confirmed, err := s.Repo.FindPaymentByTxRef(txRef)
+	if err != nil {
+		return nil, err
+	}
+	if confirmed.Status == domain.PAYMENT_UNAPPLIED {
+		if err := s.refundUnapplied(confirmed); err != nil {
+			log.Printf("Failed to refund unapplied payment %s: %v", confirmed.TxRef, err)
+		}
+		return s.Repo.FindPaymentByTxRef(txRef)
+	}
+	return confirmed, nil
+}
+
+// RefundUnappliedPayments sends back money captured for orders that could no longer take it,
+// retrying the refunds that failed or had an unknown outcome when the payment was confirmed
+func (s PaymentService) RefundUnappliedPayments() error {
+	if s.provider == nil {
+		return ErrPaymentServiceUnavailable
+	}
+
+	payments, err := s.Repo.FindUnappliedPayments()
+	if err != nil {
+		return err
+	}
+
+	for i := range payments {
+		if err := s.refundUnapplied(&payments[i]); err != nil {
+			log.Printf("Failed to refund unapplied payment %s: %v", payments[i].TxRef, err)
+		}
+	}
+	return nil
+}
+
+// refundUnapplied refunds an unapplied payment in full. The provider is asked for an earlier
+// refund under the same reference first, so a refund whose outcome was unknown is not sent twice.
+// A payment whose refund is declined stays unapplied for someone to look at.
+func (s PaymentService) refundUnapplied(unapplied *domain.Payment) error {
+	request := payment.RefundRequest{
+		TransactionID: unapplied.ProviderTxID,
+		Reference:     unapplied.RefundReference(),
+		Amount:        unapplied.Amount,
+		Currency:      unapplied.Currency,
+	}
+
+	refund, err := s.provider.FindRefund(request)
+	if errors.Is(err, payment.ErrNotFound) {
+		refund, err = s.provider.Refund(request)
+	}
+	if err != nil {
+		return err
+	}
+	if refund.Status == payment.TransactionFailed {
+		return fmt.Errorf("%w: %s", payment.ErrDeclined, refund.Message)
+	}
+
+	log.Printf("Unapplied payment %s refunded to the buyer", unapplied.TxRef)
+	return s.Repo.MarkPaymentRefunded(unapplied, refund.ID)
+}
+
+// orderConfirmationMessages are only queued if the payment is marked successful by this call
+func (s PaymentService) orderConfirmationMessages(confirmed *domain.Payment) []domain.OutboxMessage {
+	order, err := s.OrderRepo.FindOrderByID(confirmed.OrderID)
+	if err != nil {
+		log.Printf("Failed to load order %d for confirmation: %v", confirmed.OrderID, err)
+		return nil
+	}
+	return s.Notifier.OrderConfirmationMessages(order, confirmed.Currency)
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/payment"
+
+	"gorm.io/gorm"
+)
+
+// memoryPaymentRepository keeps payments in a map and applies the same status guards as the
+// database repository
+type memoryPaymentRepository struct {
+	payments map[string]*domain.Payment
+}
+
+func (r *memoryPaymentRepository) CreatePayment(p *domain.Payment) (*domain.Payment, error) {
+	r.payments[p.TxRef] = p
+	return p, nil
+}
+
+func (r *memoryPaymentRepository) FindPaymentByTxRef(txRef string) (*domain.Payment, error) {
+	p, ok := r.payments[txRef]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *p
+	return &found, nil
+}
+
+func (r *memoryPaymentRepository) FindSuccessfulPaymentByOrderID(orderID uint) (*domain.Payment, error) {
+	for _, p := range r.payments {
+		if p.OrderID == orderID && p.Status == domain.PAYMENT_SUCCESSFUL {
+			return p, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryPaymentRepository) FindPendingPaymentByOrderID(orderID uint) (*domain.Payment, error) {
+	for _, p := range r.payments {
+		if p.OrderID == orderID && p.Status == domain.PAYMENT_PENDING {
+			return p, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryPaymentRepository) FindUnappliedPayments() ([]domain.Payment, error) {
+	var unapplied []domain.Payment
+	for _, p := range r.payments {
+		if p.Status == domain.PAYMENT_UNAPPLIED {
+			unapplied = append(unapplied, *p)
+		}
+	}
+	return unapplied, nil
+}
+
+func (r *memoryPaymentRepository) UpdatePaymentLink(id uint, link string) error {
+	return nil
+}
+
+func (r *memoryPaymentRepository) MarkPaymentSuccessful(p *domain.Payment, providerTxID string, messages []domain.OutboxMessage) (bool, error) {
+	stored := r.payments[p.TxRef]
+	switch stored.Status {
+	case domain.PAYMENT_PENDING, domain.PAYMENT_FAILED:
+	case domain.PAYMENT_CANCELLED:
+		stored.Status = domain.PAYMENT_UNAPPLIED
+		stored.ProviderTxID = providerTxID
+		return false, nil
+	default:
+		return false, nil
+	}
+	stored.Status = domain.PAYMENT_SUCCESSFUL
+	stored.ProviderTxID = providerTxID
+	return true, nil
+}
+
+func (r *memoryPaymentRepository) MarkPaymentFailed(p *domain.Payment, providerTxID string) error {
+	stored := r.payments[p.TxRef]
+	if stored.Status == domain.PAYMENT_PENDING {
+		stored.Status = domain.PAYMENT_FAILED
+		stored.ProviderTxID = providerTxID
+	}
+	return nil
+}
+
+func (r *memoryPaymentRepository) MarkPaymentRefunded(p *domain.Payment, refundID string) error {
+	stored := r.payments[p.TxRef]
+	if stored.Status == domain.PAYMENT_UNAPPLIED {
+		stored.Status = domain.PAYMENT_REFUNDED
+		stored.RefundID = refundID
+	}
+	return nil
+}
+
+// missingOrderRepository has no orders, so no confirmation messages are composed
+type missingOrderRepository struct {
+	repository.OrderRepository
+}
+
+func (missingOrderRepository) FindOrderByID(id uint) (*domain.Order, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+// newWebhookTestService wires PaymentService to a Flutterwave provider backed by an httptest
+// server that reports the transaction with the given status, has no refunds on record and
+// completes every refund it is sent
+func newWebhookTestService(t *testing.T, transactionStatus string, stored domain.Payment) (PaymentService, *memoryPaymentRepository, *int) {
+	t.Helper()
+
+	verifications := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/refunds":
+			w.Write([]byte(`{"status":"success","data":[]}`))
+		case "/v3/transactions/99/refund":
+			w.Write([]byte(`{"status":"success","message":"Refund initiated","data":{"id":7,"status":"completed"}}`))
+		default:
+			verifications++
+			w.Write([]byte(`{"status":"success","data":{"id":99,"tx_ref":"` + stored.TxRef +
+				`","amount":2500,"currency":"NGN","status":"` + transactionStatus + `"}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider := payment.NewFlutterwaveProvider(flutterwave.NewClientWithBaseURL("secret", server.URL), "NG")
+	repo := &memoryPaymentRepository{payments: map[string]*domain.Payment{stored.TxRef: &stored}}
+	service := NewPaymentService(repo, missingOrderRepository{}, nil, config.AppConfig{}, NotificationService{}, provider)
+	return service, repo, &verifications
+}
+
+func chargeCompleted(txRef string, status string) flutterwave.WebhookEvent {
+	return flutterwave.WebhookEvent{
+		Event: flutterwave.EventChargeCompleted,
+		Data:  flutterwave.WebhookData{TxRef: txRef, Status: status},
+	}
+}
+
+func TestWebhookConfirmsPendingPayment(t *testing.T) {
+	service, repo, _ := newWebhookTestService(t, "successful", domain.Payment{
+		ID: 1, OrderID: 5, TxRef: "PAY-1", Amount: 2500, Currency: "NGN", Status: domain.PAYMENT_PENDING,
+	})
+
+	if err := service.HandleFlutterwaveWebhook(chargeCompleted("PAY-1", "successful")); err != nil {
+		t.Fatalf("HandleFlutterwaveWebhook: %v", err)
+	}
+	if got := repo.payments["PAY-1"]; got.Status != domain.PAYMENT_SUCCESSFUL || got.ProviderTxID != "99" {
+		t.Errorf("payment = %s/%s, want successful/99", got.Status, got.ProviderTxID)
+	}
+}
+
+func TestWebhookRechecksFailedPaymentOnSuccessEvent(t *testing.T) {
+	service, repo, verifications := newWebhookTestService(t, "successful", domain.Payment{
+		ID: 1, OrderID: 5, TxRef: "PAY-1", Amount: 2500, Currency: "NGN", Status: domain.PAYMENT_FAILED,
+	})
+
+	if err := service.HandleFlutterwaveWebhook(chargeCompleted("PAY-1", "successful")); err != nil {
+		t.Fatalf("HandleFlutterwaveWebhook: %v", err)
+	}
+	if *verifications != 1 {
+		t.Errorf("provider verified %d times, want 1", *verifications)
+	}
+	if got := repo.payments["PAY-1"].Status; got != domain.PAYMENT_SUCCESSFUL {
+		t.Errorf("status = %s, want successful", got)
+	}
+}
+
+func TestWebhookLeavesFailedPaymentOnFailureEvent(t *testing.T) {
+	service, repo, verifications := newWebhookTestService(t, "failed", domain.Payment{
+		ID: 1, OrderID: 5, TxRef: "PAY-1", Amount: 2500, Currency: "NGN", Status: domain.PAYMENT_FAILED,
+	})
+
+	if err := service.HandleFlutterwaveWebhook(chargeCompleted("PAY-1", "failed")); err != nil {
+		t.Fatalf("HandleFlutterwaveWebhook: %v", err)
+	}
+	if *verifications != 0 {
+		t.Errorf("provider verified %d times, want 0", *verifications)
+	}
+	if got := repo.payments["PAY-1"].Status; got != domain.PAYMENT_FAILED {
+		t.Errorf("status = %s, want failed", got)
+	}
+}
+
+func TestWebhookRefundsCaptureOfCancelledPayment(t *testing.T) {
+	service, repo, verifications := newWebhookTestService(t, "successful", domain.Payment{
+		ID: 1, OrderID: 5, TxRef: "PAY-1", Amount: 2500, Currency: "NGN", Status: domain.PAYMENT_CANCELLED,
+	})
+
+	if err := service.HandleFlutterwaveWebhook(chargeCompleted("PAY-1", "successful")); err != nil {
+		t.Fatalf("HandleFlutterwaveWebhook: %v", err)
+	}
+	if *verifications != 1 {
+		t.Errorf("provider verified %d times, want 1", *verifications)
+	}
+	if got := repo.payments["PAY-1"]; got.Status != domain.PAYMENT_REFUNDED || got.RefundID != "7" {
+		t.Errorf("payment = %s/%s, want refunded/7", got.Status, got.RefundID)
+	}
+}
+
+func TestWebhookRejectsUnderpaidTransaction(t *testing.T) {
+	service, repo, _ := newWebhookTestService(t, "successful", domain.Payment{
+		ID: 1, OrderID: 5, TxRef: "PAY-1", Amount: 3000, Currency: "NGN", Status: domain.PAYMENT_PENDING,
+	})
+
+	if err := service.HandleFlutterwaveWebhook(chargeCompleted("PAY-1", "successful")); err != nil {
+		t.Fatalf("HandleFlutterwaveWebhook: %v", err)
+	}
+	if got := repo.payments["PAY-1"].Status; got != domain.PAYMENT_FAILED {
+		t.Errorf("status = %s, want failed", got)
+	}
+}
+
+func TestWebhookIgnoresUnknownReference(t *testing.T) {
+	service, _, verifications := newWebhookTestService(t, "successful", domain.Payment{
+		ID: 1, TxRef: "PAY-1", Status: domain.PAYMENT_PENDING,
+	})
+
+	if err := service.HandleFlutterwaveWebhook(chargeCompleted("PAY-unknown", "successful")); err != nil {
+		t.Errorf("unknown references should be acknowledged, got %v", err)
+	}
+	if *verifications != 0 {
+		t.Errorf("provider verified %d times, want 0", *verifications)
+	}
+}
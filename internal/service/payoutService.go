@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/payment"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// payoutCheckDelay is how long a payout may wait on its transfer before the provider is asked about it
+const payoutCheckDelay = 10 * time.Minute
+
+type PayoutService struct {
+	Repo     repository.PayoutRepository
+	UserRepo repository.UserRepository
+	Config   config.AppConfig
+	Notifier NotificationService
+	provider payment.PaymentProvider
+}
+
+func NewPayoutService(repo repository.PayoutRepository, userRepo repository.UserRepository, config config.AppConfig, notifier NotificationService, provider payment.PaymentProvider) PayoutService {
+	return PayoutService{
+		Repo:     repo,
+		UserRepo: userRepo,
+		Config:   config,
+		Notifier: notifier,
+		provider: provider,
+	}
+}
+
+func (s PayoutService) GetSellerBalance(sellerID uint) (*dto.SellerBalance, error) {
+	balance, err := s.Repo.GetSellerBalance(sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance.Available = domain.RoundAmount(balance.Available)
+	balance.TotalEarned = domain.RoundAmount(balance.TotalEarned)
+	balance.TotalPaidOut = domain.RoundAmount(balance.TotalPaidOut)
+	balance.Currency = s.Config.PaymentCurrency
+	return balance, nil
+}
+
+func (s PayoutService) GetSellerPayouts(sellerID uint, query dto.PaginationParams) (*dto.PaginatedResponse, error) {
+	payouts, total, err := s.Repo.FindPayoutsBySellerID(sellerID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(payouts))
+	for i, payout := range payouts {
+		result[i] = payout
+	}
+
+	pagination := dto.PaginationMeta{
+		Take:  query.GetLimit(),
+		Skip:  query.GetOffset(),
+		Total: &total,
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: pagination,
+	}, nil
+}
+
+// RunSettlement pays every seller whose available balance has reached the payout minimum,
+// after settling earlier payouts whose transfer outcome is not known yet.
+// One seller failing does not stop the run; the others are still paid.
+func (s PayoutService) RunSettlement() error {
+	if s.provider == nil {
+		return ErrPaymentServiceUnavailable
+	}
+
+	if err := s.checkUnsettledPayouts(); err != nil {
+		log.Printf("Checking unsettled payouts failed: %v", err)
+	}
+
+	sellerIDs, err := s.Repo.FindSellerIDsWithBalance(s.Config.PayoutMinimumAmount)
+	if err != nil {
+		return err
+	}
+
+	for _, sellerID := range sellerIDs {
+		if err := s.settleSeller(sellerID); err != nil {
+			log.Printf("Settlement for seller %d failed: %v", sellerID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s PayoutService) settleSeller(sellerID uint) error {
+	bankAccount, err := s.UserRepo.FindBankAccountByUserID(sellerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrNoBankAccount
+	}
+	if err != nil {
+		return err
+	}
+
+	reference, err := helper.GenerateReference("PAYOUT")
+	if err != nil {
+		return err
+	}
+
+	payout, err := s.Repo.CreatePayout(&domain.Payout{
+		SellerID:          sellerID,
+		Reference:         reference,
+		Currency:          s.Config.PaymentCurrency,
+		BankAccountNumber: bankAccount.BankAccountNumber,
+		BankCode:          bankAccount.BankCode,
+	}, s.Config.PayoutMinimumAmount)
+	if errors.Is(err, domain.ErrBalanceTooLow) {
+		// the balance moved since it was listed, e.g. a payout already ran
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	transfer, err := s.provider.InitiateTransfer(payment.TransferRequest{
+		Reference:     payout.Reference,
+		Amount:        payout.Amount,
+		Currency:      payout.Currency,
+		BankCode:      payout.BankCode,
+		AccountNumber: payout.BankAccountNumber,
+		Narration:     "Payout " + payout.Reference,
+	})
+	if errors.Is(err, payment.ErrDeclined) {
+		return s.Repo.UpdatePayoutStatus(payout, domain.PAYOUT_FAILED, "", err.Error(), nil)
+	}
+	if err != nil {
+		// the transfer may still have gone out, so the money stays with the payout until the
+		// provider has been asked about its reference
+		if updateErr := s.Repo.UpdatePayoutStatus(payout, domain.PAYOUT_PROCESSING, "", "transfer outcome unknown: "+err.Error(), nil); updateErr != nil {
+			log.Printf("Failed to update payout %s: %v", payout.Reference, updateErr)
+		}
+		return err
+	}
+
+	return s.applyTransferStatus(payout, transfer.Status, transfer.ID, transfer.Message)
+}
+
+// checkUnsettledPayouts asks the provider about payouts that have waited on their transfer for a
+// while. A transfer the provider has never seen did not go out, so its payout fails and the
+// money goes back to the seller for the next run to send.
+func (s PayoutService) checkUnsettledPayouts() error {
+	payouts, err := s.Repo.FindUnsettledPayouts(time.Now().Add(-payoutCheckDelay))
+	if err != nil {
+		return err
+	}
+
+	for i := range payouts {
+		payout := &payouts[i]
+		transfer, err := s.provider.FindTransfer(payout.Reference)
+		if errors.Is(err, payment.ErrNotFound) {
+			err = s.Repo.UpdatePayoutStatus(payout, domain.PAYOUT_FAILED, "", "transfer was never received by the provider", nil)
+		} else if err == nil {
+			err = s.applyTransferStatus(payout, transfer.Status, transfer.ID, transfer.Message)
+		}
+		if err != nil {
+			log.Printf("Failed to check payout %s: %v", payout.Reference, err)
+		}
+	}
+	return nil
+}
+
+// HandleFlutterwaveTransfer applies a transfer.completed event to the payout it references
+func (s PayoutService) HandleFlutterwaveTransfer(event flutterwave.WebhookEvent) error {
+	err := s.CompleteTransfer(event.Data.Reference, payment.TransferStatus(event.Data.Status), strconv.FormatInt(event.Data.ID, 10), event.Data.CompleteMessage)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Ignoring Flutterwave transfer webhook for unknown reference %q", event.Data.Reference)
+		return nil
+	}
+	return err
+}
+
+// CompleteTransfer records the final outcome the provider reports for a payout transfer
+func (s PayoutService) CompleteTransfer(reference string, status string, providerRef string, message string) error {
+	payout, err := s.Repo.FindPayoutByReference(reference)
+	if err != nil {
+		return err
+	}
+
+	if payout.Status == domain.PAYOUT_SUCCESSFUL || payout.Status == domain.PAYOUT_FAILED {
+		log.Printf("Payout %s already %s, skipping", payout.Reference, payout.Status)
+		return nil
+	}
+
+	return s.applyTransferStatus(payout, status, providerRef, message)
+}
+
+func (s PayoutService) applyTransferStatus(payout *domain.Payout, status string, providerRef string, message string) error {
+	switch status {
+	case payment.TransactionSuccessful:
+		return s.Repo.UpdatePayoutStatus(payout, domain.PAYOUT_SUCCESSFUL, providerRef, "", s.Notifier.PayoutReceiptMessages(payout))
+	case payment.TransactionFailed:
+		log.Printf("Payout %s failed: %s", payout.Reference, message)
+		return s.Repo.UpdatePayoutStatus(payout, domain.PAYOUT_FAILED, providerRef, message, nil)
+	default:
+		return s.Repo.UpdatePayoutStatus(payout, domain.PAYOUT_PROCESSING, providerRef, "", nil)
+	}
+}
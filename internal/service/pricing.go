@@ -0,0 +1,38 @@
+package service
+
+import (
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"sort"
+	"strings"
+)
+
+// pricingRules reads the configured tax and shipping rates into the pricing engine
+func pricingRules(config config.AppConfig) domain.PricingRules {
+	rules := domain.PricingRules{
+		DefaultTaxBasisPoints: config.DefaultTaxBasisPoints,
+		FreeShippingOver:      domain.Money(config.FreeShippingOver),
+	}
+
+	regions := make([]string, 0, len(config.TaxRates))
+	for region := range config.TaxRates {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		country, state, _ := strings.Cut(region, "/")
+		rules.TaxRules = append(rules.TaxRules, domain.TaxRule{
+			Country:     country,
+			State:       state,
+			BasisPoints: config.TaxRates[region],
+		})
+	}
+
+	for _, method := range config.ShippingMethods {
+		rules.ShippingRates = append(rules.ShippingRates, domain.ShippingRate{
+			Method: method,
+			Amount: domain.Money(config.ShippingAmounts[method]),
+		})
+	}
+	return rules
+}
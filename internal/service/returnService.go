@@ -0,0 +1,304 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/payment"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReturnService struct {
+	Repo        repository.ReturnRepository
+	OrderRepo   repository.OrderRepository
+	PaymentRepo repository.PaymentRepository
+	Config      config.AppConfig
+	provider    payment.PaymentProvider
+}
+
+func NewReturnService(repo repository.ReturnRepository, orderRepo repository.OrderRepository, paymentRepo repository.PaymentRepository, config config.AppConfig, provider payment.PaymentProvider) ReturnService {
+	return ReturnService{
+		Repo:        repo,
+		OrderRepo:   orderRepo,
+		PaymentRepo: paymentRepo,
+		Config:      config,
+		provider:    provider,
+	}
+}
+
+// CreateReturn opens a return for an item of one of the buyer's own orders once it has been delivered
+func (s ReturnService) CreateReturn(userID uint, request dto.CreateReturnRequest) (*domain.ReturnRequest, error) {
+	item, err := s.Repo.FindOrderItemByID(request.OrderItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// looking the order up by buyer hides other buyers' items behind a not found
+	order, err := s.OrderRepo.FindOrderByUserIDAndID(userID, item.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivered := false
+	for _, sellerOrder := range order.SellerOrders {
+		if sellerOrder.ID == item.SellerOrderID {
+			delivered = sellerOrder.Status == domain.ORDER_DELIVERED
+		}
+	}
+	if !delivered {
+		return nil, domain.ErrReturnNotAllowed
+	}
+
+	reference, err := helper.GenerateReference("RET")
+	if err != nil {
+		return nil, errors.New("failed to generate return reference")
+	}
+
+	images := make([]domain.ReturnImage, len(request.Images))
+	for i, url := range request.Images {
+		images[i] = domain.ReturnImage{URL: url, Position: i}
+	}
+
+	return s.Repo.CreateReturn(&domain.ReturnRequest{
+		Reference:     reference,
+		OrderID:       item.OrderID,
+		SellerOrderID: item.SellerOrderID,
+		OrderItemID:   item.ID,
+		ProductID:     item.ProductID,
+		UserID:        userID,
+		SellerID:      item.SellerID,
+		Quantity:      request.Quantity,
+		Reason:        request.Reason,
+		Images:        images,
+		Status:        domain.RETURN_REQUESTED,
+	})
+}
+
+func (s ReturnService) GetReturns(userID uint, query dto.ReturnQuery) (*dto.PaginatedResponse, error) {
+	returns, total, err := s.Repo.FindReturnsByUserID(userID, query)
+	if err != nil {
+		return nil, err
+	}
+	return paginateReturns(returns, total, query), nil
+}
+
+func (s ReturnService) GetReturn(userID uint, id uint) (*domain.ReturnRequest, error) {
+	returnRequest, err := s.Repo.FindReturnByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if returnRequest.UserID != userID {
+		return nil, domain.ErrForbidden
+	}
+
+	return returnRequest, nil
+}
+
+func (s ReturnService) GetSellerReturns(sellerID uint, query dto.ReturnQuery) (*dto.PaginatedResponse, error) {
+	returns, total, err := s.Repo.FindReturnsBySellerID(sellerID, query)
+	if err != nil {
+		return nil, err
+	}
+	return paginateReturns(returns, total, query), nil
+}
+
+func (s ReturnService) GetSellerReturn(sellerID uint, id uint) (*domain.ReturnRequest, error) {
+	returnRequest, err := s.Repo.FindReturnByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if returnRequest.SellerID != sellerID {
+		return nil, domain.ErrForbidden
+	}
+
+	return returnRequest, nil
+}
+
+// refundCheckDelay is how long a refund may stay unconfirmed before a provider that has no
+// record of it is taken to mean it never arrived
+const refundCheckDelay = 10 * time.Minute
+
+// ApproveReturn accepts a return and refunds it through the payment provider. A refund the
+// provider declines leaves the return in refund_failed with the reason, ready to be approved again.
+// A refund whose outcome is unknown stays refund_pending; approving it again only asks the
+// provider what became of it, so the buyer is never refunded twice.
+func (s ReturnService) ApproveReturn(sellerID uint, id uint, request dto.ApproveReturnRequest) (*domain.ReturnRequest, error) {
+	if s.provider == nil {
+		return nil, ErrPaymentServiceUnavailable
+	}
+
+	returnRequest, err := s.GetSellerReturn(sellerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if returnRequest.Status == domain.RETURN_REFUND_PENDING {
+		return s.checkRefund(returnRequest)
+	}
+	if returnRequest.Status != domain.RETURN_REQUESTED && returnRequest.Status != domain.RETURN_REFUND_FAILED {
+		return nil, fmt.Errorf("%w: return is %s", domain.ErrInvalidReturnState, returnRequest.Status)
+	}
+
+	item, err := s.Repo.FindOrderItemByID(returnRequest.OrderItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the refund is what the buyer paid for the units, so their share of any discount stays off
+	lineTotal := item.Price.Times(item.Quantity) - item.Discount
+	maxRefund := (lineTotal.Times(returnRequest.Quantity) / domain.Money(item.Quantity)).Amount()
+	refundAmount := maxRefund
+	if request.RefundAmount != 0 {
+		refundAmount = domain.RoundAmount(request.RefundAmount)
+	}
+	if refundAmount <= 0 || refundAmount > maxRefund {
+		return nil, fmt.Errorf("%w: at most %.2f", domain.ErrInvalidRefundAmount, maxRefund)
+	}
+
+	paid, err := s.refundablePayment(returnRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.ApproveReturn(returnRequest, refundAmount, request.Note); err != nil {
+		return nil, err
+	}
+
+	refund, err := s.provider.Refund(refundRequest(returnRequest, paid))
+	return s.applyRefund(returnRequest, refund, err)
+}
+
+// CheckPendingRefunds asks the provider about refunds that have stayed unconfirmed for a while
+func (s ReturnService) CheckPendingRefunds() error {
+	if s.provider == nil {
+		return ErrPaymentServiceUnavailable
+	}
+
+	returns, err := s.Repo.FindPendingRefunds(time.Now().Add(-refundCheckDelay))
+	if err != nil {
+		return err
+	}
+
+	for i := range returns {
+		if _, err := s.checkRefund(&returns[i]); err != nil {
+			log.Printf("Failed to check refund for return %s: %v", returns[i].Reference, err)
+		}
+	}
+	return nil
+}
+
+// checkRefund looks a refund_pending return's refund up at the provider by its reference
+func (s ReturnService) checkRefund(returnRequest *domain.ReturnRequest) (*domain.ReturnRequest, error) {
+	paid, err := s.refundablePayment(returnRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := s.provider.FindRefund(refundRequest(returnRequest, paid))
+	if errors.Is(err, payment.ErrNotFound) {
+		if time.Since(returnRequest.UpdatedAt) < refundCheckDelay {
+			// the provider may not have recorded it yet
+			return returnRequest, nil
+		}
+		if err := s.Repo.MarkRefundFailed(returnRequest, "refund was never received by the provider"); err != nil {
+			return nil, err
+		}
+		return returnRequest, nil
+	}
+	if err != nil {
+		log.Printf("Failed to look up refund for return %s: %v", returnRequest.Reference, err)
+		return returnRequest, nil
+	}
+
+	return s.applyRefund(returnRequest, refund, nil)
+}
+
+// applyRefund records what the provider answered to a refund. Only a definite decline fails
+// the refund; an error that leaves the outcome open keeps it pending.
+func (s ReturnService) applyRefund(returnRequest *domain.ReturnRequest, refund *payment.Refund, err error) (*domain.ReturnRequest, error) {
+	if errors.Is(err, payment.ErrDeclined) {
+		log.Printf("Refund for return %s was declined: %v", returnRequest.Reference, err)
+		if err := s.Repo.MarkRefundFailed(returnRequest, err.Error()); err != nil {
+			return nil, err
+		}
+		return returnRequest, nil
+	}
+	if err != nil {
+		log.Printf("Refund for return %s has an unknown outcome: %v", returnRequest.Reference, err)
+		if err := s.Repo.MarkRefundPending(returnRequest, "refund outcome unknown: "+err.Error()); err != nil {
+			return nil, err
+		}
+		return returnRequest, nil
+	}
+	if refund.Status == payment.TransactionFailed {
+		log.Printf("Refund for return %s was declined: %s", returnRequest.Reference, refund.Message)
+		if err := s.Repo.MarkRefundFailed(returnRequest, refund.Message); err != nil {
+			return nil, err
+		}
+		return returnRequest, nil
+	}
+
+	// the provider has accepted the refund, so the money is treated as returned from here on
+	if err := s.Repo.CompleteRefund(returnRequest, refund.ID, returnRequest.SellerID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindReturnByID(returnRequest.ID)
+}
+
+// refundablePayment is the successful payment of the return's order
+func (s ReturnService) refundablePayment(returnRequest *domain.ReturnRequest) (*domain.Payment, error) {
+	paid, err := s.PaymentRepo.FindSuccessfulPaymentByOrderID(returnRequest.OrderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNoRefundablePayment
+	}
+	return paid, err
+}
+
+// refundRequest is sent with the return's reference, which is how the refund is found again
+func refundRequest(returnRequest *domain.ReturnRequest, paid *domain.Payment) payment.RefundRequest {
+	return payment.RefundRequest{
+		TransactionID: paid.ProviderTxID,
+		Reference:     returnRequest.Reference,
+		Amount:        returnRequest.RefundAmount,
+		Currency:      paid.Currency,
+	}
+}
+
+func (s ReturnService) RejectReturn(sellerID uint, id uint, request dto.RejectReturnRequest) (*domain.ReturnRequest, error) {
+	returnRequest, err := s.GetSellerReturn(sellerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.RejectReturn(returnRequest, request.Note); err != nil {
+		return nil, err
+	}
+
+	return returnRequest, nil
+}
+
+func paginateReturns(returns []domain.ReturnRequest, total int64, query dto.ReturnQuery) *dto.PaginatedResponse {
+	result := make([]interface{}, len(returns))
+	for i, returnRequest := range returns {
+		result[i] = returnRequest
+	}
+
+	return &dto.PaginatedResponse{
+		Data: result,
+		Pagination: dto.PaginationMeta{
+			Take:  query.GetLimit(),
+			Skip:  query.GetOffset(),
+			Total: &total,
+		},
+	}
+}
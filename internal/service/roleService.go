@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type RoleService struct {
+	Repo     repository.RoleRepository
+	UserRepo repository.UserRepository
+}
+
+func NewRoleService(repo repository.RoleRepository, userRepo repository.UserRepository) RoleService {
+	return RoleService{
+		Repo:     repo,
+		UserRepo: userRepo,
+	}
+}
+
+// Bootstrap seeds the default roles and grants the admin role to the configured accounts
+func (s RoleService) Bootstrap(adminEmails []string) error {
+	if err := s.Repo.SeedRoles(domain.DefaultRolePermissions); err != nil {
+		return err
+	}
+
+	for _, email := range adminEmails {
+		user, err := s.UserRepo.FindUserByEmail(email)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Admin account %s does not exist yet, skipping", email)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.AssignRole(user.ID, domain.ADMIN, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s RoleService) HasPermission(userID uint, permission string) (bool, error) {
+	permissions, err := s.Repo.FindPermissionsByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, granted := range permissions {
+		if granted == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AuthorizeUserAccess lets a user act on their own account; acting on anyone else's needs permission
+func (s RoleService) AuthorizeUserAccess(actorID uint, targetID uint, permission string) error {
+	if actorID == targetID {
+		return nil
+	}
+
+	allowed, err := s.HasPermission(actorID, permission)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+func (s RoleService) GetRoles() ([]domain.Role, error) {
+	return s.Repo.FindRoles()
+}
+
+func (s RoleService) GetUserRoles(userID uint) ([]domain.Role, error) {
+	if _, err := s.UserRepo.FindUserByID(userID); err != nil {
+		return nil, err
+	}
+	return s.Repo.FindRolesByUserID(userID)
+}
+
+func (s RoleService) AssignRole(userID uint, roleName string, grantedBy uint) ([]domain.Role, error) {
+	if _, err := s.UserRepo.FindUserByID(userID); err != nil {
+		return nil, err
+	}
+
+	role, err := s.Repo.FindRoleByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.AssignRole(&domain.UserRole{
+		UserID:    userID,
+		RoleID:    role.ID,
+		GrantedBy: grantedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindRolesByUserID(userID)
+}
+
+func (s RoleService) RemoveRole(userID uint, roleName string) ([]domain.Role, error) {
+	role, err := s.Repo.FindRoleByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.RemoveRole(userID, role.ID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindRolesByUserID(userID)
+}
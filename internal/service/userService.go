@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"go-ecommerce-app/config"
 	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
@@ -9,25 +10,47 @@ import (
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/pkg/notification"
 	"log"
-	"strconv"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// verificationCodeTTL is how long a contact verification code stays valid
+	verificationCodeTTL = 10 * time.Minute
+	// resetCodeTTL is how long a password reset code stays valid
+	resetCodeTTL = 15 * time.Minute
+	// maxResetCodesPerHour limits how many reset codes one account can be sent
+	maxResetCodesPerHour = 3
+	// maxResetAttempts is how many wrong guesses a reset code survives
+	maxResetAttempts = 5
 )
 
 type UserService struct {
 	Repo          repository.UserRepository
+	ResetRepo     repository.PasswordResetRepository
 	CatalogueRepo repository.CatalogueRepository
+	OrderRepo     repository.OrderRepository
+	CouponRepo    repository.CouponRepository
 	Auth          helper.Auth
 	Config        config.AppConfig
+	Pricing       domain.PricingRules
 	BankService   *BankService
+	Notifier      NotificationService
 }
 
-func NewUserService(repo repository.UserRepository, catalogueRepo repository.CatalogueRepository, auth helper.Auth, config config.AppConfig, bankService *BankService) UserService {
+func NewUserService(repo repository.UserRepository, resetRepo repository.PasswordResetRepository, catalogueRepo repository.CatalogueRepository, orderRepo repository.OrderRepository, couponRepo repository.CouponRepository, auth helper.Auth, config config.AppConfig, bankService *BankService, notifier NotificationService) UserService {
 	return UserService{
 		Repo:          repo,
+		ResetRepo:     resetRepo,
 		CatalogueRepo: catalogueRepo,
+		OrderRepo:     orderRepo,
+		CouponRepo:    couponRepo,
 		Auth:          auth,
 		Config:        config,
+		Pricing:       pricingRules(config),
 		BankService:   bankService,
+		Notifier:      notifier,
 	}
 }
 
@@ -54,10 +77,11 @@ func (s UserService) Register(user dto.UserSignUp) (*domain.User, error) {
 	return createdUser, nil
 }
 
-func (s UserService) Login(email string, password string) (*domain.User, string, error) {
+// Login checks the credentials; the session tokens are issued by AuthService
+func (s UserService) Login(email string, password string) (*domain.User, error) {
 	user, err := s.Repo.FindUserByEmail(email)
 	if err != nil {
-		return nil, "", errors.New("user does not exist with the provided email id")
+		return nil, errors.New("user does not exist with the provided email id")
 	}
 
 	// Verify plain text password against hashed password from database
@@ -65,21 +89,14 @@ func (s UserService) Login(email string, password string) (*domain.User, string,
 	// user.Password: bcrypt hash stored in database
 	isValidPassword, err := s.Auth.VerifyPassword(password, user.Password)
 	if err != nil {
-		return nil, "", errors.New("invalid password")
+		return nil, errors.New("invalid password")
 	}
 
 	if !isValidPassword {
-		return nil, "", errors.New("invalid password")
+		return nil, errors.New("invalid password")
 	}
 
-	token, err := s.Auth.GenerateToken(user.ID, user.Email, user.UserType)
-	if err != nil {
-		return nil, "", errors.New("failed to generate token")
-	}
-
-	return user, token, nil
-
-	// compare password and generate token
+	return user, nil
 }
 
 func (s UserService) FindUserByEmail(email string) (*domain.User, error) {
@@ -122,25 +139,40 @@ func (s UserService) UpdateUser(id uint, updateData dto.UserUpdate) (*domain.Use
 		}
 	}
 
-	updateUser := domain.User{}
+	updates := map[string]interface{}{}
+	email, phone := existingUser.Email, existingUser.Phone
 
 	if updateData.FirstName != nil {
-		updateUser.FirstName = *updateData.FirstName
+		updates["first_name"] = *updateData.FirstName
 	}
 	if updateData.LastName != nil {
-		updateUser.LastName = *updateData.LastName
+		updates["last_name"] = *updateData.LastName
 	}
 	if updateData.Email != nil {
-		updateUser.Email = *updateData.Email
+		email = *updateData.Email
+		updates["email"] = email
 	}
 	if updateData.Phone != nil {
-		updateUser.Phone = *updateData.Phone
+		phone = *updateData.Phone
+		updates["phone"] = phone
 	}
 	if updateData.Password != nil {
-		updateUser.Password = *updateData.Password
+		hashedPassword, err := s.Auth.CreateHashedPassword(*updateData.Password)
+		if err != nil {
+			return nil, err
+		}
+		updates["password"] = hashedPassword
 	}
 
-	user, err := s.Repo.UpdateUser(id, updateUser)
+	// a new email or phone has to be verified again before codes are sent to it
+	for field, value := range existingUser.ContactChangeUpdates(email, phone) {
+		updates[field] = value
+	}
+	if len(updates) == 0 {
+		return existingUser, nil
+	}
+
+	user, err := s.Repo.UpdateUserFields(id, updates)
 	if err != nil {
 		return nil, err
 	}
@@ -156,60 +188,97 @@ func (s UserService) DeleteUser(id uint) error {
 	return nil
 }
 
-func (s UserService) isVerifiedUser(id uint) bool {
-	currentUser, err := s.Repo.FindUserByID(id)
+// GetVerificationCode sends a code that verifies the contact detail behind channel,
+// the phone number for "sms" and the email address for "email". An empty channel means sms.
+func (s UserService) GetVerificationCode(id uint, channel string) error {
+	if channel == "" {
+		channel = domain.CHANNEL_SMS
+	}
+	if !domain.IsValidChannel(channel) {
+		return domain.ErrInvalidChannel
+	}
 
-	return err == nil && currentUser.Verified
-}
+	user, err := s.Repo.FindUserByID(id)
+	if err != nil {
+		return errors.New("failed to find user")
+	}
 
-func (s UserService) GetVerificationCode(id uint) error {
-	//1. check if user is verified
-	if s.isVerifiedUser(id) {
-		return errors.New("user is already verified")
+	//1. check if the contact is already verified
+	if user.ContactVerified(channel) {
+		return fmt.Errorf("%w: %s", domain.ErrAlreadyVerified, channel)
+	}
+	if !s.Notifier.CanSend(user, channel) {
+		return fmt.Errorf("%w: %s", domain.ErrChannelUnavailable, channel)
 	}
+
 	//2. if not verified, generate a verification code
 	verificationCode, err := s.Auth.GenerateVerificationCode()
 	if err != nil {
 		return errors.New("failed to generate verification code")
 	}
-	user, err := s.Repo.FindUserByID(id)
+	expiry := time.Now().Add(verificationCodeTTL)
+
+	//3. queue the code for delivery, the outbox worker sends it in the background
+	message, err := s.codeMessage(user, channel, notification.TemplateVerificationCode, verificationCode, expiry)
 	if err != nil {
-		return errors.New("failed to find user")
+		return errors.New("failed to prepare verification code: " + err.Error())
 	}
-	user.Code = verificationCode
-	user.Expiry = time.Now().Add(time.Minute * 10)
-	_, err = s.Repo.UpdateUser(id, *user)
+
+	err = s.Repo.SaveVerificationCode(id, verificationCode, channel, expiry, []domain.OutboxMessage{*message})
 	if err != nil {
 		return errors.New("failed to update user")
 	}
 
-	//send sms or email to user with verification code
-	notificationClient := notification.NewNotificationClient(s.Config)
-	formattedPhone := helper.FormatPhoneToE164(user.Phone)
-	err = notificationClient.SendSMS(formattedPhone, strconv.Itoa(verificationCode))
+	return nil
+}
+
+// codeMessage composes a one-time code notice; it is hidden from staff and dropped once the code expires
+func (s UserService) codeMessage(user *domain.User, channel string, template string, code int, expiry time.Time) (*domain.OutboxMessage, error) {
+	message, err := s.Notifier.Compose(user, channel, template, notification.CodeData{
+		Name:             user.FirstName,
+		Code:             code,
+		ExpiresInMinutes: int(time.Until(expiry).Round(time.Minute).Minutes()),
+	})
 	if err != nil {
-		return errors.New("failed to send verification code: " + err.Error())
+		return nil, err
 	}
 
-	return nil
+	message.Sensitive = true
+	message.ExpiresAt = &expiry
+	return message, nil
 }
 
+// VerifyCode checks a code from GetVerificationCode and marks the channel it was sent on as verified
 func (s UserService) VerifyCode(id uint, code int) (bool, error) {
-	//1. check if user is verified
-	if s.isVerifiedUser(id) {
-		return false, errors.New("user is already verified")
-	}
-	//2. if not verified, verify the code
 	user, err := s.Repo.FindUserByID(id)
 	if err != nil {
 		return false, errors.New("failed to find user")
 	}
+
+	channel := user.CodeChannel
+	if channel == "" {
+		channel = domain.CHANNEL_SMS
+	}
+	if user.ContactVerified(channel) {
+		return false, errors.New("user " + channel + " is already verified")
+	}
 	if user.Code != code {
 		return false, errors.New("invalid verification code")
 	}
 	if user.Expiry.Before(time.Now()) {
 		return false, errors.New("verification code has expired")
 	}
+
+	// record the phone of accounts verified before email existed explicitly,
+	// since Verified alone stops meaning phone once the email is verified too
+	if user.ContactVerified(domain.CHANNEL_SMS) {
+		user.PhoneVerified = true
+	}
+	if channel == domain.CHANNEL_EMAIL {
+		user.EmailVerified = true
+	} else {
+		user.PhoneVerified = true
+	}
 	user.Verified = true
 	_, err = s.Repo.UpdateUser(id, *user)
 	if err != nil {
@@ -218,6 +287,86 @@ func (s UserService) VerifyCode(id uint, code int) (bool, error) {
 	return true, nil
 }
 
+// ForgotPassword sends a one-time reset code over a verified channel of the account. It does not report whether
+// the account exists, was rate limited or has nothing verified, so the endpoint cannot be used to probe for accounts.
+func (s UserService) ForgotPassword(email string) error {
+	user, err := s.Repo.FindUserByEmail(email)
+	if err != nil {
+		log.Println("Password reset requested for unknown email")
+		return nil
+	}
+
+	sent, err := s.ResetRepo.CountResetCodesSince(user.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent >= maxResetCodesPerHour {
+		log.Printf("Password reset for user %d rate limited", user.ID)
+		return nil
+	}
+
+	resetCode, err := s.Auth.GenerateVerificationCode()
+	if err != nil {
+		return errors.New("failed to generate reset code")
+	}
+
+	// a reset code only goes to a contact the user has proven they own
+	channel, ok := s.Notifier.VerifiedChannelFor(user)
+	if !ok {
+		log.Printf("Password reset for user %d skipped, no verified channel", user.ID)
+		return nil
+	}
+
+	expiry := time.Now().Add(resetCodeTTL)
+	message, err := s.codeMessage(user, channel, notification.TemplatePasswordReset, resetCode, expiry)
+	if err != nil {
+		return errors.New("failed to prepare reset code: " + err.Error())
+	}
+
+	_, err = s.ResetRepo.CreateResetCode(&domain.PasswordResetCode{
+		UserID:    user.ID,
+		CodeHash:  s.Auth.HashCode(resetCode),
+		ExpiresAt: expiry,
+	}, []domain.OutboxMessage{*message})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a code from ForgotPassword and signs the user out everywhere
+func (s UserService) ResetPassword(input dto.ResetPasswordInput) error {
+	hashedPassword, err := s.Auth.CreateHashedPassword(input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.Repo.FindUserByEmail(input.Email)
+	if err != nil {
+		return domain.ErrInvalidResetCode
+	}
+
+	resetCode, err := s.ResetRepo.FindActiveResetCode(user.ID)
+	if err != nil {
+		return domain.ErrInvalidResetCode
+	}
+
+	allowed, err := s.ResetRepo.RegisterResetAttempt(resetCode.ID, maxResetAttempts)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return domain.ErrTooManyAttempts
+	}
+
+	if !s.Auth.VerifyCode(input.Code, resetCode.CodeHash) {
+		return domain.ErrInvalidResetCode
+	}
+
+	return s.ResetRepo.CompletePasswordReset(resetCode, hashedPassword)
+}
+
 func (s UserService) Profile(user interface{}) (*domain.User, error) {
 	//perform some db operation
 	//business logic
@@ -291,6 +440,12 @@ func (s UserService) UpdateProfile(userID uint, profileInput dto.ProfileUpdateIn
 	if profileInput.LastName != nil {
 		user.LastName = *profileInput.LastName
 	}
+	if profileInput.NotificationChannel != nil {
+		if !domain.IsValidChannel(*profileInput.NotificationChannel) {
+			return nil, errors.New("notification channel must be sms or email")
+		}
+		user.NotificationChannel = *profileInput.NotificationChannel
+	}
 	_, err = s.Repo.UpdateUser(userID, *user)
 	if err != nil {
 		return nil, err
@@ -393,19 +548,17 @@ func (s UserService) DeleteProfile(id uint) (*domain.User, error) {
 	return &domain.User{}, nil
 }
 
-func (s UserService) Orders(user interface{}) (*domain.User, error) {
-	//perform some db operation
-	//business logic
-	return &domain.User{}, nil
+// Orders lists the user's orders, newest first
+func (s UserService) Orders(userID uint, query dto.OrderQuery) (*dto.PaginatedResponse, error) {
+	return s.GetOrders(userID, query)
 }
 
-func (s UserService) GetOrder(user interface{}) (*domain.User, error) {
-	//perform some db operation
-	//business logic
-	return &domain.User{}, nil
+// GetOrder returns one of the user's orders with its sub-orders
+func (s UserService) GetOrder(id uint, userID uint) (*domain.Order, error) {
+	return s.GetOrderById(id, userID)
 }
 
-func (s UserService) BecomeSeller(id uint, seller dto.BecomeSellerInput) (*domain.User, string, error) {
+func (s UserService) BecomeSeller(id uint, sessionID string, seller dto.BecomeSellerInput) (*domain.User, string, error) {
 	// find existing user
 	user, err := s.Repo.FindUserByID(id)
 	if err != nil {
@@ -430,13 +583,19 @@ func (s UserService) BecomeSeller(id uint, seller dto.BecomeSellerInput) (*domai
 	}
 	log.Printf("Bank account verified successfully for user %d, proceeding with seller status update", id)
 
-	// update user - only happens after successful bank verification
-	user.UserType = "seller"
-	user.FirstName = seller.FirstName
-	user.LastName = seller.LastName
-	user.Phone = seller.PhoneNumber
+	// update user - only happens after successful bank verification; a new phone number has to
+	// be verified again
+	updates := map[string]interface{}{
+		"user_type":  "seller",
+		"first_name": seller.FirstName,
+		"last_name":  seller.LastName,
+		"phone":      seller.PhoneNumber,
+	}
+	for field, value := range user.ContactChangeUpdates(user.Email, seller.PhoneNumber) {
+		updates[field] = value
+	}
 
-	updatedUser, err := s.Repo.UpdateUser(id, *user)
+	updatedUser, err := s.Repo.UpdateUserFields(id, updates)
 	if err != nil {
 		log.Printf("Error updating user to seller: %v", err)
 		return nil, "", errors.New("failed to update user: " + err.Error())
@@ -457,8 +616,8 @@ func (s UserService) BecomeSeller(id uint, seller dto.BecomeSellerInput) (*domai
 	}
 	log.Printf("Bank account created successfully: ID=%d", createdBankAccount.ID)
 
-	// generate new token
-	token, err := s.Auth.GenerateToken(updatedUser.ID, updatedUser.Email, updatedUser.UserType)
+	// generate new token in the same session
+	token, err := s.Auth.GenerateToken(updatedUser.ID, updatedUser.Email, updatedUser.UserType, updatedUser.TokenVersion, sessionID)
 	if err != nil {
 		return nil, "", errors.New("failed to generate token")
 	}
@@ -468,49 +627,166 @@ func (s UserService) BecomeSeller(id uint, seller dto.BecomeSellerInput) (*domai
 }
 
 func (s UserService) AddToCart(userID uint, request dto.CreateCartRequest) (*domain.Cart, error) {
-	product, err := s.CatalogueRepo.GetProductByID(request.ProductID)
+	return addToCart(s.Repo, s.CatalogueRepo, userID, request)
+}
+
+// addToCart puts a product in the user's cart, adding to the quantity of a line already there.
+// A line already there keeps the price it was added at until the buyer acknowledges a new one.
+func addToCart(userRepo repository.UserRepository, catalogueRepo repository.CatalogueRepository, userID uint, request dto.CreateCartRequest) (*domain.Cart, error) {
+	cartItem, err := newCartItem(catalogueRepo, request)
 	if err != nil {
-		return nil, errors.New("product not found")
+		return nil, err
 	}
 
-	existingCart, err := s.Repo.FindCartByUserIDAndProductID(userID, request.ProductID)
+	existingCart, err := userRepo.FindCartByUserIDAndProductID(userID, request.ProductID, request.VariantID)
 	if err == nil {
 		existingCart.Quantity += request.Quantity
-		updatedCart, err := s.Repo.UpdateCart(existingCart)
+		updatedCart, err := userRepo.UpdateCart(existingCart)
 		if err != nil {
 			return nil, err
 		}
 		return updatedCart, nil
 	}
 
-	cartItem := &domain.Cart{
-		UserID:    userID,
+	cartItem.UserID = userID
+	createdCart, err := userRepo.CreateCart(cartItem)
+	if err != nil {
+		return nil, err
+	}
+
+	return createdCart, nil
+}
+
+// newCartItem builds the cart line for a product at its current price. Products with variants
+// are sold per SKU, which sets the line's price, image and name.
+func newCartItem(catalogueRepo repository.CatalogueRepository, request dto.CreateCartRequest) (*domain.Cart, error) {
+	product, err := catalogueRepo.GetProductByID(request.ProductID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	name, imageURL, price, sku := product.Name, product.ImageURL, product.Price, ""
+	if product.HasVariants || request.VariantID != nil {
+		if request.VariantID == nil {
+			return nil, domain.ErrVariantRequired
+		}
+		variant, err := catalogueRepo.GetVariantByID(product.ID, *request.VariantID)
+		if err != nil {
+			return nil, errors.New("product variant not found")
+		}
+		name = product.Name + " (" + variant.Label(product.Options) + ")"
+		price, sku = variant.Price, variant.SKU
+		if variant.ImageURL != "" {
+			imageURL = variant.ImageURL
+		}
+	}
+
+	return &domain.Cart{
 		SellerID:  product.SellerID,
-		Name:      product.Name,
-		ImageURL:  product.ImageURL,
-		Price:     product.Price,
+		Name:      name,
+		ImageURL:  imageURL,
+		Price:     domain.MoneyFromAmount(price),
 		Quantity:  request.Quantity,
 		ProductID: request.ProductID,
+		VariantID: request.VariantID,
+		SKU:       sku,
+	}, nil
+}
+
+// GetCart prices the cart against the catalogue without changing it. Lines keep warning about a
+// new price until the buyer acknowledges it with AcknowledgeCartChanges. Lines whose product or
+// SKU is gone are returned separately; they stay in the cart, and block checkout, until then.
+func (s UserService) GetCart(userID uint) ([]domain.CartLine, []domain.CartLine, error) {
+	current, unavailable, _, err := s.loadCart(userID)
+	return current, unavailable, err
+}
+
+// loadCart does the work of GetCart and also hands back the products the cart was priced against
+func (s UserService) loadCart(userID uint) ([]domain.CartLine, []domain.CartLine, map[uint]*domain.Product, error) {
+	cartItems, err := s.Repo.FindCartByUserID(userID)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	createdCart, err := s.Repo.CreateCart(cartItem)
+	lines, products, err := priceCart(s.CatalogueRepo, cartItems)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	return createdCart, nil
+	current, unavailable := splitUnavailable(lines)
+	return current, unavailable, products, nil
 }
 
-func (s UserService) FindCartItems(userID uint) ([]domain.Cart, error) {
+// AcknowledgeCartChanges is the buyer accepting what the cart warned about: every line takes
+// the current price of its product or SKU, and lines that can no longer be bought are removed
+func (s UserService) AcknowledgeCartChanges(userID uint) ([]domain.CartLine, error) {
 	cartItems, err := s.Repo.FindCartByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
-	return cartItems, nil
+
+	lines, _, err := priceCart(s.CatalogueRepo, cartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, line := range lines {
+		if line.Unavailable() {
+			if err := s.Repo.DeleteCartItem(userID, line.ProductID, line.VariantID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if line.Price != cartItems[i].Price {
+			if _, err := s.Repo.UpdateCart(&line.Cart); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	current, _, err := s.GetCart(userID)
+	return current, err
+}
+
+// splitUnavailable separates the lines that can still be bought from those that cannot
+func splitUnavailable(lines []domain.CartLine) ([]domain.CartLine, []domain.CartLine) {
+	current := []domain.CartLine{}
+	unavailable := []domain.CartLine{}
+	for _, line := range lines {
+		if line.Unavailable() {
+			unavailable = append(unavailable, line)
+			continue
+		}
+		current = append(current, line)
+	}
+	return current, unavailable
 }
 
-func (s UserService) GetCartItem(userID uint, productID uint) (*domain.Cart, error) {
-	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, productID)
+// priceCart checks every cart line against the current products in one query
+func priceCart(catalogueRepo repository.CatalogueRepository, cartItems []domain.Cart) ([]domain.CartLine, map[uint]*domain.Product, error) {
+	productIDs := make([]uint, 0, len(cartItems))
+	for _, item := range cartItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	products, err := catalogueRepo.GetProductsByIDs(productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	productsByID := make(map[uint]*domain.Product, len(products))
+	for i := range products {
+		productsByID[products[i].ID] = &products[i]
+	}
+
+	lines := make([]domain.CartLine, len(cartItems))
+	for i, item := range cartItems {
+		lines[i] = domain.PriceCartLine(item, productsByID[item.ProductID])
+	}
+	return lines, productsByID, nil
+}
+
+func (s UserService) GetCartItem(userID uint, productID uint, variantID *uint) (*domain.Cart, error) {
+	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, productID, variantID)
 	if err != nil {
 		return nil, errors.New("cart item not found")
 	}
@@ -522,7 +798,7 @@ func (s UserService) UpdateCart(userID uint, request dto.UpdateCartRequest) (*do
 		return nil, errors.New("product ID is required")
 	}
 
-	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, *request.ProductID)
+	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, *request.ProductID, request.VariantID)
 	if err != nil {
 		return nil, errors.New("cart item not found")
 	}
@@ -530,9 +806,6 @@ func (s UserService) UpdateCart(userID uint, request dto.UpdateCartRequest) (*do
 	if request.Quantity != nil {
 		cartItem.Quantity = *request.Quantity
 	}
-	if request.Price != nil {
-		cartItem.Price = *request.Price
-	}
 
 	updatedCart, err := s.Repo.UpdateCart(cartItem)
 	if err != nil {
@@ -542,16 +815,16 @@ func (s UserService) UpdateCart(userID uint, request dto.UpdateCartRequest) (*do
 	return updatedCart, nil
 }
 
-func (s UserService) DeleteCartItem(userID uint, productID uint) error {
-	return s.Repo.DeleteCartItem(userID, productID)
+func (s UserService) DeleteCartItem(userID uint, productID uint, variantID *uint) error {
+	return s.Repo.DeleteCartItem(userID, productID, variantID)
 }
 
 func (s UserService) ClearCart(userID uint) error {
 	return s.Repo.DeleteAllCartItems(userID)
 }
 
-func (s UserService) IncrementCartItem(userID uint, productID uint) (*domain.Cart, error) {
-	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, productID)
+func (s UserService) IncrementCartItem(userID uint, productID uint, variantID *uint) (*domain.Cart, error) {
+	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, productID, variantID)
 	if err != nil {
 		return nil, errors.New("cart item not found")
 	}
@@ -565,8 +838,8 @@ func (s UserService) IncrementCartItem(userID uint, productID uint) (*domain.Car
 	return updatedCart, nil
 }
 
-func (s UserService) DecrementCartItem(userID uint, productID uint) (*domain.Cart, error) {
-	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, productID)
+func (s UserService) DecrementCartItem(userID uint, productID uint, variantID *uint) (*domain.Cart, error) {
+	cartItem, err := s.Repo.FindCartByUserIDAndProductID(userID, productID, variantID)
 	if err != nil {
 		return nil, errors.New("cart item not found")
 	}
@@ -584,26 +857,272 @@ func (s UserService) DecrementCartItem(userID uint, productID uint) (*domain.Car
 	return updatedCart, nil
 }
 
-func (s UserService) CreateOrder(user interface{}) (*domain.User, error) {
-	//perform some db operation
-	//business logic
-	return &domain.User{}, nil
+// GetCartSummary prices the cart and totals it per seller, with the applied coupon, tax for the
+// buyer's address and shipping by the chosen method. Checkout charges exactly these totals.
+// A coupon that no longer applies stays on the cart, with the reason in CouponMessage.
+func (s UserService) GetCartSummary(userID uint, shippingMethod string) (*domain.CartSummary, []domain.CartLine, error) {
+	lines, unavailable, products, err := s.loadCart(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cartCoupon, err := s.CouponRepo.FindCartCoupon(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	couponMessage := ""
+	if cartCoupon != nil {
+		if _, err := s.discountCart(userID, cartCoupon.Code, lines, products); err != nil {
+			if !isCouponError(err) {
+				return nil, nil, err
+			}
+			couponMessage = err.Error()
+		}
+	}
+
+	address, err := s.Repo.FindAddressByUserID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary, err := s.Pricing.Summarize(lines, address, shippingMethod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cartCoupon != nil {
+		summary.Coupon = cartCoupon.Code
+		summary.CouponMessage = couponMessage
+	}
+	return summary, unavailable, nil
 }
 
-func (s UserService) FindOrder(id uint) (*domain.User, error) {
-	//perform some db operation
-	//business logic
-	return &domain.User{}, nil
+// ApplyCoupon puts a discount code on the cart once it is known to apply to it
+func (s UserService) ApplyCoupon(userID uint, code string) (*domain.CartSummary, error) {
+	code = domain.NormalizeCouponCode(code)
+
+	lines, _, products, err := s.loadCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, domain.ErrCartEmpty
+	}
+	if _, err := s.discountCart(userID, code, lines, products); err != nil {
+		return nil, err
+	}
+
+	if err := s.CouponRepo.SetCartCoupon(userID, code); err != nil {
+		return nil, err
+	}
+
+	summary, _, err := s.GetCartSummary(userID, "")
+	return summary, err
 }
 
-func (s UserService) GetOrderById(id uint, user interface{}) (*domain.User, error) {
-	//perform some db operation
-	//business logic
-	return &domain.User{}, nil
+func (s UserService) RemoveCoupon(userID uint) error {
+	return s.CouponRepo.DeleteCartCoupon(userID)
 }
 
-func (s UserService) GetOrders(user interface{}) ([]interface{}, error) {
-	//perform some db operation
-	//business logic
-	return []interface{}{}, nil
+// discountCart checks that the coupon can be used by the buyer on these lines and sets the
+// discount on each line it covers
+func (s UserService) discountCart(userID uint, code string, lines []domain.CartLine, products map[uint]*domain.Product) (*domain.Coupon, error) {
+	coupon, err := s.CouponRepo.FindCouponByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s is not a valid code", domain.ErrCouponNotApplicable, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := coupon.CheckAvailable(time.Now()); err != nil {
+		return nil, err
+	}
+	if coupon.MaxPerUser > 0 {
+		used, err := s.CouponRepo.CountUserRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(coupon.MaxPerUser) {
+			return nil, fmt.Errorf("%w: you have already used %s", domain.ErrCouponExhausted, coupon.Code)
+		}
+	}
+
+	var categories map[uint]bool
+	if coupon.CategoryID != nil {
+		nodes, err := s.CatalogueRepo.GetCategoryTree(coupon.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		categories = make(map[uint]bool, len(nodes))
+		for _, node := range nodes {
+			categories[node.ID] = true
+		}
+	}
+
+	covers := func(line domain.CartLine) bool {
+		if coupon.SellerID != nil && line.SellerID != *coupon.SellerID {
+			return false
+		}
+		if coupon.ProductID != nil && line.ProductID != *coupon.ProductID {
+			return false
+		}
+		if categories != nil {
+			product, ok := products[line.ProductID]
+			return ok && categories[product.CategoryID]
+		}
+		return true
+	}
+
+	if _, err := domain.ApplyCoupon(*coupon, lines, covers); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+func isCouponError(err error) bool {
+	return errors.Is(err, domain.ErrCouponNotApplicable) || errors.Is(err, domain.ErrCouponExhausted)
+}
+
+func (s UserService) CreateOrder(userID uint, shippingMethod string) (*domain.Order, error) {
+	cartItems, err := s.Repo.FindCartByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartItems) == 0 {
+		return nil, domain.ErrCartEmpty
+	}
+
+	// the buyer pays the prices they acknowledged, so a change they have not seen stops checkout
+	lines, products, err := priceCart(s.CatalogueRepo, cartItems)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range lines {
+		if line.Unavailable() {
+			return nil, fmt.Errorf("%w: %s", domain.ErrProductUnavailable, line.Name)
+		}
+		if line.Price != cartItems[i].Price {
+			return nil, fmt.Errorf("%w: %s", domain.ErrPriceChanged, line.Name)
+		}
+	}
+
+	cartCoupon, err := s.CouponRepo.FindCartCoupon(userID)
+	if err != nil {
+		return nil, err
+	}
+	var coupon *domain.Coupon
+	if cartCoupon != nil {
+		coupon, err = s.discountCart(userID, cartCoupon.Code, lines, products)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	address, err := s.Repo.FindAddressByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.Pricing.Summarize(lines, address, shippingMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	orderRef, err := helper.GenerateReference("ORD")
+	if err != nil {
+		return nil, errors.New("failed to generate order reference")
+	}
+
+	// one sub-order per seller; name, price and seller are copied so the order is
+	// unaffected by later catalogue changes
+	sellerOrders := make([]domain.SellerOrder, len(summary.Sellers))
+	for i, seller := range summary.Sellers {
+		sellerOrders[i] = domain.SellerOrder{
+			SellerID: seller.SellerID,
+			Status:   domain.ORDER_PENDING,
+			Subtotal: seller.Subtotal,
+			Discount: seller.Discount,
+			Tax:      seller.Tax,
+			Shipping: seller.Shipping,
+		}
+		if coupon != nil && coupon.FundedBy == domain.COUPON_FUNDED_BY_PLATFORM {
+			sellerOrders[i].PlatformDiscount = seller.Discount
+		}
+		for _, item := range seller.Items {
+			sellerOrders[i].Items = append(sellerOrders[i].Items, domain.OrderItem{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				SKU:       item.SKU,
+				SellerID:  item.SellerID,
+				Name:      item.Name,
+				ImageURL:  item.ImageURL,
+				Price:     item.Price,
+				Quantity:  item.Quantity,
+				Discount:  item.Discount,
+			})
+		}
+	}
+
+	order := &domain.Order{
+		UserID:         userID,
+		OrderRef:       orderRef,
+		Status:         domain.ORDER_PENDING,
+		TotalAmount:    summary.Total,
+		ShippingMethod: summary.ShippingMethod,
+		Discount:       summary.Discount,
+		SellerOrders:   sellerOrders,
+	}
+
+	var redemption *domain.CouponRedemption
+	if coupon != nil {
+		order.CouponCode = coupon.Code
+		redemption = &domain.CouponRedemption{
+			CouponID: coupon.ID,
+			UserID:   userID,
+			Discount: summary.Discount,
+		}
+	}
+
+	createdOrder, err := s.OrderRepo.CreateOrder(order, cartItems, redemption)
+	if err != nil {
+		return nil, err
+	}
+
+	return createdOrder, nil
+}
+
+func (s UserService) FindOrder(id uint) (*domain.Order, error) {
+	order, err := s.OrderRepo.FindOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s UserService) GetOrderById(id uint, userID uint) (*domain.Order, error) {
+	order, err := s.OrderRepo.FindOrderByUserIDAndID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s UserService) GetOrders(userID uint, query dto.OrderQuery) (*dto.PaginatedResponse, error) {
+	orders, page, err := s.OrderRepo.FindOrdersByUserID(userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(orders))
+	for i, order := range orders {
+		result[i] = order
+	}
+
+	pagination := dto.NewPaginationMeta(query.PaginationParams, page)
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: pagination,
+	}, nil
 }
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// memoryUserRepository keeps one user and applies field updates the way the database would
+type memoryUserRepository struct {
+	repository.UserRepository
+	user domain.User
+}
+
+func (r *memoryUserRepository) FindUserByID(id uint) (*domain.User, error) {
+	if id != r.user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := r.user
+	return &found, nil
+}
+
+func (r *memoryUserRepository) FindUserByEmail(email string) (*domain.User, error) {
+	if email != r.user.Email {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := r.user
+	return &found, nil
+}
+
+func (r *memoryUserRepository) UpdateUserFields(id uint, updates map[string]interface{}) (domain.User, error) {
+	for field, value := range updates {
+		switch field {
+		case "first_name":
+			r.user.FirstName = value.(string)
+		case "last_name":
+			r.user.LastName = value.(string)
+		case "email":
+			r.user.Email = value.(string)
+		case "phone":
+			r.user.Phone = value.(string)
+		case "password":
+			r.user.Password = value.(string)
+		case "email_verified":
+			r.user.EmailVerified = value.(bool)
+		case "phone_verified":
+			r.user.PhoneVerified = value.(bool)
+		case "verified":
+			r.user.Verified = value.(bool)
+		}
+	}
+	return r.user, nil
+}
+
+// memoryResetRepository records the messages reset codes were sent with
+type memoryResetRepository struct {
+	repository.PasswordResetRepository
+	sent []domain.OutboxMessage
+}
+
+func (r *memoryResetRepository) CountResetCodesSince(userID uint, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryResetRepository) CreateResetCode(code *domain.PasswordResetCode, messages []domain.OutboxMessage) (*domain.PasswordResetCode, error) {
+	r.sent = append(r.sent, messages...)
+	return code, nil
+}
+
+// emailAndSMSClient can reach users on both channels
+type emailAndSMSClient struct{}
+
+func (emailAndSMSClient) SendSMS(phone string, message string) error             { return nil }
+func (emailAndSMSClient) SendEmail(to string, subject string, body string) error { return nil }
+func (emailAndSMSClient) EmailEnabled() bool                                     { return true }
+
+func newResetTestService(user domain.User) (UserService, *memoryUserRepository, *memoryResetRepository) {
+	users := &memoryUserRepository{user: user}
+	resets := &memoryResetRepository{}
+	service := UserService{
+		Repo:      users,
+		ResetRepo: resets,
+		Auth:      helper.Auth{Secret: "secret"},
+		Notifier:  NotificationService{Client: emailAndSMSClient{}, UserRepo: users},
+	}
+	return service, users, resets
+}
+
+func TestResetCodeIsNotSentToChangedEmail(t *testing.T) {
+	service, users, resets := newResetTestService(domain.User{
+		ID: 1, FirstName: "Ada", Email: "ada@example.com", Phone: "08030000000",
+		EmailVerified: true, Verified: true, NotificationChannel: domain.CHANNEL_EMAIL,
+	})
+
+	newEmail := "attacker@example.com"
+	if _, err := service.UpdateUser(1, dto.UserUpdate{Email: &newEmail}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if users.user.EmailVerified || users.user.Verified {
+		t.Errorf("changed email is still verified: email_verified=%v verified=%v", users.user.EmailVerified, users.user.Verified)
+	}
+
+	if err := service.ForgotPassword(newEmail); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	if len(resets.sent) != 0 {
+		t.Errorf("reset code sent to %q, want no code", resets.sent[0].Recipient)
+	}
+}
+
+func TestResetCodeGoesToVerifiedPhoneAfterEmailChange(t *testing.T) {
+	service, users, resets := newResetTestService(domain.User{
+		ID: 1, FirstName: "Ada", Email: "ada@example.com", Phone: "08030000000",
+		EmailVerified: true, PhoneVerified: true, Verified: true, NotificationChannel: domain.CHANNEL_EMAIL,
+	})
+
+	newEmail := "ada@work.example.com"
+	if _, err := service.UpdateUser(1, dto.UserUpdate{Email: &newEmail}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if !users.user.PhoneVerified || !users.user.Verified {
+		t.Errorf("unchanged phone lost its verification")
+	}
+
+	if err := service.ForgotPassword(newEmail); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	if len(resets.sent) != 1 || resets.sent[0].Channel != domain.CHANNEL_SMS {
+		t.Fatalf("sent %+v, want one code by SMS", resets.sent)
+	}
+}
+
+func TestChangedPhoneIsNoLongerVerified(t *testing.T) {
+	service, users, _ := newResetTestService(domain.User{
+		ID: 1, FirstName: "Ada", Email: "ada@example.com", Phone: "08030000000", Verified: true,
+	})
+
+	newPhone := "08039999999"
+	if _, err := service.UpdateUser(1, dto.UserUpdate{Phone: &newPhone}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if users.user.ContactVerified(domain.CHANNEL_SMS) || users.user.Verified {
+		t.Errorf("changed phone is still verified: phone_verified=%v verified=%v", users.user.PhoneVerified, users.user.Verified)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/notification"
+	"log"
+)
+
+type WishlistService struct {
+	Repo          repository.WishlistRepository
+	UserRepo      repository.UserRepository
+	CatalogueRepo repository.CatalogueRepository
+	Notifier      NotificationService
+	Config        config.AppConfig
+}
+
+func NewWishlistService(repo repository.WishlistRepository, userRepo repository.UserRepository, catalogueRepo repository.CatalogueRepository, notifier NotificationService, config config.AppConfig) WishlistService {
+	return WishlistService{
+		Repo:          repo,
+		UserRepo:      userRepo,
+		CatalogueRepo: catalogueRepo,
+		Notifier:      notifier,
+		Config:        config,
+	}
+}
+
+func (s WishlistService) GetWishlist(userID uint, params dto.PaginationParams) (*dto.PaginatedResponse, error) {
+	items, page, err := s.Repo.FindItems(userID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		result[i] = item
+	}
+
+	return &dto.PaginatedResponse{
+		Data:       result,
+		Pagination: dto.NewPaginationMeta(params, page),
+	}, nil
+}
+
+func (s WishlistService) AddItem(userID uint, request dto.AddWishlistRequest) (*domain.WishlistItem, error) {
+	product, err := s.CatalogueRepo.GetProductByID(request.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.AddItem(&domain.WishlistItem{
+		UserID:            userID,
+		ProductID:         product.ID,
+		NotifyPriceDrop:   request.NotifyPriceDrop,
+		NotifyBackInStock: request.NotifyBackInStock,
+		AlertPrice:        product.MinPrice,
+	})
+}
+
+// UpdateItem switches the alerts of a wishlist item on or off. Turning price-drop alerts on
+// starts again from the current price.
+func (s WishlistService) UpdateItem(userID uint, productID uint, request dto.UpdateWishlistRequest) (*domain.WishlistItem, error) {
+	item, err := s.Repo.FindItem(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if request.NotifyPriceDrop != nil {
+		updates["notify_price_drop"] = *request.NotifyPriceDrop
+		if *request.NotifyPriceDrop && !item.NotifyPriceDrop && item.Product != nil {
+			updates["alert_price"] = item.Product.MinPrice
+		}
+	}
+	if request.NotifyBackInStock != nil {
+		updates["notify_back_in_stock"] = *request.NotifyBackInStock
+	}
+	if len(updates) == 0 {
+		return item, nil
+	}
+
+	return s.Repo.UpdateItem(item, updates)
+}
+
+func (s WishlistService) RemoveItem(userID uint, productID uint) error {
+	return s.Repo.DeleteItem(userID, productID)
+}
+
+// MoveToCart adds a wishlist item to the cart and takes it off the wishlist
+func (s WishlistService) MoveToCart(userID uint, productID uint, request dto.MoveToCartRequest) (*domain.Cart, error) {
+	if _, err := s.Repo.FindItem(userID, productID); err != nil {
+		return nil, err
+	}
+
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, domain.ErrInvalidQuantity
+	}
+
+	cartItem, err := addToCart(s.UserRepo, s.CatalogueRepo, userID, dto.CreateCartRequest{
+		ProductID: productID,
+		VariantID: request.VariantID,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the item is in the cart either way, so a wishlist entry that could not be removed is only logged
+	if err := s.Repo.DeleteItem(userID, productID); err != nil {
+		log.Printf("Failed to remove product %d from the wishlist of user %d: %v", productID, userID, err)
+	}
+	return cartItem, nil
+}
+
+// GetSellerCounts shows a seller how many wishlists each of their products is on
+func (s WishlistService) GetSellerCounts(sellerID uint) ([]domain.WishlistCount, error) {
+	return s.Repo.CountBySeller(sellerID)
+}
+
+// NotifyProductChange sends the price-drop and back-in-stock alerts a product change sets off.
+// Alerts never fail the change itself; problems are logged.
+func (s WishlistService) NotifyProductChange(before domain.Product, after domain.Product) {
+	priceDrop, backInStock := domain.WishlistAlerts(before, after)
+	if priceDrop {
+		s.sendAlerts(domain.WISHLIST_PRICE_DROP, notification.TemplatePriceDrop, after)
+	}
+	if backInStock {
+		s.sendAlerts(domain.WISHLIST_BACK_IN_STOCK, notification.TemplateBackInStock, after)
+	}
+}
+
+// sendAlerts queues an alert for every wishlist item of the product that asked for it
+func (s WishlistService) sendAlerts(alert string, template string, product domain.Product) {
+	items, err := s.Repo.FindWatchers(product.ID, alert, product.MinPrice)
+	if err != nil {
+		log.Printf("Failed to load %s watchers of product %d: %v", alert, product.ID, err)
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	messages := []domain.OutboxMessage{}
+	for _, item := range items {
+		previous := item.AlertPrice
+		messages = append(messages, s.Notifier.ComposeForUser(item.UserID, template, func(user *domain.User) interface{} {
+			return notification.WishlistAlertData{
+				Name:          user.FirstName,
+				Product:       product.Name,
+				Price:         product.MinPrice,
+				PreviousPrice: previous,
+				Currency:      s.Config.PaymentCurrency,
+			}
+		})...)
+	}
+
+	if err := s.Repo.RecordAlerts(alert, items, product.MinPrice, messages); err != nil {
+		return
+	}
+	log.Printf("Queued %d %s alerts for product %d", len(messages), alert, product.ID)
+}
@@ -2,10 +2,12 @@ package flutterwave
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"time"
 )
 
@@ -23,6 +25,7 @@ const (
 type Client struct {
 	httpClient *http.Client
 	secretKey  string
+	baseURL    string
 }
 
 type VerifyAccountRequest struct {
@@ -48,6 +51,17 @@ type ErrorResponse struct {
 	Data    interface{} `json:"data"`
 }
 
+// APIError is a response in which Flutterwave refused a request. Any other failure, such as a
+// timeout, a dropped connection or a server error, leaves the outcome of the request unknown.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+}
+
 type VerifyAccountResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
@@ -57,17 +71,167 @@ type VerifyAccountResponse struct {
 	} `json:"data"`
 }
 
+type PaymentCustomer struct {
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phonenumber,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+type PaymentCustomizations struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type InitiatePaymentRequest struct {
+	TxRef          string                 `json:"tx_ref"`
+	Amount         float64                `json:"amount"`
+	Currency       string                 `json:"currency"`
+	RedirectURL    string                 `json:"redirect_url"`
+	Customer       PaymentCustomer        `json:"customer"`
+	Customizations *PaymentCustomizations `json:"customizations,omitempty"`
+	Meta           map[string]interface{} `json:"meta,omitempty"`
+}
+
+type InitiatePaymentResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		Link string `json:"link"`
+	} `json:"data"`
+}
+
+type Transaction struct {
+	ID            int64   `json:"id"`
+	TxRef         string  `json:"tx_ref"`
+	FlwRef        string  `json:"flw_ref"`
+	Amount        float64 `json:"amount"`
+	ChargedAmount float64 `json:"charged_amount"`
+	Currency      string  `json:"currency"`
+	Status        string  `json:"status"`
+}
+
+type VerifyTransactionResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Data    Transaction `json:"data"`
+}
+
+type TransferRequest struct {
+	AccountBank   string  `json:"account_bank"`
+	AccountNumber string  `json:"account_number"`
+	Amount        float64 `json:"amount"`
+	Narration     string  `json:"narration"`
+	Currency      string  `json:"currency"`
+	Reference     string  `json:"reference"`
+	DebitCurrency string  `json:"debit_currency,omitempty"`
+}
+
+type TransferResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID              int64   `json:"id"`
+		Reference       string  `json:"reference"`
+		Amount          float64 `json:"amount"`
+		Status          string  `json:"status"`
+		CompleteMessage string  `json:"complete_message"`
+	} `json:"data"`
+}
+
+type Transfer struct {
+	ID              int64   `json:"id"`
+	Reference       string  `json:"reference"`
+	Amount          float64 `json:"amount"`
+	Status          string  `json:"status"`
+	CompleteMessage string  `json:"complete_message"`
+}
+
+type GetTransfersResponse struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message"`
+	Data    []Transfer `json:"data"`
+}
+
+type RefundRequest struct {
+	Amount   float64 `json:"amount,omitempty"`
+	Comments string  `json:"comments,omitempty"`
+}
+
+type RefundResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID             int64   `json:"id"`
+		AmountRefunded float64 `json:"amount_refunded"`
+		Status         string  `json:"status"`
+		FlwRef         string  `json:"flw_ref"`
+	} `json:"data"`
+}
+
+type RefundRecord struct {
+	ID             int64   `json:"id"`
+	TxID           int64   `json:"tx_id"`
+	AmountRefunded float64 `json:"amount_refunded"`
+	Status         string  `json:"status"`
+	Comments       string  `json:"comments"`
+}
+
+type GetRefundsResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    []RefundRecord `json:"data"`
+}
+
+// WebhookData covers the fields of both charge and transfer events
+type WebhookData struct {
+	ID              int64   `json:"id"`
+	TxRef           string  `json:"tx_ref"`
+	FlwRef          string  `json:"flw_ref"`
+	Reference       string  `json:"reference"`
+	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency"`
+	Status          string  `json:"status"`
+	CompleteMessage string  `json:"complete_message"`
+}
+
+// Webhook event types handled by the application
+const (
+	EventChargeCompleted   = "charge.completed"
+	EventTransferCompleted = "transfer.completed"
+)
+
+// WebhookEvent is the payload Flutterwave posts to the webhook URL set on the dashboard
+type WebhookEvent struct {
+	Event string      `json:"event"`
+	Data  WebhookData `json:"data"`
+}
+
 func NewClient(secretKey string) *Client {
+	return NewClientWithBaseURL(secretKey, baseURL)
+}
+
+// NewClientWithBaseURL points the client at another host, e.g. an httptest server in tests
+func NewClientWithBaseURL(secretKey string, baseURL string) *Client {
 	return &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 		secretKey: secretKey,
+		baseURL:   baseURL,
 	}
 }
 
+// VerifyWebhookSignature compares the verif-hash header against the secret hash
+// configured on the Flutterwave dashboard
+func VerifyWebhookSignature(secretHash string, signature string) bool {
+	if secretHash == "" || signature == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secretHash), []byte(signature)) == 1
+}
+
 func (c *Client) GetBanks(country string) ([]Bank, error) {
-	url := fmt.Sprintf("%s/banks?country=%s", baseURL, country)
+	url := fmt.Sprintf("%s/banks?country=%s", c.baseURL, country)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -117,7 +281,7 @@ func (c *Client) GetBanks(country string) ([]Bank, error) {
 }
 
 func (c *Client) VerifyAccount(accountNumber, bankCode string) (*VerifyAccountResponse, error) {
-	url := fmt.Sprintf("%s/v3/accounts/resolve", baseURL)
+	url := fmt.Sprintf("%s/v3/accounts/resolve", c.baseURL)
 
 	requestBody := VerifyAccountRequest{
 		AccountNumber: accountNumber,
@@ -167,3 +331,179 @@ func (c *Client) VerifyAccount(accountNumber, bankCode string) (*VerifyAccountRe
 
 	return &apiResponse, nil
 }
+
+// InitiatePayment creates a Flutterwave Standard payment and returns the hosted checkout link
+func (c *Client) InitiatePayment(request InitiatePaymentRequest) (*InitiatePaymentResponse, error) {
+	url := fmt.Sprintf("%s/v3/payments", c.baseURL)
+
+	var apiResponse InitiatePaymentResponse
+	if err := c.doJSON("POST", url, request, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" || apiResponse.Data.Link == "" {
+		return nil, fmt.Errorf("payment initiation failed: %s", apiResponse.Message)
+	}
+
+	return &apiResponse, nil
+}
+
+// VerifyTransaction fetches the authoritative state of a transaction from Flutterwave
+func (c *Client) VerifyTransaction(transactionID int64) (*VerifyTransactionResponse, error) {
+	url := fmt.Sprintf("%s/v3/transactions/%d/verify", c.baseURL, transactionID)
+
+	var apiResponse VerifyTransactionResponse
+	if err := c.doJSON("GET", url, nil, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" {
+		return nil, fmt.Errorf("transaction verification failed: %s", apiResponse.Message)
+	}
+
+	return &apiResponse, nil
+}
+
+// VerifyTransactionByReference looks a transaction up by the tx_ref we generated for it
+func (c *Client) VerifyTransactionByReference(txRef string) (*VerifyTransactionResponse, error) {
+	url := fmt.Sprintf("%s/v3/transactions/verify_by_reference?tx_ref=%s", c.baseURL, neturl.QueryEscape(txRef))
+
+	var apiResponse VerifyTransactionResponse
+	if err := c.doJSON("GET", url, nil, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" {
+		return nil, fmt.Errorf("transaction verification failed: %s", apiResponse.Message)
+	}
+
+	return &apiResponse, nil
+}
+
+// InitiateTransfer sends money from the Flutterwave balance to a bank account
+func (c *Client) InitiateTransfer(request TransferRequest) (*TransferResponse, error) {
+	url := fmt.Sprintf("%s/v3/transfers", c.baseURL)
+
+	var apiResponse TransferResponse
+	if err := c.doJSON("POST", url, request, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" {
+		return nil, &APIError{StatusCode: http.StatusOK, Message: "transfer failed: " + apiResponse.Message}
+	}
+
+	return &apiResponse, nil
+}
+
+// FindTransfer looks a transfer up by the reference we sent it with; it returns nil when
+// Flutterwave has no transfer with that reference
+func (c *Client) FindTransfer(reference string) (*Transfer, error) {
+	url := fmt.Sprintf("%s/v3/transfers?reference=%s", c.baseURL, neturl.QueryEscape(reference))
+
+	var apiResponse GetTransfersResponse
+	if err := c.doJSON("GET", url, nil, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" {
+		return nil, fmt.Errorf("transfer lookup failed: %s", apiResponse.Message)
+	}
+
+	for i := range apiResponse.Data {
+		if apiResponse.Data[i].Reference == reference {
+			return &apiResponse.Data[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// RefundTransaction refunds the given amount of a successful transaction, or all of it when amount is zero
+func (c *Client) RefundTransaction(transactionID int64, request RefundRequest) (*RefundResponse, error) {
+	url := fmt.Sprintf("%s/v3/transactions/%d/refund", c.baseURL, transactionID)
+
+	var apiResponse RefundResponse
+	if err := c.doJSON("POST", url, request, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" {
+		return nil, &APIError{StatusCode: http.StatusOK, Message: "refund failed: " + apiResponse.Message}
+	}
+
+	return &apiResponse, nil
+}
+
+// FindRefund looks for the refund of a transaction made with the given comments, which is where
+// the refund reference is sent; it returns nil when there is none
+func (c *Client) FindRefund(transactionID int64, comments string) (*RefundRecord, error) {
+	url := fmt.Sprintf("%s/v3/refunds?tx_id=%d", c.baseURL, transactionID)
+
+	var apiResponse GetRefundsResponse
+	if err := c.doJSON("GET", url, nil, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if apiResponse.Status != "success" {
+		return nil, fmt.Errorf("refund lookup failed: %s", apiResponse.Message)
+	}
+
+	for i := range apiResponse.Data {
+		if apiResponse.Data[i].TxID == transactionID && apiResponse.Data[i].Comments == comments {
+			return &apiResponse.Data[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// doJSON sends an authenticated request with an optional JSON body and decodes the JSON response into out
+func (c *Client) doJSON(method string, url string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(respBody, &errorResp); err == nil && errorResp.Message != "" {
+			// a 4xx answer is Flutterwave turning the request down; a 5xx may have been processed
+			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				return &APIError{StatusCode: resp.StatusCode, Message: errorResp.Message}
+			}
+			return fmt.Errorf("API error (status %d): %s", resp.StatusCode, errorResp.Message)
+		}
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(respBody[:min(200, len(respBody))]))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
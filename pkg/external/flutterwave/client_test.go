@@ -0,0 +1,140 @@
+package flutterwave
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClientWithBaseURL("FLWSECK_TEST-secret", server.URL)
+}
+
+func TestInitiatePaymentSendsRequestAndReturnsLink(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/payments" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer FLWSECK_TEST-secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+
+		var request InitiatePaymentRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if request.TxRef != "PAY-1" || request.Amount != 2500 || request.Currency != "NGN" {
+			t.Errorf("unexpected request body %+v", request)
+		}
+
+		w.Write([]byte(`{"status":"success","message":"Hosted Link","data":{"link":"https://checkout.test/pay/abc"}}`))
+	})
+
+	response, err := client.InitiatePayment(InitiatePaymentRequest{
+		TxRef:    "PAY-1",
+		Amount:   2500,
+		Currency: "NGN",
+		Customer: PaymentCustomer{Email: "buyer@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("InitiatePayment: %v", err)
+	}
+	if response.Data.Link != "https://checkout.test/pay/abc" {
+		t.Errorf("link = %q", response.Data.Link)
+	}
+}
+
+func TestInitiatePaymentWithoutLinkFails(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","message":"no link","data":{}}`))
+	})
+
+	if _, err := client.InitiatePayment(InitiatePaymentRequest{TxRef: "PAY-1"}); err == nil {
+		t.Fatal("expected an error for a response without a link")
+	}
+}
+
+func TestVerifyTransactionByReferenceEscapesReference(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/transactions/verify_by_reference" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("tx_ref"); got != "PAY 1&x" {
+			t.Errorf("tx_ref = %q", got)
+		}
+		w.Write([]byte(`{"status":"success","data":{"id":42,"tx_ref":"PAY 1&x","amount":2500,"currency":"NGN","status":"successful"}}`))
+	})
+
+	response, err := client.VerifyTransactionByReference("PAY 1&x")
+	if err != nil {
+		t.Fatalf("VerifyTransactionByReference: %v", err)
+	}
+	if response.Data.ID != 42 || response.Data.Status != "successful" || response.Data.Amount != 2500 {
+		t.Errorf("unexpected transaction %+v", response.Data)
+	}
+}
+
+func TestErrorStatusReturnsProviderMessage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","message":"Invalid transaction reference"}`))
+	})
+
+	_, err := client.VerifyTransactionByReference("PAY-1")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "status 400") || !strings.Contains(err.Error(), "Invalid transaction reference") {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestRefundTransactionPostsAmount(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/transactions/42/refund" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var request RefundRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if request.Amount != 1000 {
+			t.Errorf("amount = %v", request.Amount)
+		}
+		w.Write([]byte(`{"status":"success","message":"Refund initiated","data":{"id":7,"amount_refunded":1000,"status":"completed"}}`))
+	})
+
+	response, err := client.RefundTransaction(42, RefundRequest{Amount: 1000})
+	if err != nil {
+		t.Fatalf("RefundTransaction: %v", err)
+	}
+	if response.Data.ID != 7 || response.Data.Status != "completed" {
+		t.Errorf("unexpected refund %+v", response.Data)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		secretHash string
+		signature  string
+		want       bool
+	}{
+		{"matching hash", "hash-123", "hash-123", true},
+		{"wrong hash", "hash-123", "hash-124", false},
+		{"missing header", "hash-123", "", false},
+		{"hash not configured", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature(tt.secretHash, tt.signature); got != tt.want {
+				t.Errorf("VerifyWebhookSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
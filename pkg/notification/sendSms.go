@@ -2,6 +2,7 @@ package notification
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-ecommerce-app/config"
 
@@ -11,10 +12,13 @@ import (
 
 type NotificationClient interface {
 	SendSMS(phone string, message string) error
+	SendEmail(to string, subject string, body string) error
+	EmailEnabled() bool
 }
 
 type notificationClient struct {
 	config config.AppConfig
+	mailer *SMTPMailer
 }
 
 // twillio
@@ -40,6 +44,22 @@ func (c notificationClient) SendSMS(phone string, message string) error {
 	return nil
 }
 
+// SendEmail delivers through SMTP; it fails when SMTP_HOST is not configured
+func (c notificationClient) SendEmail(to string, subject string, body string) error {
+	if c.mailer == nil {
+		return errors.New("email is not configured, set SMTP_HOST")
+	}
+	return c.mailer.Send(to, subject, body)
+}
+
+func (c notificationClient) EmailEnabled() bool {
+	return c.mailer != nil
+}
+
 func NewNotificationClient(config config.AppConfig) NotificationClient {
-	return &notificationClient{config: config}
+	client := &notificationClient{config: config}
+	if config.SMTPHost != "" {
+		client.mailer = NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.SMTPFrom)
+	}
+	return client
 }
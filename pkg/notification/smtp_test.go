@@ -0,0 +1,140 @@
+package notification
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// receivedMail is what the fake server got in one SMTP transaction
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// startFakeSMTPServer accepts one connection and speaks just enough SMTP for net/smtp to
+// deliver a message without authentication or TLS
+func startFakeSMTPServer(t *testing.T) (string, string, <-chan receivedMail) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan receivedMail, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		mail := receivedMail{}
+
+		reply("220 localhost fake smtp")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimRight(line, "\r\n")
+			verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0])
+
+			switch verb {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL":
+				mail.from = strings.TrimPrefix(command, "MAIL FROM:")
+				reply("250 OK")
+			case "RCPT":
+				mail.to = append(mail.to, strings.TrimPrefix(command, "RCPT TO:"))
+				reply("250 OK")
+			case "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				mail.data = data.String()
+				reply("250 OK queued")
+				received <- mail
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port, received
+}
+
+func TestSMTPMailerSendsPlainTextMessage(t *testing.T) {
+	host, port, received := startFakeSMTPServer(t)
+	mailer := NewSMTPMailer(host, port, "", "", "shop@example.com")
+
+	if err := mailer.Send("buyer@example.com", "Your verification code", "Hi Ada,\nYour code is 123456."); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	mail := <-received
+	if !strings.Contains(mail.from, "<shop@example.com>") {
+		t.Errorf("MAIL FROM = %q", mail.from)
+	}
+	if len(mail.to) != 1 || !strings.Contains(mail.to[0], "<buyer@example.com>") {
+		t.Errorf("RCPT TO = %q", mail.to)
+	}
+	for _, header := range []string{
+		"From: shop@example.com\r\n",
+		"To: buyer@example.com\r\n",
+		"Subject: Your verification code\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+	} {
+		if !strings.Contains(mail.data, header) {
+			t.Errorf("message is missing header %q:\n%s", header, mail.data)
+		}
+	}
+	if !strings.Contains(mail.data, "\r\n\r\nHi Ada,\r\nYour code is 123456.") {
+		t.Errorf("body line endings were not normalised:\n%q", mail.data)
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := NewSMTPMailer("127.0.0.1", "1", "", "", "shop@example.com")
+
+	if err := mailer.Send("buyer@example.com\r\nBcc: other@example.com", "hi", "body"); err == nil {
+		t.Error("expected a recipient with a line break to be rejected")
+	}
+	if err := mailer.Send("buyer@example.com", "hi\nBcc: other@example.com", "body"); err == nil {
+		t.Error("expected a subject with a line break to be rejected")
+	}
+}
+
+func TestSMTPMailerReportsUnreachableServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	mailer := NewSMTPMailer(host, port, "", "", "shop@example.com")
+	if err := mailer.Send("buyer@example.com", "hi", "body"); err == nil {
+		t.Error("expected an error when the server is down")
+	}
+}
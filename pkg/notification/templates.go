@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	TemplateVerificationCode  = "verification_code"
+	TemplatePasswordReset     = "password_reset"
+	TemplateOrderConfirmation = "order_confirmation"
+	TemplateShippingUpdate    = "shipping_update"
+	TemplatePayoutReceipt     = "payout_receipt"
+	TemplatePriceDrop         = "wishlist_price_drop"
+	TemplateBackInStock       = "wishlist_back_in_stock"
+)
+
+// Message is a rendered template: Subject and Body are used for email, SMS for text messages
+type Message struct {
+	Subject string
+	Body    string
+	SMS     string
+}
+
+type CodeData struct {
+	Name             string
+	Code             int
+	ExpiresInMinutes int
+}
+
+type OrderLine struct {
+	Name     string
+	Quantity int
+	Price    float64
+}
+
+type OrderConfirmationData struct {
+	Name     string
+	OrderRef string
+	Total    float64
+	Currency string
+	Items    []OrderLine
+}
+
+type ShippingUpdateData struct {
+	Name     string
+	OrderRef string
+	Status   string
+	Items    []OrderLine
+}
+
+type PayoutReceiptData struct {
+	Name          string
+	Reference     string
+	Amount        float64
+	Currency      string
+	AccountEnding string
+}
+
+// WishlistAlertData describes a saved product that got cheaper or came back in stock
+type WishlistAlertData struct {
+	Name          string
+	Product       string
+	Price         float64
+	PreviousPrice float64
+	Currency      string
+}
+
+// each template defines a .subject, .body and .sms part
+const templateText = `
+{{define "verification_code.subject"}}Your verification code{{end}}
+{{define "verification_code.body"}}Hi {{.Name}},
+
+Your verification code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.
+
+If you did not ask for this code you can ignore this message.
+{{end}}
+{{define "verification_code.sms"}}Your verification code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.{{end}}
+
+{{define "password_reset.subject"}}Reset your password{{end}}
+{{define "password_reset.body"}}Hi {{.Name}},
+
+Your password reset code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.
+
+If you did not ask to reset your password you can ignore this message, your password stays the same.
+{{end}}
+{{define "password_reset.sms"}}Your password reset code is {{.Code}}. It expires in {{.ExpiresInMinutes}} minutes.{{end}}
+
+{{define "order_confirmation.subject"}}Order {{.OrderRef}} confirmed{{end}}
+{{define "order_confirmation.body"}}Hi {{.Name}},
+
+Thank you for your order. We have received your payment for order {{.OrderRef}}.
+{{range .Items}}
+- {{.Quantity}} x {{.Name}} at {{printf "%.2f" .Price}}{{end}}
+
+Total paid: {{printf "%.2f" .Total}} {{.Currency}}
+
+We will let you know when your items ship.
+{{end}}
+{{define "order_confirmation.sms"}}Payment received for order {{.OrderRef}}: {{printf "%.2f" .Total}} {{.Currency}}. Thank you!{{end}}
+
+{{define "shipping_update.subject"}}Order {{.OrderRef}} is {{.Status}}{{end}}
+{{define "shipping_update.body"}}Hi {{.Name}},
+
+Items from your order {{.OrderRef}} are now {{.Status}}:
+{{range .Items}}
+- {{.Quantity}} x {{.Name}}{{end}}
+{{end}}
+{{define "shipping_update.sms"}}Items from your order {{.OrderRef}} are now {{.Status}}.{{end}}
+
+{{define "payout_receipt.subject"}}Payout {{.Reference}} sent{{end}}
+{{define "payout_receipt.body"}}Hi {{.Name}},
+
+We have paid {{printf "%.2f" .Amount}} {{.Currency}} to your bank account ending in {{.AccountEnding}}.
+
+Payout reference: {{.Reference}}
+{{end}}
+{{define "payout_receipt.sms"}}Payout {{.Reference}}: {{printf "%.2f" .Amount}} {{.Currency}} sent to your account ending in {{.AccountEnding}}.{{end}}
+
+{{define "wishlist_price_drop.subject"}}{{.Product}} is now cheaper{{end}}
+{{define "wishlist_price_drop.body"}}Hi {{.Name}},
+
+{{.Product}} from your wishlist now costs {{printf "%.2f" .Price}} {{.Currency}}, down from {{printf "%.2f" .PreviousPrice}} {{.Currency}}.
+{{end}}
+{{define "wishlist_price_drop.sms"}}{{.Product}} from your wishlist is now {{printf "%.2f" .Price}} {{.Currency}}, down from {{printf "%.2f" .PreviousPrice}}.{{end}}
+
+{{define "wishlist_back_in_stock.subject"}}{{.Product}} is back in stock{{end}}
+{{define "wishlist_back_in_stock.body"}}Hi {{.Name}},
+
+{{.Product}} from your wishlist is back in stock at {{printf "%.2f" .Price}} {{.Currency}}.
+{{end}}
+{{define "wishlist_back_in_stock.sms"}}{{.Product}} from your wishlist is back in stock.{{end}}
+`
+
+var templates = template.Must(template.New("notifications").Parse(templateText))
+
+// Render fills in the named template with data
+func Render(name string, data interface{}) (*Message, error) {
+	message := &Message{}
+	parts := []struct {
+		suffix string
+		target *string
+	}{
+		{"subject", &message.Subject},
+		{"body", &message.Body},
+		{"sms", &message.SMS},
+	}
+
+	for _, part := range parts {
+		var buffer bytes.Buffer
+		if err := templates.ExecuteTemplate(&buffer, name+"."+part.suffix, data); err != nil {
+			return nil, fmt.Errorf("failed to render %s template: %w", name, err)
+		}
+		*part.target = buffer.String()
+	}
+
+	return message, nil
+}
@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/pkg/external/flutterwave"
+	"go-ecommerce-app/pkg/external/verifyme"
+)
+
+const (
+	ProviderFlutterwave = "flutterwave"
+	ProviderVerifyMe    = "verifyme"
+	ProviderFake        = "fake"
+)
+
+// NewProviders builds the payment provider and bank verifier selected in the config.
+// Either is nil when its setting is empty, which disables the related features.
+func NewProviders(cfg config.AppConfig) (PaymentProvider, BankVerifier, error) {
+	var flutterwaveProvider *FlutterwaveProvider
+	getFlutterwave := func() (*FlutterwaveProvider, error) {
+		if cfg.FlutterwaveSecretKey == "" {
+			return nil, errors.New("FLUTTERWAVE_SECRET_KEY is required for the flutterwave provider")
+		}
+		if flutterwaveProvider == nil {
+			flutterwaveProvider = NewFlutterwaveProvider(flutterwave.NewClient(cfg.FlutterwaveSecretKey), cfg.BankCountry)
+		}
+		return flutterwaveProvider, nil
+	}
+	fake := NewFakeProvider()
+
+	var paymentProvider PaymentProvider
+	switch cfg.PaymentProvider {
+	case "":
+	case ProviderFlutterwave:
+		provider, err := getFlutterwave()
+		if err != nil {
+			return nil, nil, err
+		}
+		paymentProvider = provider
+	case ProviderFake:
+		paymentProvider = fake
+	default:
+		return nil, nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", cfg.PaymentProvider)
+	}
+
+	var bankVerifier BankVerifier
+	switch cfg.BankVerifier {
+	case "":
+	case ProviderFlutterwave:
+		provider, err := getFlutterwave()
+		if err != nil {
+			return nil, nil, err
+		}
+		bankVerifier = provider
+	case ProviderVerifyMe:
+		if cfg.VerifyMeAPIKey == "" {
+			return nil, nil, errors.New("VERIFYME_API_KEY is required for the verifyme bank verifier")
+		}
+		bankVerifier = NewVerifyMeVerifier(verifyme.NewClient(cfg.VerifyMeAPIKey))
+	case ProviderFake:
+		bankVerifier = fake
+	default:
+		return nil, nil, fmt.Errorf("unknown BANK_VERIFIER %q", cfg.BankVerifier)
+	}
+
+	return paymentProvider, bankVerifier, nil
+}
@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// FakeProvider is an in-memory PaymentProvider and BankVerifier for development and CI.
+// Every account with a 10 digit number verifies and every payment succeeds immediately.
+type FakeProvider struct {
+	mu           sync.Mutex
+	transactions map[string]Transaction
+	transfers    map[string]Transfer
+	refunds      map[string]Refund
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		transactions: map[string]Transaction{},
+		transfers:    map[string]Transfer{},
+		refunds:      map[string]Refund{},
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) GetBanks() ([]Bank, error) {
+	return []Bank{
+		{Code: "044", Name: "Access Bank"},
+		{Code: "058", Name: "Guaranty Trust Bank"},
+		{Code: "033", Name: "United Bank for Africa"},
+		{Code: "057", Name: "Zenith Bank"},
+	}, nil
+}
+
+func (p *FakeProvider) VerifyAccount(accountNumber, bankCode string) (*AccountDetails, error) {
+	if len(accountNumber) != 10 {
+		return nil, errors.New("verification failed: account number must be 10 digits")
+	}
+	for _, r := range accountNumber {
+		if r < '0' || r > '9' {
+			return nil, errors.New("verification failed: account number must be 10 digits")
+		}
+	}
+
+	return &AccountDetails{
+		AccountNumber: accountNumber,
+		AccountName:   "Test Account " + accountNumber[6:],
+		BankCode:      bankCode,
+	}, nil
+}
+
+func (p *FakeProvider) InitiatePayment(request PaymentRequest) (*PaymentSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transactions[request.Reference] = Transaction{
+		ID:        fmt.Sprintf("fake-%d", len(p.transactions)+1),
+		Reference: request.Reference,
+		Amount:    request.Amount,
+		Currency:  request.Currency,
+		Status:    TransactionSuccessful,
+	}
+
+	query := url.Values{}
+	query.Set("status", TransactionSuccessful)
+	query.Set("tx_ref", request.Reference)
+
+	return &PaymentSession{
+		Reference: request.Reference,
+		Link:      request.RedirectURL + "?" + query.Encode(),
+	}, nil
+}
+
+func (p *FakeProvider) VerifyTransaction(reference string) (*Transaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	transaction, ok := p.transactions[reference]
+	if !ok {
+		return nil, fmt.Errorf("%w: transaction %s", ErrNotFound, reference)
+	}
+	return &transaction, nil
+}
+
+func (p *FakeProvider) InitiateTransfer(request TransferRequest) (*Transfer, error) {
+	if _, err := p.VerifyAccount(request.AccountNumber, request.BankCode); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeclined, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	transfer := Transfer{
+		ID:        "fake-transfer-" + request.Reference,
+		Reference: request.Reference,
+		Status:    TransactionSuccessful,
+	}
+	p.transfers[request.Reference] = transfer
+	return &transfer, nil
+}
+
+func (p *FakeProvider) FindTransfer(reference string) (*Transfer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	transfer, ok := p.transfers[reference]
+	if !ok {
+		return nil, fmt.Errorf("%w: transfer %s", ErrNotFound, reference)
+	}
+	return &transfer, nil
+}
+
+func (p *FakeProvider) Refund(request RefundRequest) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, transaction := range p.transactions {
+		if transaction.ID != request.TransactionID {
+			continue
+		}
+		if request.Amount > transaction.Amount {
+			return nil, fmt.Errorf("%w: refund of %.2f exceeds transaction amount %.2f", ErrDeclined, request.Amount, transaction.Amount)
+		}
+		refund := Refund{
+			ID:     "fake-refund-" + request.Reference,
+			Status: TransactionSuccessful,
+		}
+		p.refunds[request.Reference] = refund
+		return &refund, nil
+	}
+
+	return nil, fmt.Errorf("%w: transaction %s not found", ErrDeclined, request.TransactionID)
+}
+
+func (p *FakeProvider) FindRefund(request RefundRequest) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	refund, ok := p.refunds[request.Reference]
+	if !ok {
+		return nil, fmt.Errorf("%w: refund %s", ErrNotFound, request.Reference)
+	}
+	return &refund, nil
+}
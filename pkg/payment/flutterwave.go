@@ -0,0 +1,206 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/pkg/external/flutterwave"
+	"strconv"
+)
+
+type FlutterwaveProvider struct {
+	client  *flutterwave.Client
+	country string
+}
+
+// NewFlutterwaveProvider adapts the Flutterwave client to PaymentProvider and BankVerifier
+func NewFlutterwaveProvider(client *flutterwave.Client, country string) *FlutterwaveProvider {
+	return &FlutterwaveProvider{client: client, country: country}
+}
+
+func (p *FlutterwaveProvider) Name() string {
+	return "flutterwave"
+}
+
+func (p *FlutterwaveProvider) GetBanks() ([]Bank, error) {
+	flutterwaveBanks, err := p.client.GetBanks(p.country)
+	if err != nil {
+		return nil, err
+	}
+
+	banks := make([]Bank, len(flutterwaveBanks))
+	for i, bank := range flutterwaveBanks {
+		banks[i] = Bank{Code: bank.Code, Name: bank.Name}
+	}
+	return banks, nil
+}
+
+func (p *FlutterwaveProvider) VerifyAccount(accountNumber, bankCode string) (*AccountDetails, error) {
+	result, err := p.client.VerifyAccount(accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountDetails{
+		AccountNumber: result.Data.AccountNumber,
+		AccountName:   result.Data.AccountName,
+		BankCode:      bankCode,
+	}, nil
+}
+
+func (p *FlutterwaveProvider) InitiatePayment(request PaymentRequest) (*PaymentSession, error) {
+	response, err := p.client.InitiatePayment(flutterwave.InitiatePaymentRequest{
+		TxRef:       request.Reference,
+		Amount:      request.Amount,
+		Currency:    request.Currency,
+		RedirectURL: request.RedirectURL,
+		Customer: flutterwave.PaymentCustomer{
+			Email:       request.Customer.Email,
+			PhoneNumber: request.Customer.Phone,
+			Name:        request.Customer.Name,
+		},
+		Customizations: &flutterwave.PaymentCustomizations{
+			Title:       request.Title,
+			Description: request.Description,
+		},
+		Meta: request.Meta,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentSession{Reference: request.Reference, Link: response.Data.Link}, nil
+}
+
+func (p *FlutterwaveProvider) VerifyTransaction(reference string) (*Transaction, error) {
+	response, err := p.client.VerifyTransactionByReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	status := TransactionPending
+	switch response.Data.Status {
+	case "successful":
+		status = TransactionSuccessful
+	case "failed", "cancelled":
+		status = TransactionFailed
+	}
+
+	return &Transaction{
+		ID:        strconv.FormatInt(response.Data.ID, 10),
+		Reference: response.Data.TxRef,
+		Amount:    response.Data.Amount,
+		Currency:  response.Data.Currency,
+		Status:    status,
+	}, nil
+}
+
+func (p *FlutterwaveProvider) InitiateTransfer(request TransferRequest) (*Transfer, error) {
+	response, err := p.client.InitiateTransfer(flutterwave.TransferRequest{
+		AccountBank:   request.BankCode,
+		AccountNumber: request.AccountNumber,
+		Amount:        request.Amount,
+		Currency:      request.Currency,
+		Narration:     request.Narration,
+		Reference:     request.Reference,
+		DebitCurrency: request.Currency,
+	})
+	if err != nil {
+		return nil, declined(err)
+	}
+
+	return &Transfer{
+		ID:        strconv.FormatInt(response.Data.ID, 10),
+		Reference: response.Data.Reference,
+		Status:    TransferStatus(response.Data.Status),
+		Message:   response.Data.CompleteMessage,
+	}, nil
+}
+
+func (p *FlutterwaveProvider) FindTransfer(reference string) (*Transfer, error) {
+	transfer, err := p.client.FindTransfer(reference)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil {
+		return nil, fmt.Errorf("%w: transfer %s", ErrNotFound, reference)
+	}
+
+	return &Transfer{
+		ID:        strconv.FormatInt(transfer.ID, 10),
+		Reference: transfer.Reference,
+		Status:    TransferStatus(transfer.Status),
+		Message:   transfer.CompleteMessage,
+	}, nil
+}
+
+func (p *FlutterwaveProvider) Refund(request RefundRequest) (*Refund, error) {
+	transactionID, err := strconv.ParseInt(request.TransactionID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Flutterwave transaction id %q", request.TransactionID)
+	}
+
+	response, err := p.client.RefundTransaction(transactionID, flutterwave.RefundRequest{
+		Amount:   request.Amount,
+		Comments: request.Reference,
+	})
+	if err != nil {
+		return nil, declined(err)
+	}
+
+	return &Refund{
+		ID:      strconv.FormatInt(response.Data.ID, 10),
+		Status:  refundStatus(response.Data.Status),
+		Message: response.Message,
+	}, nil
+}
+
+// FindRefund looks the refund up by the reference it was sent with in its comments
+func (p *FlutterwaveProvider) FindRefund(request RefundRequest) (*Refund, error) {
+	transactionID, err := strconv.ParseInt(request.TransactionID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Flutterwave transaction id %q", request.TransactionID)
+	}
+
+	refund, err := p.client.FindRefund(transactionID, request.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if refund == nil {
+		return nil, fmt.Errorf("%w: refund %s", ErrNotFound, request.Reference)
+	}
+
+	return &Refund{
+		ID:     strconv.FormatInt(refund.ID, 10),
+		Status: refundStatus(refund.Status),
+	}, nil
+}
+
+func refundStatus(status string) string {
+	switch status {
+	case "completed", "successful":
+		return TransactionSuccessful
+	case "failed":
+		return TransactionFailed
+	}
+	return TransactionPending
+}
+
+// declined marks errors in which Flutterwave refused the request with ErrDeclined
+func declined(err error) error {
+	var apiErr *flutterwave.APIError
+	if errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: %s", ErrDeclined, apiErr.Message)
+	}
+	return err
+}
+
+// TransferStatus maps a Flutterwave transfer status onto the Transaction* constants
+func TransferStatus(status string) string {
+	switch status {
+	case "SUCCESSFUL":
+		return TransactionSuccessful
+	case "FAILED":
+		return TransactionFailed
+	}
+	return TransactionPending
+}
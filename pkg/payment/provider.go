@@ -0,0 +1,108 @@
+package payment
+
+import "errors"
+
+var (
+	// ErrDeclined means the provider definitely turned the request down, so it can be retried.
+	// Any other error leaves the outcome unknown until the provider is asked about it.
+	ErrDeclined = errors.New("declined by the payment provider")
+	// ErrNotFound means the provider has no record of the reference it was asked about
+	ErrNotFound = errors.New("not found at the payment provider")
+)
+
+const (
+	TransactionPending    = "pending"
+	TransactionSuccessful = "successful"
+	TransactionFailed     = "failed"
+)
+
+// BankVerifier lists banks and resolves account numbers to account names
+type BankVerifier interface {
+	GetBanks() ([]Bank, error)
+	VerifyAccount(accountNumber, bankCode string) (*AccountDetails, error)
+}
+
+// PaymentProvider collects payments from buyers, reports their outcome and pays sellers out
+type PaymentProvider interface {
+	Name() string
+	InitiatePayment(request PaymentRequest) (*PaymentSession, error)
+	VerifyTransaction(reference string) (*Transaction, error)
+	InitiateTransfer(request TransferRequest) (*Transfer, error)
+	FindTransfer(reference string) (*Transfer, error)
+	Refund(request RefundRequest) (*Refund, error)
+	FindRefund(request RefundRequest) (*Refund, error)
+}
+
+type Bank struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type AccountDetails struct {
+	AccountNumber string `json:"account_number"`
+	AccountName   string `json:"account_name"`
+	BankCode      string `json:"bank_code"`
+}
+
+type Customer struct {
+	Email string
+	Phone string
+	Name  string
+}
+
+type PaymentRequest struct {
+	Reference   string
+	Amount      float64
+	Currency    string
+	RedirectURL string
+	Title       string
+	Description string
+	Customer    Customer
+	Meta        map[string]interface{}
+}
+
+type PaymentSession struct {
+	Reference string `json:"reference"`
+	Link      string `json:"link"`
+}
+
+// Transaction is the provider's authoritative view of a payment; Status is one of the Transaction* constants
+type Transaction struct {
+	ID        string  `json:"id"`
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Status    string  `json:"status"`
+}
+
+type TransferRequest struct {
+	Reference     string
+	Amount        float64
+	Currency      string
+	BankCode      string
+	AccountNumber string
+	Narration     string
+}
+
+// Transfer is a payout to a bank account; Status is one of the Transaction* constants
+type Transfer struct {
+	ID        string `json:"id"`
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+}
+
+// RefundRequest returns part or all of a captured payment; TransactionID is the provider's id for it
+type RefundRequest struct {
+	TransactionID string
+	Reference     string
+	Amount        float64
+	Currency      string
+}
+
+// Refund is the provider's answer to a refund; Status is one of the Transaction* constants
+type Refund struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,38 @@
+package payment
+
+import "go-ecommerce-app/pkg/external/verifyme"
+
+type VerifyMeVerifier struct {
+	client *verifyme.Client
+}
+
+// NewVerifyMeVerifier adapts the VerifyMe client to BankVerifier
+func NewVerifyMeVerifier(client *verifyme.Client) *VerifyMeVerifier {
+	return &VerifyMeVerifier{client: client}
+}
+
+func (v *VerifyMeVerifier) GetBanks() ([]Bank, error) {
+	verifyMeBanks, err := v.client.GetBanks()
+	if err != nil {
+		return nil, err
+	}
+
+	banks := make([]Bank, len(verifyMeBanks))
+	for i, bank := range verifyMeBanks {
+		banks[i] = Bank{Code: bank.Code, Name: bank.Name}
+	}
+	return banks, nil
+}
+
+func (v *VerifyMeVerifier) VerifyAccount(accountNumber, bankCode string) (*AccountDetails, error) {
+	result, err := v.client.VerifyAccount(accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountDetails{
+		AccountNumber: result.Data.AccountNumber,
+		AccountName:   result.Data.AccountName,
+		BankCode:      bankCode,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage writes files below a directory that the server exposes as a static route
+type LocalStorage struct {
+	dir     string
+	baseURL string
+	route   string
+}
+
+// NewLocalStorage stores files in dir and hands out URLs below baseURL. The base URL may name
+// a host in front of the server, but its path is the route the server serves the files under.
+func NewLocalStorage(dir string, baseURL string) (*LocalStorage, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Path == "" {
+		return nil, errors.New("UPLOAD_BASE_URL must end in a path such as /uploads")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: baseURL,
+		route:   parsed.Path,
+	}, nil
+}
+
+// Route is the path the files must be served under for the URLs Save returns to work
+func (s *LocalStorage) Route() string {
+	return s.route
+}
+
+func (s *LocalStorage) Save(key string, r io.Reader) (string, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so readers never see a partial upload
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return s.baseURL + "/" + path.Clean(key), nil
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file inside the storage directory, refusing keys that escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"fmt"
+	"go-ecommerce-app/config"
+	"io"
+)
+
+const (
+	DriverLocal = "local"
+)
+
+// Storage keeps uploaded files under a key and reports the URL they are served from
+type Storage interface {
+	Save(key string, r io.Reader) (string, error)
+	Delete(key string) error
+}
+
+// NewStorage builds the storage backend selected in the config
+func NewStorage(cfg config.AppConfig) (Storage, error) {
+	switch cfg.StorageDriver {
+	case DriverLocal:
+		return NewLocalStorage(cfg.UploadDir, cfg.UploadBaseURL)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", cfg.StorageDriver)
+	}
+}